@@ -8,10 +8,3 @@ func envString(key, def string) string {
 	}
 	return def
 }
-
-func envBool(key string) bool {
-	if env := os.Getenv(key); env == "true" {
-		return true
-	}
-	return false
-}
@@ -0,0 +1,163 @@
+// bookctl is the admin tool of bookshop.
+//
+// Usage:
+//
+//	bookctl [flags] migrate up
+//	bookctl [flags] migrate down [steps]
+//	bookctl [flags] migrate status
+//	bookctl [flags] roles <email> <roles> [publisher-id]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/db/migrate"
+	"github.com/kavirajk/bookshop/db/sqldb"
+	"github.com/kavirajk/bookshop/user"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	var (
+		dbDriver = flag.String(
+			"db-driver", envString("DB_DRIVER", "postgres"),
+			"Name of the database driver: postgres or sqlite3",
+		)
+		dbSource = flag.String(
+			"db-source", envString("DB_SOURCE", ""),
+			"Database source to connect to.e.g: user=<user> password=<password> dbname=<dbname>, or the file for sqlite3",
+		)
+	)
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 || (args[0] != "migrate" && args[0] != "roles") {
+		usage()
+		os.Exit(2)
+	}
+	if *dbSource == "" {
+		fmt.Println("db-source argument is missing. Type --help for more info")
+		os.Exit(1)
+	}
+
+	database, err := db.Open(*dbDriver, *dbSource, db.PoolOptions{})
+	if err != nil {
+		log.Fatalf("error connecting to db: %v\n", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	if args[0] == "roles" {
+		setRoles(ctx, database, args[1:])
+		return
+	}
+
+	migrations, err := sqldb.Migrations(database.Dialect())
+	if err != nil {
+		log.Fatalf("error loading migrations: %v\n", err)
+	}
+	m := migrate.New(database.SQL(), database.Dialect(), migrations)
+
+	switch args[1] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mg := range applied {
+			fmt.Printf("applied %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Fatalf("error migrating up: %v\n", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 2 {
+			if steps, err = strconv.Atoi(args[2]); err != nil || steps <= 0 {
+				log.Fatalf("invalid steps %q\n", args[2])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mg := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Fatalf("error migrating down: %v\n", err)
+		}
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("error reading migration status: %v\n", err)
+		}
+		printStatus(status)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// setRoles replaces the roles of the user of an email, e.g: to make the
+// first admin, who then manages the others through the API.
+func setRoles(ctx context.Context, database *db.DB, args []string) {
+	if len(args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var publisherID string
+	if len(args) > 2 {
+		publisherID = args[2]
+	}
+	repo := sqldb.NewUserRepo(database)
+	u, err := repo.GetByEmail(ctx, args[0])
+	if err != nil {
+		log.Fatalf("error finding user %s: %v\n", args[0], err)
+	}
+	u, err = user.NewService(repo, user.DefaultLockout).SetRoles(ctx, u.ID, auth.ParseRoles(args[1]), publisherID)
+	if err != nil {
+		log.Fatalf("error setting roles: %v\n", err)
+	}
+	fmt.Printf("%s has roles %q\n", u.Email, u.Roles.String())
+}
+
+func printStatus(status []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range status {
+		state, at := "pending", ""
+		if s.Applied {
+			state, at = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Unknown {
+			state = "unknown"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+	}
+	w.Flush()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: bookctl [flags] <command>
+
+Commands:
+  migrate up             apply all pending migrations
+  migrate down [steps]   revert last steps migrations, 1 by default
+  migrate status         list migrations and whether they are applied
+  roles <email> <roles> [publisher-id]
+                         replace the comma separated roles of the user,
+                         admin or publisher, "" for none
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kavirajk/bookshop/config"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/tracing"
+	"github.com/kavirajk/bookshop/transport"
+	"github.com/kavirajk/bookshop/user"
+)
+
+// Config of bookserver. It is loaded from the -config file, then env vars,
+// then flags, see config.Apply.
+type Config struct {
+	HTTP  HTTPConfig  `yaml:"http" toml:"http"`
+	DB    DBConfig    `yaml:"db" toml:"db"`
+	Cache CacheConfig `yaml:"cache" toml:"cache"`
+	Redis RedisConfig `yaml:"redis" toml:"redis"`
+	Purge PurgeConfig `yaml:"purge" toml:"purge"`
+	Log   LogConfig   `yaml:"log" toml:"log"`
+	Trace TraceConfig `yaml:"trace" toml:"trace"`
+
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Lockout   LockoutConfig   `yaml:"lockout" toml:"lockout"`
+
+	HealthCheckTimeout config.Duration `yaml:"health_check_timeout" toml:"health_check_timeout"`
+	RatesFile          string          `yaml:"rates_file" toml:"rates_file"`
+	TaxRatesFile       string          `yaml:"tax_rates_file" toml:"tax_rates_file"`
+	ShippingRatesFile  string          `yaml:"shipping_rates_file" toml:"shipping_rates_file"`
+}
+
+type HTTPConfig struct {
+	Addr            string          `yaml:"addr" toml:"addr"`
+	ReadTimeout     config.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    config.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     config.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownDelay   config.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownTimeout config.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	TrustedProxies  []string        `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+type DBConfig struct {
+	Driver                 string          `yaml:"driver" toml:"driver"`
+	Source                 string          `yaml:"source" toml:"source"`
+	MaxOpenConns           int             `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns           int             `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime        config.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime        config.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	ReplicaSources         []string        `yaml:"replica_sources" toml:"replica_sources"`
+	ReplicaCheckInterval   config.Duration `yaml:"replica_check_interval" toml:"replica_check_interval"`
+	AllowPendingMigrations bool            `yaml:"allow_pending_migrations" toml:"allow_pending_migrations"`
+}
+
+type CacheConfig struct {
+	Backend   string          `yaml:"backend" toml:"backend"`
+	Size      int             `yaml:"size" toml:"size"`
+	TTLGet    config.Duration `yaml:"ttl_get" toml:"ttl_get"`
+	TTLSearch config.Duration `yaml:"ttl_search" toml:"ttl_search"`
+	TTLList   config.Duration `yaml:"ttl_list" toml:"ttl_list"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+type PurgeConfig struct {
+	Retention config.Duration `yaml:"retention" toml:"retention"`
+	Interval  config.Duration `yaml:"interval" toml:"interval"`
+}
+
+type LogConfig struct {
+	Format string `yaml:"format" toml:"format"`
+}
+
+type TraceConfig struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type RateLimitConfig struct {
+	Store        string          `yaml:"store" toml:"store"`
+	IPBurst      int             `yaml:"ip_burst" toml:"ip_burst"`
+	IPEvery      config.Duration `yaml:"ip_every" toml:"ip_every"`
+	AccountBurst int             `yaml:"account_burst" toml:"account_burst"`
+	AccountEvery config.Duration `yaml:"account_every" toml:"account_every"`
+}
+
+type LockoutConfig struct {
+	Threshold int             `yaml:"threshold" toml:"threshold"`
+	Duration  config.Duration `yaml:"duration" toml:"duration"`
+	Max       config.Duration `yaml:"max" toml:"max"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Addr:            "0.0.0.0:8080",
+			ReadTimeout:     config.Duration{Duration: 15 * time.Second},
+			WriteTimeout:    config.Duration{Duration: 30 * time.Second},
+			IdleTimeout:     config.Duration{Duration: 2 * time.Minute},
+			ShutdownTimeout: config.Duration{Duration: 30 * time.Second},
+			TrustedProxies:  []string{},
+		},
+		DB: DBConfig{
+			Driver:               "postgres",
+			MaxOpenConns:         20,
+			MaxIdleConns:         5,
+			ConnMaxLifetime:      config.Duration{Duration: 30 * time.Minute},
+			ConnMaxIdleTime:      config.Duration{Duration: 5 * time.Minute},
+			ReplicaSources:       []string{},
+			ReplicaCheckInterval: config.Duration{Duration: 10 * time.Second},
+		},
+		Cache: CacheConfig{
+			Backend:   "lru",
+			Size:      10000,
+			TTLGet:    config.Duration{Duration: 5 * time.Minute},
+			TTLSearch: config.Duration{Duration: time.Minute},
+			TTLList:   config.Duration{Duration: time.Minute},
+		},
+		Redis: RedisConfig{Addr: "localhost:6379"},
+		Purge: PurgeConfig{
+			Retention: config.Duration{Duration: 30 * 24 * time.Hour},
+			Interval:  config.Duration{Duration: time.Hour},
+		},
+		Log:   LogConfig{Format: logging.Logfmt},
+		Trace: TraceConfig{Exporter: tracing.None, SampleRatio: 1},
+		RateLimit: RateLimitConfig{
+			Store:        "memory",
+			IPBurst:      20,
+			IPEvery:      config.Duration{Duration: 3 * time.Second},
+			AccountBurst: 5,
+			AccountEvery: config.Duration{Duration: time.Minute},
+		},
+		Lockout: LockoutConfig{
+			Threshold: user.DefaultLockout.Threshold,
+			Duration:  config.Duration{Duration: user.DefaultLockout.Duration},
+			Max:       config.Duration{Duration: user.DefaultLockout.Max},
+		},
+		HealthCheckTimeout: config.Duration{Duration: 2 * time.Second},
+	}
+}
+
+// settings binds the fields of c to their flags and env vars.
+func settings(c *Config) []config.Setting {
+	return []config.Setting{
+		{
+			Flag: "db-driver", Env: "DB_DRIVER", Value: config.String(&c.DB.Driver),
+			Usage: "Name of the database driver: postgres, sqlite3 for a single node, or inmem to keep everything in memory for demos",
+		},
+		{
+			Flag: "db-source", Env: "DB_SOURCE", Value: config.String(&c.DB.Source), Redact: config.SecretSource,
+			Usage: "Database source to connect to.e.g: user=<user> password=<password> dbname=<dbname>, or the file for sqlite3",
+		},
+		{
+			Flag: "db-max-open-conns", Env: "DB_MAX_OPEN_CONNS", Value: config.Int(&c.DB.MaxOpenConns),
+			Usage: "Maximum number of open connections to the database shared by all the services",
+		},
+		{
+			Flag: "db-max-idle-conns", Env: "DB_MAX_IDLE_CONNS", Value: config.Int(&c.DB.MaxIdleConns),
+			Usage: "Maximum number of idle connections kept in the pool",
+		},
+		{
+			Flag: "db-conn-max-lifetime", Env: "DB_CONN_MAX_LIFETIME", Value: config.DurationOf(&c.DB.ConnMaxLifetime),
+			Usage: "Maximum time a connection is reused. e.g: 30m",
+		},
+		{
+			Flag: "db-conn-max-idle-time", Env: "DB_CONN_MAX_IDLE_TIME", Value: config.DurationOf(&c.DB.ConnMaxIdleTime),
+			Usage: "Maximum time a connection stays idle in the pool. e.g: 5m",
+		},
+		{
+			Flag: "db-replica-sources", Env: "DB_REPLICA_SOURCES", Value: config.List(&c.DB.ReplicaSources), Redact: redactSources,
+			Usage: "Comma separated sources of read replicas catalog reads are spread over. The primary serves them if none is healthy",
+		},
+		{
+			Flag: "db-replica-check-interval", Env: "DB_REPLICA_CHECK_INTERVAL", Value: config.DurationOf(&c.DB.ReplicaCheckInterval),
+			Usage: "How often read replicas are health checked. e.g: 10s",
+		},
+		{
+			Flag: "allow-pending-migrations", Env: "ALLOW_PENDING_MIGRATIONS", Value: config.Bool(&c.DB.AllowPendingMigrations),
+			Usage: "Start even if the database has pending schema migrations. Apply them with: bookctl migrate up",
+		},
+		{
+			Flag: "purge-retention", Env: "PURGE_RETENTION", Value: config.DurationOf(&c.Purge.Retention),
+			Usage: "How long deleted users, books and orders can be restored before they are purged for good. 0 disables purging",
+		},
+		{
+			Flag: "purge-interval", Env: "PURGE_INTERVAL", Value: config.DurationOf(&c.Purge.Interval),
+			Usage: "How often deleted entities past the retention are purged. e.g: 1h",
+		},
+		{
+			Flag: "cache", Env: "CACHE", Value: config.String(&c.Cache.Backend),
+			Usage: "Where catalog reads are cached: lru in process, redis shared by all the nodes, or none",
+		},
+		{
+			Flag: "cache-size", Env: "CACHE_SIZE", Value: config.Int(&c.Cache.Size),
+			Usage: "Maximum number of results kept by the lru cache",
+		},
+		{
+			Flag: "cache-ttl-get", Env: "CACHE_TTL_GET", Value: config.DurationOf(&c.Cache.TTLGet),
+			Usage: "How long a book is cached. 0 disables caching it",
+		},
+		{
+			Flag: "cache-ttl-search", Env: "CACHE_TTL_SEARCH", Value: config.DurationOf(&c.Cache.TTLSearch),
+			Usage: "How long search results are cached. 0 disables caching them",
+		},
+		{
+			Flag: "cache-ttl-list", Env: "CACHE_TTL_LIST", Value: config.DurationOf(&c.Cache.TTLList),
+			Usage: "How long pages of the catalog are cached. 0 disables caching them",
+		},
+		{
+			Flag: "redis-addr", Env: "REDIS_ADDR", Value: config.String(&c.Redis.Addr),
+			Usage: "Address of the redis server of the cache and rate limits. e.g: localhost:6379",
+		},
+		{
+			Flag: "redis-password", Env: "REDIS_PASSWORD", Value: config.String(&c.Redis.Password), Redact: config.Secret,
+			Usage: "Password of the redis server",
+		},
+		{
+			Flag: "redis-db", Env: "REDIS_DB", Value: config.Int(&c.Redis.DB),
+			Usage: "Database number of the redis server",
+		},
+		{
+			Flag: "rate-limit-store", Env: "RATE_LIMIT_STORE", Value: config.String(&c.RateLimit.Store),
+			Usage: "Where the rate limits of logins and password resets are kept: memory per node, redis shared by all the nodes, or none",
+		},
+		{
+			Flag: "rate-limit-ip-burst", Env: "RATE_LIMIT_IP_BURST", Value: config.Int(&c.RateLimit.IPBurst),
+			Usage: "Logins and password resets a client IP can try at once",
+		},
+		{
+			Flag: "rate-limit-ip-every", Env: "RATE_LIMIT_IP_EVERY", Value: config.DurationOf(&c.RateLimit.IPEvery),
+			Usage: "How often a client IP can try one more login or password reset once its burst is spent. e.g: 3s",
+		},
+		{
+			Flag: "rate-limit-account-burst", Env: "RATE_LIMIT_ACCOUNT_BURST", Value: config.Int(&c.RateLimit.AccountBurst),
+			Usage: "Logins an account can be tried with at once",
+		},
+		{
+			Flag: "rate-limit-account-every", Env: "RATE_LIMIT_ACCOUNT_EVERY", Value: config.DurationOf(&c.RateLimit.AccountEvery),
+			Usage: "How often an account can be tried once more once its burst is spent. e.g: 1m",
+		},
+		{
+			Flag: "lockout-threshold", Env: "LOCKOUT_THRESHOLD", Value: config.Int(&c.Lockout.Threshold),
+			Usage: "Failed logins in a row locking the account out. 0 never locks",
+		},
+		{
+			Flag: "lockout-duration", Env: "LOCKOUT_DURATION", Value: config.DurationOf(&c.Lockout.Duration),
+			Usage: "How long the first lockout lasts, doubled by every failed login after. e.g: 1m",
+		},
+		{
+			Flag: "lockout-max", Env: "LOCKOUT_MAX", Value: config.DurationOf(&c.Lockout.Max),
+			Usage: "Maximum duration of a lockout. e.g: 1h",
+		},
+		{
+			Flag: "http-addr", Env: "HTTP_ADDR", Value: config.String(&c.HTTP.Addr),
+			Usage: "http address to listen to e.g: 0.0.0.0:8080",
+		},
+		{
+			Flag: "http-read-timeout", Env: "HTTP_READ_TIMEOUT", Value: config.DurationOf(&c.HTTP.ReadTimeout),
+			Usage: "Maximum time to read a request, body included. 0 is no limit",
+		},
+		{
+			Flag: "http-write-timeout", Env: "HTTP_WRITE_TIMEOUT", Value: config.DurationOf(&c.HTTP.WriteTimeout),
+			Usage: "Maximum time to write a response, from the end of the request headers. 0 is no limit",
+		},
+		{
+			Flag: "http-idle-timeout", Env: "HTTP_IDLE_TIMEOUT", Value: config.DurationOf(&c.HTTP.IdleTimeout),
+			Usage: "Maximum time a keep-alive connection waits for the next request",
+		},
+		{
+			Flag: "http-trusted-proxies", Env: "HTTP_TRUSTED_PROXIES", Value: config.List(&c.HTTP.TrustedProxies),
+			Usage: "Comma separated addresses or networks of the proxies in front, whose X-Forwarded-For tells the client IP. e.g: 10.0.0.0/8",
+		},
+		{
+			Flag: "shutdown-delay", Env: "SHUTDOWN_DELAY", Value: config.DurationOf(&c.HTTP.ShutdownDelay),
+			Usage: "How long /readyz fails before the server stops accepting connections on SIGTERM, for load balancers to notice",
+		},
+		{
+			Flag: "shutdown-timeout", Env: "SHUTDOWN_TIMEOUT", Value: config.DurationOf(&c.HTTP.ShutdownTimeout),
+			Usage: "Maximum time in-flight requests have to finish on SIGTERM before the server closes",
+		},
+		{
+			Flag: "log-format", Env: "LOG_FORMAT", Value: config.String(&c.Log.Format),
+			Usage: "Format of the logs: logfmt or json",
+		},
+		{
+			Flag: "trace-exporter", Env: "TRACE_EXPORTER", Value: config.String(&c.Trace.Exporter),
+			Usage: "Where spans are exported: none, stdout for local use, or otlp to a collector",
+		},
+		{
+			Flag: "trace-otlp-endpoint", Env: "TRACE_OTLP_ENDPOINT", Value: config.String(&c.Trace.OTLPEndpoint),
+			Usage: "host:port of the OTLP/HTTP collector. e.g: localhost:4318, OTEL_EXPORTER_OTLP_ENDPOINT if empty",
+		},
+		{
+			Flag: "trace-otlp-insecure", Env: "TRACE_OTLP_INSECURE", Value: config.Bool(&c.Trace.OTLPInsecure),
+			Usage: "Send spans to the OTLP collector over plain http",
+		},
+		{
+			Flag: "trace-sample-ratio", Env: "TRACE_SAMPLE_RATIO", Value: config.Float(&c.Trace.SampleRatio),
+			Usage: "Ratio of the traces started here that are sampled, from 0 to 1",
+		},
+		{
+			Flag: "health-check-timeout", Env: "HEALTH_CHECK_TIMEOUT", Value: config.DurationOf(&c.HealthCheckTimeout),
+			Usage: "Maximum time every dependency check of /readyz has to pass",
+		},
+		{
+			Flag: "rates-file", Env: "RATES_FILE", Value: config.String(&c.RatesFile),
+			Usage: "JSON file with initial currency exchange rates. e.g: rates.json",
+		},
+		{
+			Flag: "tax-rates-file", Env: "TAX_RATES_FILE", Value: config.String(&c.TaxRatesFile),
+			Usage: "JSON file with tax rules by billing country, region and product class. e.g: tax.json",
+		},
+		{
+			Flag: "shipping-rates-file", Env: "SHIPPING_RATES_FILE", Value: config.String(&c.ShippingRatesFile),
+			Usage: "JSON file with shipping methods and their rates by destination and weight. e.g: shipping.json",
+		},
+	}
+}
+
+// redactSources redacts the password of every comma separated source.
+func redactSources(value string) string {
+	sources := strings.Split(value, ",")
+	for i := range sources {
+		sources[i] = config.SecretSource(sources[i])
+	}
+	return strings.Join(sources, ",")
+}
+
+// Validate reports every invalid setting at once, by its flag name.
+func (c Config) Validate() error {
+	var p config.Problems
+	switch c.DB.Driver {
+	case "inmem":
+	case "postgres", "sqlite3":
+		p.Check(c.DB.Source != "", "db-source is required by the %s driver, e.g: user=<user> password=<password> dbname=<dbname>", c.DB.Driver)
+	default:
+		p.Check(false, "db-driver %q is unknown, want postgres, sqlite3 or inmem", c.DB.Driver)
+	}
+	p.Check(c.DB.MaxOpenConns > 0, "db-max-open-conns must be positive, got %d", c.DB.MaxOpenConns)
+	p.Check(c.DB.MaxIdleConns >= 0, "db-max-idle-conns must not be negative, got %d", c.DB.MaxIdleConns)
+	p.Check(c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db-max-idle-conns %d must not exceed db-max-open-conns %d", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	p.Check(c.DB.ReplicaCheckInterval.Duration > 0 || len(c.DB.ReplicaSources) == 0, "db-replica-check-interval must be positive with replicas")
+
+	switch c.Cache.Backend {
+	case "none", "redis":
+	case "lru":
+		p.Check(c.Cache.Size > 0, "cache-size must be positive, got %d", c.Cache.Size)
+	default:
+		p.Check(false, "cache %q is unknown, want lru, redis or none", c.Cache.Backend)
+	}
+	switch c.RateLimit.Store {
+	case "memory", "redis", "none":
+	default:
+		p.Check(false, "rate-limit-store %q is unknown, want memory, redis or none", c.RateLimit.Store)
+	}
+	if c.Cache.Backend == "redis" || c.RateLimit.Store == "redis" {
+		p.Check(c.Redis.Addr != "", "redis-addr is required by the redis cache and rate limits")
+		p.Check(c.Redis.DB >= 0, "redis-db must not be negative, got %d", c.Redis.DB)
+	}
+	p.Check(c.RateLimit.IPBurst >= 0, "rate-limit-ip-burst must not be negative, got %d", c.RateLimit.IPBurst)
+	p.Check(c.RateLimit.AccountBurst >= 0, "rate-limit-account-burst must not be negative, got %d", c.RateLimit.AccountBurst)
+	p.Check(c.Lockout.Threshold >= 0, "lockout-threshold must not be negative, got %d", c.Lockout.Threshold)
+	p.Check(c.Lockout.Max.Duration >= c.Lockout.Duration.Duration, "lockout-max %v must not be less than lockout-duration %v", c.Lockout.Max, c.Lockout.Duration)
+	_, err := transport.ParseCIDRs(c.HTTP.TrustedProxies)
+	p.Check(err == nil, "http-trusted-proxies: %v", err)
+
+	p.Check(c.Log.Format == logging.Logfmt || c.Log.Format == logging.JSON, "log-format %q is unknown, want logfmt or json", c.Log.Format)
+	switch c.Trace.Exporter {
+	case tracing.None, tracing.Stdout, tracing.OTLP:
+	default:
+		p.Check(false, "trace-exporter %q is unknown, want none, stdout or otlp", c.Trace.Exporter)
+	}
+	p.Check(c.Trace.SampleRatio >= 0 && c.Trace.SampleRatio <= 1, "trace-sample-ratio must be from 0 to 1, got %v", c.Trace.SampleRatio)
+	p.Check(c.HTTP.Addr != "", "http-addr is required, e.g: 0.0.0.0:8080")
+	p.Check(c.Purge.Interval.Duration > 0 || c.Purge.Retention.Duration == 0, "purge-interval must be positive when purging")
+	p.Check(c.HealthCheckTimeout.Duration > 0, "health-check-timeout must be positive")
+	for _, d := range []struct {
+		flag  string
+		value config.Duration
+	}{
+		{"db-conn-max-lifetime", c.DB.ConnMaxLifetime},
+		{"db-conn-max-idle-time", c.DB.ConnMaxIdleTime},
+		{"cache-ttl-get", c.Cache.TTLGet},
+		{"cache-ttl-search", c.Cache.TTLSearch},
+		{"cache-ttl-list", c.Cache.TTLList},
+		{"http-read-timeout", c.HTTP.ReadTimeout},
+		{"http-write-timeout", c.HTTP.WriteTimeout},
+		{"http-idle-timeout", c.HTTP.IdleTimeout},
+		{"shutdown-delay", c.HTTP.ShutdownDelay},
+		{"shutdown-timeout", c.HTTP.ShutdownTimeout},
+		{"purge-retention", c.Purge.Retention},
+		{"rate-limit-ip-every", c.RateLimit.IPEvery},
+		{"rate-limit-account-every", c.RateLimit.AccountEvery},
+		{"lockout-duration", c.Lockout.Duration},
+	} {
+		p.Check(d.value.Duration >= 0, "%s must not be negative, got %v", d.flag, d.value)
+	}
+	return p.Err()
+}
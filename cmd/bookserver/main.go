@@ -13,123 +13,382 @@ import (
 
 	kitlog "github.com/go-kit/kit/log"
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/cache"
 	"github.com/kavirajk/bookshop/catalog"
-	"github.com/kavirajk/bookshop/db/postgres"
+	"github.com/kavirajk/bookshop/config"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/db/migrate"
+	"github.com/kavirajk/bookshop/db/sqldb"
+	"github.com/kavirajk/bookshop/exchange"
+	"github.com/kavirajk/bookshop/health"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/metrics"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/openapi"
 	"github.com/kavirajk/bookshop/order"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/ratelimit"
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/kavirajk/bookshop/tax"
+	"github.com/kavirajk/bookshop/tracing"
+	"github.com/kavirajk/bookshop/transport"
 	"github.com/kavirajk/bookshop/user"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
 	var (
-		dbDriver = flag.String(
-			"db-driver", envString("DB_DRIVER", "postgres"),
-			"Name of the database driver. e.g: postgres",
+		configFile = flag.String(
+			"config", os.Getenv("CONFIG_FILE"),
+			"YAML or TOML file with the settings, env vars and flags override it. e.g: bookserver.yaml (env CONFIG_FILE)",
 		)
-		dbSource = flag.String(
-			"db-source", envString("DB_SOURCE", ""),
-			"Database source to connect to.e.g: user=<user> password=<password> dbname=<dbname>",
-		)
-		listenAddr = flag.String(
-			"http-addr", envString("HTTP_ADDR", "0.0.0.0:8080"),
-			"http address to listen to e.g: 0.0.0.0:8080",
+		printConfig = flag.Bool(
+			"print-config", false,
+			"Print the settings in effect, secrets redacted, and exit",
 		)
 	)
+	// Flags parse into their own copy of the defaults, so that the help
+	// shows them and only the flags given override the file and env vars.
+	flags := defaultConfig()
+	config.Register(flag.CommandLine, settings(&flags))
 	flag.Parse()
 
-	var logger kitlog.Logger
-	logger = kitlog.NewLogfmtLogger(os.Stderr)
-	ctx := context.Background()
+	cfg := defaultConfig()
+	if *configFile != "" {
+		if err := config.Load(*configFile, &cfg); err != nil {
+			log.Fatalf("error loading config: %v\n", err)
+		}
+	}
+	if err := config.Apply(flag.CommandLine, settings(&cfg), os.LookupEnv); err != nil {
+		log.Fatalf("%v\n", err)
+	}
+	if *printConfig {
+		printed := cfg
+		printed.DB.ReplicaSources = append([]string(nil), cfg.DB.ReplicaSources...)
+		config.Redact(settings(&printed))
+		b, err := config.Marshal(printed)
+		if err != nil {
+			log.Fatalf("error printing config: %v\n", err)
+		}
+		os.Stdout.Write(b)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("%v\n", err)
+	}
 
-	if *dbSource == "" {
-		fmt.Println("db-source argument is missing. Type --help for more info")
-		os.Exit(1)
+	logger, err := logging.New(os.Stderr, cfg.Log.Format)
+	if err != nil {
+		log.Fatalf("%v\n", err)
 	}
+	// Registered first, so that a failing server exits once the rest of the
+	// teardown is done.
+	failed := false
+	defer func() {
+		if failed {
+			os.Exit(1)
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	urepo, err := postgres.NewUserRepo(*dbDriver, *dbSource)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Service:     "bookserver",
+		Exporter:    cfg.Trace.Exporter,
+		Endpoint:    cfg.Trace.OTLPEndpoint,
+		Insecure:    cfg.Trace.OTLPInsecure,
+		SampleRatio: cfg.Trace.SampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("error creating user repo: %v\n", err)
+		log.Fatalf("%v\n", err)
+	}
+
+	checks := health.New(cfg.HealthCheckTimeout.Duration)
+
+	var (
+		urepo user.Repo
+		crepo catalog.Repo
+		orepo order.Repo
+		prepo promotion.Repo
+		uow   db.UnitOfWork
+	)
+	switch cfg.DB.Driver {
+	case "inmem":
+		log.Println("bookserver: using in memory repos, nothing is persisted")
+		urepo = inmem.NewUserRepo()
+		crepo = inmem.NewCatalogRepo()
+		orepo = inmem.NewOrderRepo()
+		prepo = inmem.NewPromotionRepo()
+		uow = db.NoTx
+	default:
+		database, err := db.Open(cfg.DB.Driver, cfg.DB.Source, db.PoolOptions{
+			MaxOpenConns:    cfg.DB.MaxOpenConns,
+			MaxIdleConns:    cfg.DB.MaxIdleConns,
+			ConnMaxLifetime: cfg.DB.ConnMaxLifetime.Duration,
+			ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime.Duration,
+
+			ReplicaCheckInterval: cfg.DB.ReplicaCheckInterval.Duration,
+		}, cfg.DB.ReplicaSources...)
+		if err != nil {
+			log.Fatalf("error connecting to db: %v\n", err)
+		}
+		defer database.Close()
+
+		if err := checkMigrations(ctx, database, cfg.DB.AllowPendingMigrations); err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		urepo = sqldb.NewUserRepo(database)
+		crepo = sqldb.NewCatalogRepo(database)
+		orepo = sqldb.NewOrderRepo(database)
+		prepo = sqldb.NewPromotionRepo(database)
+		uow = database
+		stdprometheus.MustRegister(metrics.DBPool(database.SQL()))
+
+		ping := health.CheckFunc(database.Ping)
+		checks.Register(
+			health.Check{Service: "users", Name: "db", Checker: ping},
+			health.Check{Service: "catalog", Name: "db", Checker: ping},
+			health.Check{Service: "catalog", Name: "db-replicas", Checker: health.CheckFunc(database.CheckReplicas), Optional: true},
+			health.Check{Service: "orders", Name: "db", Checker: ping},
+			health.Check{Service: "promotions", Name: "db", Checker: ping},
+		)
+	}
+
+	queries := metrics.Queries()
+	urepo = user.InstrumentingRepo(urepo, queries)
+	crepo = catalog.InstrumentingRepo(crepo, queries)
+	orepo = order.InstrumentingRepo(orepo, queries)
+	prepo = promotion.InstrumentingRepo(prepo, queries)
+
+	// One redis client serves the cache and the rate limits.
+	var redis *cache.Redis
+	if cfg.Cache.Backend == "redis" || cfg.RateLimit.Store == "redis" {
+		redis = cache.NewRedis(cfg.Redis.Addr, cache.RedisOptions{Password: cfg.Redis.Password, DB: cfg.Redis.DB})
+		defer redis.Close()
+		if err := redis.Ping(ctx); err != nil {
+			log.Printf("bookserver: redis unreachable, cache reads and rate limits bypass it until it is back: %v\n", err)
+		}
+	}
+
+	var bookCache cache.Cache
+	switch cfg.Cache.Backend {
+	case "none":
+	case "lru":
+		bookCache = cache.NewLRU(cfg.Cache.Size)
+	case "redis":
+		bookCache = redis
+		checks.Register(health.Check{Service: "catalog", Name: "cache", Checker: health.CheckFunc(redis.Ping), Optional: true})
+	default:
+		log.Fatalf("unknown cache %q\n", cfg.Cache.Backend)
+	}
+	if bookCache != nil {
+		crepo = catalog.InvalidatingRepo(crepo, bookCache, kitlog.With(logger, "component", "catalog"))
+	}
+
+	var limits ratelimit.Store
+	switch cfg.RateLimit.Store {
+	case "none":
+	case "memory":
+		limits = ratelimit.NewMemory()
+	case "redis":
+		limits = ratelimit.NewRedis(redis)
+	default:
+		log.Fatalf("unknown rate limit store %q\n", cfg.RateLimit.Store)
+	}
+	blocked := kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "ratelimit",
+		Name:      "blocked_total",
+		Help:      "Number of requests over a rate limit by limiter",
+	}, []string{"limiter"})
+
+	var rates money.Rates
+	if cfg.RatesFile != "" {
+		rates, err = money.LoadRatesFile(cfg.RatesFile)
+		if err != nil {
+			log.Fatalf("error loading exchange rates: %v\n", err)
+		}
 	}
 
-	crepo, err := postgres.NewCatalogRepo(*dbDriver, *dbSource)
+	taxes, err := tax.NewTable(nil)
+	if cfg.TaxRatesFile != "" {
+		taxes, err = tax.LoadTableFile(cfg.TaxRatesFile)
+	}
 	if err != nil {
-		log.Fatalf("error creating user repo: %v\n", err)
+		log.Fatalf("error loading tax rates: %v\n", err)
 	}
 
-	orepo, err := postgres.NewOrderRepo(*dbDriver, *dbSource)
+	shippingRates, err := shipping.NewTable(nil)
+	if cfg.ShippingRatesFile != "" {
+		shippingRates, err = shipping.LoadTableFile(cfg.ShippingRatesFile)
+	}
 	if err != nil {
-		log.Fatalf("error creating user repo: %v\n", err)
+		log.Fatalf("error loading shipping rates: %v\n", err)
 	}
 
-	fieldKeys := []string{"method", "error"}
+	var xs exchange.Service
+	xs = exchange.NewService(rates)
+	xs = exchange.LoggingMiddleware(kitlog.With(logger, "component", "exchange"))(xs)
+	xs = exchange.InstrumentingMiddleware(metrics.Service("exchange"))(xs)
+	xs = exchange.TracingMiddleware(tracing.Tracer())(xs)
 
 	var us user.Service
-	us = user.NewService(urepo)
-	us = user.LoggingMiddleware(kitlog.NewContext(logger).With("component", "user"))(us)
-	us = user.InstrumentingMiddleware(
+	us = user.NewService(urepo, user.Lockout{
+		Threshold: cfg.Lockout.Threshold,
+		Duration:  cfg.Lockout.Duration.Duration,
+		Max:       cfg.Lockout.Max.Duration,
+	})
+	us = user.RateLimitingMiddleware(
+		ratelimit.Limiter{Name: "login_ip", Store: limits, Blocked: blocked, Limit: ratelimit.Limit{
+			Burst: cfg.RateLimit.IPBurst,
+			Every: cfg.RateLimit.IPEvery.Duration,
+		}},
+		ratelimit.Limiter{Name: "login_account", Store: limits, Blocked: blocked, Limit: ratelimit.Limit{
+			Burst: cfg.RateLimit.AccountBurst,
+			Every: cfg.RateLimit.AccountEvery.Duration,
+		}},
+	)(us)
+	us = user.LoggingMiddleware(kitlog.With(logger, "component", "user"))(us)
+	us = user.InstrumentingMiddleware(metrics.Service("user"))(us)
+	us = user.BusinessMetricsMiddleware(
 		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
-			Namespace: "api",
+			Namespace: metrics.Namespace,
 			Subsystem: "user_service",
-			Name:      "request_count",
-			Help:      "Number of requests received",
-		}, fieldKeys),
-		kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
-			Namespace: "api",
+			Name:      "registrations_total",
+			Help:      "Number of users registered",
+		}, nil),
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metrics.Namespace,
 			Subsystem: "user_service",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds",
-		}, fieldKeys),
+			Name:      "failed_logins_total",
+			Help:      "Number of logins with wrong credentials by reason",
+		}, []string{"reason"}),
 	)(us)
+	us = user.TracingMiddleware(tracing.Tracer())(us)
 
 	var cs catalog.Service
-	cs = catalog.NewService(crepo)
-	cs = catalog.LoggingMiddleware(kitlog.NewContext(logger).With("component", "catalog"))(cs)
-	cs = catalog.InstrumentingMiddleware(
-		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
-			Namespace: "api",
-			Subsystem: "catalog_service",
-			Name:      "request_count",
-			Help:      "Number of requests received",
-		}, fieldKeys),
-		kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
-			Namespace: "api",
-			Subsystem: "catalog_service",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds",
-		}, fieldKeys),
-	)(cs)
-
-	var os order.Service
-	os = order.NewService(orepo)
-	os = order.LoggingMiddleware(kitlog.NewContext(logger).With("component", "order"))(os)
-	os = order.InstrumentingMiddleware(
+	cs = catalog.NewService(crepo, xs)
+	if bookCache != nil {
+		cs = catalog.CachingMiddleware(bookCache, catalog.CacheTTLs{
+			Get:    cfg.Cache.TTLGet.Duration,
+			Search: cfg.Cache.TTLSearch.Duration,
+			List:   cfg.Cache.TTLList.Duration,
+		})(cs)
+	}
+	cs = catalog.LoggingMiddleware(kitlog.With(logger, "component", "catalog"))(cs)
+	cs = catalog.InstrumentingMiddleware(metrics.Service("catalog"))(cs)
+	cs = catalog.TracingMiddleware(tracing.Tracer())(cs)
+
+	var ps promotion.Service
+	ps = promotion.NewService(prepo)
+	ps = promotion.LoggingMiddleware(kitlog.With(logger, "component", "promotion"))(ps)
+	ps = promotion.InstrumentingMiddleware(metrics.Service("promotion"))(ps)
+	ps = promotion.TracingMiddleware(tracing.Tracer())(ps)
+
+	var ords order.Service
+	ords = order.NewService(orepo, uow, crepo, xs, ps, taxes, shippingRates)
+	ords = order.LoggingMiddleware(kitlog.With(logger, "component", "order"))(ords)
+	ords = order.InstrumentingMiddleware(metrics.Service("order"))(ords)
+	ords = order.BusinessMetricsMiddleware(
 		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
-			Namespace: "api",
+			Namespace: metrics.Namespace,
 			Subsystem: "order_service",
-			Name:      "request_count",
-			Help:      "Number of requests received",
-		}, fieldKeys),
-		kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
-			Namespace: "api",
+			Name:      "orders_placed_total",
+			Help:      "Number of orders placed",
+		}, nil),
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: metrics.Namespace,
 			Subsystem: "order_service",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds",
-		}, fieldKeys),
-	)(os)
+			Name:      "revenue_total",
+			Help:      "Total price of the orders placed by currency, in major units",
+		}, []string{"currency"}),
+	)(ords)
+	ords = order.TracingMiddleware(tracing.Tracer())(ords)
+
+	if cfg.Purge.Retention.Duration > 0 {
+		purge := db.PurgeJob{
+			Retention: cfg.Purge.Retention.Duration,
+			Purgers:   map[string]db.Purger{"users": urepo, "books": crepo, "orders": orepo},
+			Logger:    kitlog.With(logger, "component", "purge"),
+		}
+		go purge.Run(ctx, cfg.Purge.Interval.Duration)
+	}
+
+	httpLogger := kitlog.With(logger, "component", "http")
+
+	// Every service registers its routes under its prefix on one router, so
+	// that a route is served exactly where the spec says.
+	router := mux.NewRouter()
+	transport.Mount(router,
+		user.MakeRoutes(ctx, us, httpLogger),
+		catalog.MakeRoutes(ctx, cs, httpLogger),
+		order.MakeRoutes(ctx, ords, httpLogger),
+		exchange.MakeRoutes(ctx, xs, httpLogger),
+		promotion.MakeRoutes(ctx, ps, httpLogger),
+	)
 
-	httpLogger := kitlog.NewContext(logger).With("component", "http")
-	mux := http.NewServeMux()
+	spec := openapi.Build(
+		openapi.Info{Title: "Bookshop", Version: "v1"},
+		user.OpenAPI(), catalog.OpenAPI(), order.OpenAPI(), exchange.OpenAPI(), promotion.OpenAPI(),
+	)
+	router.Handle("/openapi.json", openapi.Handler(spec)).Methods("GET")
+	router.Handle("/routes", transport.RoutesHandler(router)).Methods("GET")
+	router.Handle("/metrics", stdprometheus.Handler())
+	router.Handle("/healthz", checks.LiveHandler()).Methods("GET")
+	router.Handle("/readyz", checks.ReadyHandler()).Methods("GET")
 
-	userHandler := user.MakeHTTPHandler(ctx, us, httpLogger)
-	catalogHandler := catalog.MakeHTTPHandler(ctx, cs, httpLogger)
-	orderHandler := order.MakeHTTPHandler(ctx, os, httpLogger)
+	// Validate checked them already.
+	trustedProxies, _ := transport.ParseCIDRs(cfg.HTTP.TrustedProxies)
 
-	mux.Handle("/users/v1/", userHandler)
-	mux.Handle("/catalog/v1/", catalogHandler)
-	mux.Handle("/order/v1/", orderHandler)
+	if err := transport.CheckRoutes(router); err != nil {
+		log.Fatalf("bookserver: %v\n", err)
+	}
 
-	mux.Handle("/metrics", stdprometheus.Handler())
-	http.Handle("/", mux)
+	// Requests are authenticated by the auth token of their user once an
+	// endpoint requires permissions.
+	handler := transport.Authenticate(metrics.HTTP(router, metrics.NewHTTPMetrics()), user.Authenticator(us))
+	srv := &http.Server{
+		Addr:         cfg.HTTP.Addr,
+		Handler:      transport.RequestID(transport.ClientIP(tracing.HTTP(handler), trustedProxies...)),
+		ReadTimeout:  cfg.HTTP.ReadTimeout.Duration,
+		WriteTimeout: cfg.HTTP.WriteTimeout.Duration,
+		IdleTimeout:  cfg.HTTP.IdleTimeout.Duration,
+	}
+	if err := serve(srv, checks, cfg.HTTP.ShutdownDelay.Duration, cfg.HTTP.ShutdownTimeout.Duration); err != nil {
+		log.Printf("bookserver: %v\n", err)
+		failed = true
+	}
+	// In-flight requests are done or cut, stop the jobs before the deferred
+	// closing of the pools.
+	cancel()
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout.Duration)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("bookserver: error flushing spans: %v\n", err)
+	}
+}
 
-	log.Println("bookserver: Listening on", *listenAddr)
-	log.Fatal(http.ListenAndServe(*listenAddr, nil))
+// checkMigrations fails if the database schema is behind this build,
+// unless pending migrations are allowed.
+func checkMigrations(ctx context.Context, database *db.DB, allowPending bool) error {
+	migrations, err := sqldb.Migrations(database.Dialect())
+	if err != nil {
+		return fmt.Errorf("error loading migrations: %v", err)
+	}
+	pending, err := migrate.New(database.SQL(), database.Dialect(), migrations).Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking migrations: %v", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if !allowPending {
+		return fmt.Errorf("%d pending migrations, first %04d_%s. Run: bookctl migrate up",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	log.Printf("bookserver: starting with %d pending migrations\n", len(pending))
+	return nil
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kavirajk/bookshop/health"
+)
+
+// serve serves srv until SIGINT or SIGTERM, then shuts it down gracefully:
+// /readyz of checks fails for delay, so that load balancers stop sending
+// requests, then in-flight requests have timeout to finish. Requests still
+// running then are cut and logged, serve doesn't fail, so that the caller
+// goes on with its teardown.
+func serve(srv *http.Server, checks *health.Health, delay, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		log.Println("bookserver: Listening on", srv.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case err := <-errc:
+		return err
+	case s := <-sig:
+		log.Printf("bookserver: %v received, shutting down\n", s)
+	}
+
+	checks.Drain()
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("bookserver: error shutting down, closing in-flight requests: %v\n", err)
+		srv.Close()
+		return nil
+	}
+	log.Println("bookserver: shut down")
+	return nil
+}
@@ -0,0 +1,260 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/money"
+	"golang.org/x/sync/singleflight"
+)
+
+// generationKey holds the generation of the cached results, a part of
+// every key. Writes to the catalog are rare next to reads, so any write
+// bumps it, invalidating everything cached at once.
+const generationKey = "catalog:generation"
+
+// primaryFillWindow is how long after a write, seen as a new generation,
+// the results are cached from the primary. Replicas may not have the write
+// yet, their results would be cached in the new generation until they
+// expire.
+const primaryFillWindow = 30 * time.Second
+
+// CacheTTLs tells how long results of every method are cached.
+// 0 doesn't cache the method.
+type CacheTTLs struct {
+	Get    time.Duration
+	Search time.Duration
+	List   time.Duration
+}
+
+type cachingService struct {
+	cache  cache.Cache
+	ttls   CacheTTLs
+	flight *singleflight.Group
+	gen    *generation
+	next   Service
+}
+
+// generation is the last generation seen by the process, and when it was
+// first seen.
+type generation struct {
+	mu      sync.Mutex
+	current string
+	seen    time.Time
+}
+
+// observe records gen as seen now, and reports whether results are to be
+// read on the primary, see primaryFillWindow. The generation is unknown
+// on start, so is the first one seen.
+func (g *generation) observe(gen string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen.IsZero() || gen != g.current {
+		g.current, g.seen = gen, now
+	}
+	return now.Sub(g.seen) < primaryFillWindow
+}
+
+// CachingMiddleware caches results of Get, Search and List in c. Concurrent
+// misses of the same call share a single call to next, which isn't
+// cancelled with the caller starting it. Failing cache is bypassed. Use
+// InvalidatingRepo on the repo of next, so that writes invalidate the
+// cached results.
+func CachingMiddleware(c cache.Cache, ttls CacheTTLs) Middleware {
+	return func(next Service) Service {
+		return cachingService{
+			cache:  c,
+			ttls:   ttls,
+			flight: &singleflight.Group{},
+			gen:    &generation{},
+			next:   next,
+		}
+	}
+}
+
+func (s cachingService) Search(ctx context.Context, query string, currency money.Currency) ([]Book, error) {
+	if s.ttls.Search <= 0 {
+		return s.next.Search(ctx, query, currency)
+	}
+	var books []Book
+	err := s.cached(ctx, s.ttls.Search, &books, func(ctx context.Context) (interface{}, error) {
+		return s.next.Search(ctx, query, currency)
+	}, "search", string(currency), query)
+	if books == nil {
+		books = make([]Book, 0) // gob decodes empty slice as nil
+	}
+	return books, err
+}
+
+// listResult is the cached result of List.
+type listResult struct {
+	Books []Book
+	Total int
+}
+
+func (s cachingService) List(ctx context.Context, order string, limit, offset int, currency money.Currency) ([]Book, int, error) {
+	if s.ttls.List <= 0 {
+		return s.next.List(ctx, order, limit, offset, currency)
+	}
+	var res listResult
+	err := s.cached(ctx, s.ttls.List, &res, func(ctx context.Context) (interface{}, error) {
+		books, total, err := s.next.List(ctx, order, limit, offset, currency)
+		return listResult{Books: books, Total: total}, err
+	}, "list", string(currency), strconv.Itoa(limit), strconv.Itoa(offset), order)
+	if res.Books == nil {
+		res.Books = make([]Book, 0)
+	}
+	return res.Books, res.Total, err
+}
+
+func (s cachingService) Get(ctx context.Context, id string, currency money.Currency) (Book, error) {
+	if s.ttls.Get <= 0 {
+		return s.next.Get(ctx, id, currency)
+	}
+	var book Book
+	err := s.cached(ctx, s.ttls.Get, &book, func(ctx context.Context) (interface{}, error) {
+		return s.next.Get(ctx, id, currency)
+	}, "get", string(currency), id)
+	return book, err
+}
+
+func (s cachingService) Delete(ctx context.Context, id string) error {
+	return s.next.Delete(ctx, id)
+}
+
+func (s cachingService) ListDeleted(ctx context.Context) ([]Book, error) {
+	return s.next.ListDeleted(ctx)
+}
+
+func (s cachingService) Restore(ctx context.Context, id string) (Book, error) {
+	return s.next.Restore(ctx, id)
+}
+
+// cached decodes into dst the result of the call named by parts, calling
+// fn on a miss and caching its result for ttl. Errors are not cached.
+// Misses share the call of fn by key, so that a call started before a
+// write isn't shared with the ones after it. Fn is called with ctx
+// detached from the caller, it serves all of them. Every caller decodes
+// its own copy, so that they can't change the result shared with the
+// others.
+func (s cachingService) cached(ctx context.Context, ttl time.Duration, dst interface{}, fn func(ctx context.Context) (interface{}, error), parts ...string) error {
+	call := strings.Join(parts, ":")
+	key, primary, kerr := s.key(ctx, call)
+	if kerr == nil {
+		if b, err := s.cache.Get(ctx, key); err == nil && decode(b, dst) == nil {
+			return nil
+		}
+	} else {
+		key = call
+	}
+	ch := s.flight.DoChan(key, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		if primary {
+			ctx = db.ReadPrimary(ctx)
+		}
+		res, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, err := encode(res)
+		if err != nil {
+			return nil, err
+		}
+		if kerr == nil {
+			s.cache.Set(ctx, key, b, ttl)
+		}
+		return b, nil
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+		return decode(res.Val.([]byte), dst)
+	}
+}
+
+// key returns cache key of call in the current generation, and whether
+// its result is to be read on the primary.
+func (s cachingService) key(ctx context.Context, call string) (string, bool, error) {
+	gen, err := s.cache.Get(ctx, generationKey)
+	if err == cache.ErrMiss {
+		gen, err = []byte("0"), nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	primary := s.gen.observe(string(gen), time.Now())
+	return "catalog:" + string(gen) + ":" + call, primary, nil
+}
+
+// Books are gob encoded, their JSON leaves out authors, genres and prices.
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func decode(b []byte, dst interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(dst)
+}
+
+type invalidatingRepo struct {
+	Repo
+	cache  cache.Cache
+	logger log.Logger
+}
+
+// InvalidatingRepo returns r invalidating the results cached in c by
+// CachingMiddleware whenever books are written. Failing invalidation is
+// logged, the stale results expire with their TTL.
+func InvalidatingRepo(r Repo, c cache.Cache, logger log.Logger) Repo {
+	return invalidatingRepo{Repo: r, cache: c, logger: logger}
+}
+
+func (r invalidatingRepo) Create(ctx context.Context, book *Book) error {
+	return r.invalidate(ctx, r.Repo.Create(ctx, book))
+}
+
+func (r invalidatingRepo) Save(ctx context.Context, book *Book) error {
+	return r.invalidate(ctx, r.Repo.Save(ctx, book))
+}
+
+func (r invalidatingRepo) Delete(ctx context.Context, id string, version int64) error {
+	return r.invalidate(ctx, r.Repo.Delete(ctx, id, version))
+}
+
+func (r invalidatingRepo) Restore(ctx context.Context, id string) error {
+	return r.invalidate(ctx, r.Repo.Restore(ctx, id))
+}
+
+func (r invalidatingRepo) Drop(ctx context.Context) error {
+	return r.invalidate(ctx, r.Repo.Drop(ctx))
+}
+
+// invalidate bumps the generation of the cache unless the write failed
+// with err, and returns err. Within a unit of work the generation is
+// bumped once it commits, so that results read before can't be cached in
+// the new generation.
+func (r invalidatingRepo) invalidate(ctx context.Context, err error) error {
+	if err != nil {
+		return err
+	}
+	db.AfterCommit(ctx, func() {
+		if _, ierr := r.cache.Incr(ctx, generationKey); ierr != nil {
+			_ = logging.FromContext(ctx, r.logger).Log("method", "invalidate", "err", ierr)
+		}
+	})
+	return nil
+}
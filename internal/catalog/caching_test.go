@@ -0,0 +1,204 @@
+package catalog_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/kavirajk/bookshop/cache/cachetest"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db/inmem"
+)
+
+// countingRepo counts GetByID calls, holding their results until gate is
+// closed. Like a database, it fails the calls whose ctx is done meanwhile.
+type countingRepo struct {
+	catalog.Repo
+	calls int32
+	gate  chan struct{}
+}
+
+func (r *countingRepo) GetByID(ctx context.Context, id string) (catalog.Book, error) {
+	atomic.AddInt32(&r.calls, 1)
+	book, err := r.Repo.GetByID(ctx, id)
+	if r.gate != nil {
+		<-r.gate
+	}
+	if ctx.Err() != nil {
+		return catalog.Book{}, ctx.Err()
+	}
+	return book, err
+}
+
+// waitCalls waits until the repo got n calls.
+func (r *countingRepo) waitCalls(t *testing.T, n int) {
+	for i := 0; r.count() < n; i++ {
+		if i == 100 {
+			t.Fatalf("expected %v repo calls, got %v", n, r.count())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (r *countingRepo) count() int {
+	return int(atomic.LoadInt32(&r.calls))
+}
+
+var ttls = catalog.CacheTTLs{Get: time.Minute, Search: time.Minute, List: time.Minute}
+
+func caches(t *testing.T, fn func(t *testing.T, c cache.Cache)) {
+	t.Run("lru", func(t *testing.T) { fn(t, cache.NewLRU(100)) })
+	t.Run("redis", func(t *testing.T) {
+		c := cache.NewRedis(cachetest.NewServer(t).Addr, cache.RedisOptions{})
+		defer c.Close()
+		fn(t, c)
+	})
+}
+
+func TestCachingMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	caches(t, func(t *testing.T, c cache.Cache) {
+		repo := &countingRepo{Repo: inmem.NewCatalogRepo()}
+		books := catalog.InvalidatingRepo(repo, c, log.NewNopLogger())
+		s := catalog.CachingMiddleware(c, ttls)(catalog.NewService(books, nil))
+
+		b := catalog.Book{Title: "Dune"}
+		books.Create(ctx, &b)
+
+		t.Run("cached", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				got, err := s.Get(ctx, b.ID, "")
+				if err != nil || got.Title != "Dune" {
+					t.Fatalf("expected Dune, got %v (%v)", got.Title, err)
+				}
+			}
+			if repo.count() != 1 {
+				t.Errorf("expected 1 repo call, got %v", repo.count())
+			}
+		})
+
+		t.Run("invalidated on save", func(t *testing.T) {
+			b.Title = "Dune Messiah"
+			if err := books.Save(ctx, &b); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			got, _ := s.Get(ctx, b.ID, "")
+			if got.Title != "Dune Messiah" {
+				t.Errorf("expected Dune Messiah, got %v", got.Title)
+			}
+			if found, _ := s.Search(ctx, "messiah", ""); len(found) != 1 {
+				t.Errorf("expected 1 book, got %v", found)
+			}
+		})
+
+		t.Run("errors not cached", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if _, err := s.Get(ctx, "missing", ""); err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			}
+			if found, _ := s.Search(ctx, "missing", ""); found == nil {
+				t.Errorf("expected empty books, got nil")
+			}
+		})
+	})
+}
+
+func TestCachingMiddlewareSingleflight(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(100)
+	repo := &countingRepo{Repo: inmem.NewCatalogRepo(), gate: make(chan struct{})}
+	b := catalog.Book{Title: "Dune"}
+	repo.Create(ctx, &b)
+	s := catalog.CachingMiddleware(c, ttls)(catalog.NewService(repo, nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Get(ctx, b.ID, ""); err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let them all miss
+	close(repo.gate)
+	wg.Wait()
+
+	if repo.count() != 1 {
+		t.Errorf("expected 1 repo call, got %v", repo.count())
+	}
+}
+
+func TestCachingMiddlewareSingleflightCancel(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(100)
+	repo := &countingRepo{Repo: inmem.NewCatalogRepo(), gate: make(chan struct{})}
+	b := catalog.Book{Title: "Dune"}
+	repo.Create(ctx, &b)
+	s := catalog.CachingMiddleware(c, ttls)(catalog.NewService(repo, nil))
+
+	cctx, cancel := context.WithCancel(ctx)
+	first := make(chan error)
+	go func() {
+		_, err := s.Get(cctx, b.ID, "")
+		first <- err
+	}()
+	repo.waitCalls(t, 1)
+	second := make(chan error)
+	go func() {
+		_, err := s.Get(ctx, b.ID, "")
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond) // let it join the call of the first
+	cancel()
+	select {
+	case err := <-first:
+		if err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected cancelled call to return before the shared call")
+	}
+	close(repo.gate)
+	if err := <-second; err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if repo.count() != 1 {
+		t.Errorf("expected 1 repo call, got %v", repo.count())
+	}
+}
+
+func TestCachingMiddlewareSingleflightWrite(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(100)
+	repo := &countingRepo{Repo: inmem.NewCatalogRepo(), gate: make(chan struct{})}
+	books := catalog.InvalidatingRepo(repo, c, log.NewNopLogger())
+	b := catalog.Book{Title: "Dune"}
+	books.Create(ctx, &b)
+	s := catalog.CachingMiddleware(c, ttls)(catalog.NewService(books, nil))
+
+	go s.Get(ctx, b.ID, "")
+	repo.waitCalls(t, 1) // read before the write
+
+	b.Title = "Dune Messiah"
+	if err := books.Save(ctx, &b); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	after := make(chan catalog.Book)
+	go func() {
+		got, _ := s.Get(ctx, b.ID, "")
+		after <- got
+	}()
+	repo.waitCalls(t, 2)
+	close(repo.gate)
+	if got := <-after; got.Title != "Dune Messiah" {
+		t.Errorf("expected Dune Messiah, got %v", got.Title)
+	}
+}
@@ -3,22 +3,66 @@ package catalog
 import (
 	"strings"
 	"time"
+
+	"github.com/kavirajk/bookshop/money"
 )
 
 type Book struct {
-	ID              string     `json:"id"`
-	ISBN            string     `json:"isbn"`
-	Title           string     `json:"title"`
-	TagString       string     `json:"-"`
-	Authors         []Author   `json:"-" gorm:"many_to_many"`
-	Genres          []Genre    `json:"-" gorm:"many_to_many"`
-	Publisher       *Publisher `json:"-"`
-	PublisherID     string     `json:"-"`
-	PublicationYear string     `json:"publication_year"`
-	PublicationDate time.Time  `json:"-"`
-	SampleURL       string     `json:"-"`
-	FullURL         string     `json:"-"`
-	Price           float64    `json:"price"`
+	ID              string      `json:"id"`
+	ISBN            string      `json:"isbn"`
+	Title           string      `json:"title"`
+	TagString       string      `json:"-"`
+	Authors         []Author    `json:"-" gorm:"many2many:book_authors"`
+	Genres          []Genre     `json:"-" gorm:"many2many:book_genres"`
+	Publisher       *Publisher  `json:"-"`
+	PublisherID     string      `json:"-"`
+	PublicationYear string      `json:"publication_year"`
+	PublicationDate time.Time   `json:"-"`
+	SampleURL       string      `json:"-"`
+	FullURL         string      `json:"-"`
+	Format          Format      `json:"format"`
+	WeightGrams     int         `json:"weight_grams"` // shipping weight of print books
+	Price           money.Money `json:"price" gorm:"embedded;embedded_prefix:price_"`
+	Prices          []BookPrice `json:"-"`
+	Version         int64       `json:"version"` // bumped on every save
+	UpdatedAt       time.Time   `json:"updated_at"`
+	DeletedAt       *time.Time  `json:"deleted_at,omitempty"`
+}
+
+// BookPrice is a price explicitly set for a book in a particular currency.
+// It takes precedence over converting the base Price.
+type BookPrice struct {
+	ID     string      `json:"-"`
+	BookID string      `json:"-"`
+	Price  money.Money `json:"price" gorm:"embedded;embedded_prefix:price_"`
+}
+
+// PriceIn returns book price in currency c. Price explicitly set for c wins,
+// otherwise base Price is converted with rates.
+func (b *Book) PriceIn(c money.Currency, rates money.Rates) (money.Money, error) {
+	if c == b.Price.Currency {
+		return b.Price, nil
+	}
+	for _, p := range b.Prices {
+		if p.Price.Currency == c {
+			return p.Price, nil
+		}
+	}
+	return rates.Convert(b.Price, c, money.RoundHalfEven)
+}
+
+// Format is the physical form a book is sold in.
+type Format string
+
+const (
+	Print Format = "print"
+	Ebook Format = "ebook"
+)
+
+// Digital reports whether book is delivered electronically.
+// Books without format are treated as print.
+func (b *Book) Digital() bool {
+	return b.Format == Ebook
 }
 
 func (b *Book) Tags() []string {
@@ -2,31 +2,45 @@ package catalog
 
 import (
 	"net/http"
+	"time"
 
 	"context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/tracing"
+	"github.com/kavirajk/bookshop/validate"
 )
 
 // Endpoints combine all the catalog service endpoints under single type.
 type Endpoints struct {
-	SearchEndpoint endpoint.Endpoint
-	GetEndpoint    endpoint.Endpoint
+	SearchEndpoint      endpoint.Endpoint
+	GetEndpoint         endpoint.Endpoint
+	DeleteEndpoint      endpoint.Endpoint
+	ListDeletedEndpoint endpoint.Endpoint
+	RestoreEndpoint     endpoint.Endpoint
 }
 
 // MakeEndpoints returns Endpoints type which is the combination of
-// all the catalog service endpoints.
+// all the catalog service endpoints. Managing books needs
+// auth.ManageCatalog, or auth.ManageOwnBooks for the books of the
+// publisher of the user.
 func MakeEndpoints(s Service) Endpoints {
+	manage := auth.Require(auth.ManageCatalog, auth.ManageOwnBooks)
 	return Endpoints{
-		SearchEndpoint: MakeSearchEndpoint(s),
-		GetEndpoint:    MakeGetEndpoint(s),
+		SearchEndpoint:      tracing.Endpoint("catalog.search")(MakeSearchEndpoint(s)),
+		GetEndpoint:         tracing.Endpoint("catalog.get")(MakeGetEndpoint(s)),
+		DeleteEndpoint:      tracing.Endpoint("catalog.delete")(manage(MakeDeleteEndpoint(s))),
+		ListDeletedEndpoint: tracing.Endpoint("catalog.list_deleted")(manage(MakeListDeletedEndpoint(s))),
+		RestoreEndpoint:     tracing.Endpoint("catalog.restore")(manage(MakeRestoreEndpoint(s))),
 	}
 }
 
 func MakeSearchEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(searchRequest)
-		books, e := s.Search(ctx, req.Q)
+		books, e := s.Search(ctx, req.Q, req.Currency)
 		if e != nil {
 			return searchResponse{Books: make([]Book, 0), Error: e}, nil
 		}
@@ -37,16 +51,28 @@ func MakeSearchEndpoint(s Service) endpoint.Endpoint {
 func MakeGetEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(getRequest)
-		book, e := s.Get(ctx, req.ID)
+		book, e := s.Get(ctx, req.ID, req.Currency)
 		if e != nil {
 			return getResponse{Book: nil, Error: e}, nil
 		}
-		return getResponse{Book: &book}, nil
+		return getResponse{Book: &book, Currency: req.Currency}, nil
 	}
 }
 
 type searchRequest struct {
-	Q string `json:"q"`
+	Q        string         `json:"q"`
+	Currency money.Currency `json:"currency"`
+}
+
+// maxQueryLength bounds the search query, in characters.
+const maxQueryLength = 200
+
+func (r searchRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("q", r.Q) {
+		v.Length("q", r.Q, 1, maxQueryLength)
+	}
+	return v.Err()
 }
 
 type searchResponse struct {
@@ -55,28 +81,128 @@ type searchResponse struct {
 	Error  error  `json:"error,omitempty"`
 }
 
-func (r searchResponse) status() int {
+func (r searchResponse) StatusCode() int {
 	return r.Status
 }
 
-func (r searchResponse) error() error {
+func (r searchResponse) Failed() error {
 	return r.Error
 }
 
+// LastModified is of the latest changed book. A listing can't tell when a
+// book left it, so only its ETag is validated.
+func (r searchResponse) LastModified() (time.Time, bool) {
+	var last time.Time
+	for _, b := range r.Books {
+		if b.UpdatedAt.After(last) {
+			last = b.UpdatedAt
+		}
+	}
+	return last, false
+}
+
 type getRequest struct {
-	ID string `json:"id"`
+	ID       string         `json:"id"`
+	Currency money.Currency `json:"currency"`
 }
 
 type getResponse struct {
-	Status int   `json:"-"`
-	Book   *Book `json:"book,omitempty"`
-	Error  error `json:"error,omitempty"`
+	Status   int            `json:"-"`
+	Book     *Book          `json:"book,omitempty"`
+	Currency money.Currency `json:"-"`
+	Error    error          `json:"error,omitempty"`
 }
 
-func (l getResponse) status() int {
+func (l getResponse) StatusCode() int {
 	return l.Status
 }
 
-func (r getResponse) error() error {
+func (r getResponse) Failed() error {
+	return r.Error
+}
+
+// Version is not sent for prices converted to Currency, they change with
+// the exchange rates without a new version. ETag is of the content then.
+func (r getResponse) Version() int64 {
+	if r.Book == nil || r.Currency != "" {
+		return 0
+	}
+	return r.Book.Version
+}
+
+func (r getResponse) LastModified() (time.Time, bool) {
+	if r.Book == nil {
+		return time.Time{}, false
+	}
+	return r.Book.UpdatedAt, true
+}
+
+func MakeDeleteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteRequest)
+		e := s.Delete(ctx, req.ID)
+		if e != nil {
+			return deleteResponse{Error: e}, nil
+		}
+		return deleteResponse{}, nil
+	}
+}
+
+func MakeListDeletedEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		books, e := s.ListDeleted(ctx)
+		if e != nil {
+			return listDeletedResponse{Books: make([]Book, 0), Error: e}, nil
+		}
+		return listDeletedResponse{Books: books}, nil
+	}
+}
+
+func MakeRestoreEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteRequest)
+		book, e := s.Restore(ctx, req.ID)
+		if e != nil {
+			return restoreResponse{Error: e}, nil
+		}
+		return restoreResponse{Book: &book}, nil
+	}
+}
+
+// deleteRequest is the book of delete and restore, from the path.
+type deleteRequest struct {
+	ID string `json:"id"`
+}
+
+type deleteResponse struct {
+	Error error `json:"error,omitempty"`
+}
+
+func (r deleteResponse) Failed() error {
 	return r.Error
 }
+
+type listDeletedResponse struct {
+	Books []Book `json:"books"`
+	Error error  `json:"error,omitempty"`
+}
+
+func (r listDeletedResponse) Failed() error {
+	return r.Error
+}
+
+type restoreResponse struct {
+	Book  *Book `json:"book,omitempty"`
+	Error error `json:"error,omitempty"`
+}
+
+func (r restoreResponse) Failed() error {
+	return r.Error
+}
+
+func (r restoreResponse) Version() int64 {
+	if r.Book == nil {
+		return 0
+	}
+	return r.Book.Version
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/money"
 )
 
 type instrmw struct {
@@ -25,35 +26,155 @@ func InstrumentingMiddleware(counter metrics.Counter, latency metrics.Histogram)
 	}
 }
 
-func (mw instrmw) Search(ctx context.Context, query string) (books []Book, err error) {
+func (mw instrmw) Search(ctx context.Context, query string, currency money.Currency) (books []Book, err error) {
 	defer func(begin time.Time) {
 		lvs := []string{"method", "search", "error", fmt.Sprint(err != nil)}
 		mw.requestCount.With(lvs...).Add(1)
 		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	books, err = mw.next.Search(ctx, query)
+	books, err = mw.next.Search(ctx, query, currency)
 	return
 }
 
-func (mw instrmw) List(ctx context.Context, order string, limit, offset int) (books []Book, total int, err error) {
+func (mw instrmw) List(ctx context.Context, order string, limit, offset int, currency money.Currency) (books []Book, total int, err error) {
 	defer func(begin time.Time) {
-		lvs := []string{"method", "search", "error", fmt.Sprint(err != nil)}
+		lvs := []string{"method", "list", "error", fmt.Sprint(err != nil)}
 		mw.requestCount.With(lvs...).Add(1)
 		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	books, total, err = mw.next.List(ctx, order, limit, offset)
+	books, total, err = mw.next.List(ctx, order, limit, offset, currency)
 	return
 }
 
-func (mw instrmw) Get(ctx context.Context, ID string) (book Book, err error) {
+func (mw instrmw) Get(ctx context.Context, ID string, currency money.Currency) (book Book, err error) {
 	defer func(begin time.Time) {
 		lvs := []string{"method", "get", "error", fmt.Sprint(err != nil)}
 		mw.requestCount.With(lvs...).Add(1)
 		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	book, err = mw.next.Get(ctx, ID)
+	book, err = mw.next.Get(ctx, ID, currency)
+	return
+}
+
+func (mw instrmw) Delete(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "delete", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	err = mw.next.Delete(ctx, id)
+	return
+}
+
+func (mw instrmw) ListDeleted(ctx context.Context) (books []Book, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "list-deleted", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	books, err = mw.next.ListDeleted(ctx)
+	return
+}
+
+func (mw instrmw) Restore(ctx context.Context, id string) (book Book, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "restore", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	book, err = mw.next.Restore(ctx, id)
+	return
+}
+
+type instrumentingRepo struct {
+	duration metrics.Histogram
+	next     Repo
+}
+
+// InstrumentingRepo returns r timing its queries in duration, labelled by
+// method and error, see metrics.Queries.
+func InstrumentingRepo(r Repo, duration metrics.Histogram) Repo {
+	return instrumentingRepo{duration: duration.With("repo", "books"), next: r}
+}
+
+func (r instrumentingRepo) observe(method string, begin time.Time, err error) {
+	r.duration.With("method", method, "error", fmt.Sprint(err != nil)).Observe(time.Since(begin).Seconds())
+}
+
+func (r instrumentingRepo) Create(ctx context.Context, book *Book) (err error) {
+	defer func(begin time.Time) { r.observe("create", begin, err) }(time.Now())
+	err = r.next.Create(ctx, book)
+	return
+}
+
+func (r instrumentingRepo) Save(ctx context.Context, book *Book) (err error) {
+	defer func(begin time.Time) { r.observe("save", begin, err) }(time.Now())
+	err = r.next.Save(ctx, book)
+	return
+}
+
+func (r instrumentingRepo) GetByID(ctx context.Context, ID string) (book Book, err error) {
+	defer func(begin time.Time) { r.observe("get_by_id", begin, err) }(time.Now())
+	book, err = r.next.GetByID(ctx, ID)
+	return
+}
+
+func (r instrumentingRepo) List(ctx context.Context, order string, limit, offset int) (books []Book, total int, err error) {
+	defer func(begin time.Time) { r.observe("list", begin, err) }(time.Now())
+	books, total, err = r.next.List(ctx, order, limit, offset)
+	return
+}
+
+func (r instrumentingRepo) Search(ctx context.Context, name string) (books []Book, err error) {
+	defer func(begin time.Time) { r.observe("search", begin, err) }(time.Now())
+	books, err = r.next.Search(ctx, name)
+	return
+}
+
+func (r instrumentingRepo) GetByISBN(ctx context.Context, ISBN string) (book Book, err error) {
+	defer func(begin time.Time) { r.observe("get_by_isbn", begin, err) }(time.Now())
+	book, err = r.next.GetByISBN(ctx, ISBN)
+	return
+}
+
+func (r instrumentingRepo) ListByAuthor(ctx context.Context, authorID string) (books []Book, err error) {
+	defer func(begin time.Time) { r.observe("list_by_author", begin, err) }(time.Now())
+	books, err = r.next.ListByAuthor(ctx, authorID)
+	return
+}
+
+func (r instrumentingRepo) Drop(ctx context.Context) (err error) {
+	defer func(begin time.Time) { r.observe("drop", begin, err) }(time.Now())
+	err = r.next.Drop(ctx)
+	return
+}
+
+func (r instrumentingRepo) Delete(ctx context.Context, id string, version int64) (err error) {
+	defer func(begin time.Time) { r.observe("delete", begin, err) }(time.Now())
+	err = r.next.Delete(ctx, id, version)
+	return
+}
+
+func (r instrumentingRepo) ListDeleted(ctx context.Context) (books []Book, err error) {
+	defer func(begin time.Time) { r.observe("list_deleted", begin, err) }(time.Now())
+	books, err = r.next.ListDeleted(ctx)
+	return
+}
+
+func (r instrumentingRepo) Restore(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) { r.observe("restore", begin, err) }(time.Now())
+	err = r.next.Restore(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) Purge(ctx context.Context, before time.Time) (n int, err error) {
+	defer func(begin time.Time) { r.observe("purge", begin, err) }(time.Now())
+	n, err = r.next.Purge(ctx, before)
 	return
 }
@@ -6,6 +6,8 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/money"
 )
 
 type loggingService struct {
@@ -22,35 +24,75 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 	}
 }
 
-func (s loggingService) Search(ctx context.Context, query string) (books []Book, err error) {
+func (s loggingService) Search(ctx context.Context, query string, currency money.Currency) (books []Book, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "search",
+			"query", query,
 			"err", err,
 			"took", time.Since(begin),
 		)
 	}(time.Now())
-	return s.next.Search(ctx, query)
+	return s.next.Search(ctx, query, currency)
 }
 
-func (s loggingService) List(ctx context.Context, order string, limit, offset int) (books []Book, total int, err error) {
+func (s loggingService) List(ctx context.Context, order string, limit, offset int, currency money.Currency) (books []Book, total int, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "list",
 			"err", err,
 			"took", time.Since(begin),
 		)
 	}(time.Now())
-	return s.next.List(ctx, order, limit, offset)
+	return s.next.List(ctx, order, limit, offset, currency)
 }
 
-func (s loggingService) Get(ctx context.Context, ID string) (book Book, err error) {
+func (s loggingService) Get(ctx context.Context, ID string, currency money.Currency) (book Book, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "get",
+			"book_id", ID,
 			"err", err,
 			"took", time.Since(begin),
 		)
 	}(time.Now())
-	return s.next.Get(ctx, ID)
+	return s.next.Get(ctx, ID, currency)
+}
+
+func (s loggingService) Delete(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "delete",
+			"book_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Delete(ctx, id)
+}
+
+func (s loggingService) ListDeleted(ctx context.Context) (books []Book, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "list-deleted",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.ListDeleted(ctx)
+}
+
+func (s loggingService) Restore(ctx context.Context, id string) (book Book, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "restore",
+			"book_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Restore(ctx, id)
 }
@@ -0,0 +1,51 @@
+package catalog
+
+import (
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/openapi"
+)
+
+var currencyParam = openapi.Param{Name: "currency", Description: "Currency to price the books in, e.g: EUR"}
+
+// manage are the permissions of the routes managing books.
+var manage = []auth.Permission{auth.ManageCatalog, auth.ManageOwnBooks}
+
+// OpenAPI returns the API of the routes of MakeHTTPHandler.
+func OpenAPI() openapi.Service {
+	return openapi.Service{
+		Name:   "catalog",
+		Errors: errs.Infos(),
+		Routes: []openapi.Route{
+			{
+				Name: "searchBooks", Method: "GET", Path: "/catalog/v1/search",
+				Summary: "Searches the books by title",
+				Query: []openapi.Param{
+					{Name: "q", Description: "Search query", Required: true},
+					currencyParam,
+				},
+				Response: searchResponse{},
+			},
+			{
+				Name: "getBook", Method: "GET", Path: "/catalog/v1/{id}",
+				Summary:  "Returns the book",
+				Query:    []openapi.Param{currencyParam},
+				Response: getResponse{},
+			},
+			{
+				Name: "listDeletedBooks", Method: "GET", Path: "/catalog/v1/deleted",
+				Summary:  "Lists the soft deleted books",
+				Response: listDeletedResponse{}, Permissions: manage,
+			},
+			{
+				Name: "deleteBook", Method: "DELETE", Path: "/catalog/v1/{id}",
+				Summary:     "Soft deletes the book",
+				Permissions: manage,
+			},
+			{
+				Name: "restoreBook", Method: "POST", Path: "/catalog/v1/{id}/restore",
+				Summary:  "Restores the soft deleted book",
+				Response: restoreResponse{}, Permissions: manage,
+			},
+		},
+	}
+}
@@ -1,13 +1,28 @@
 package catalog
 
+import (
+	"context"
+	"time"
+)
+
 // Repo abstracts all the persistant storage operations of Catalog Service
 type Repo interface {
-	Create(book *Book) error
-	Save(book *Book) error
-	GetByID(ID string) (Book, error)
-	List(order string, limit, offset int) ([]Book, int, error)
-	Search(name string) ([]Book, error)
-	GetByISBN(ISBN string) (Book, error)
-	ListByAuthor(authorID string) ([]Book, error)
-	Drop() error
+	Create(ctx context.Context, book *Book) error
+	Save(ctx context.Context, book *Book) error
+	GetByID(ctx context.Context, ID string) (Book, error)
+	List(ctx context.Context, order string, limit, offset int) ([]Book, int, error)
+	Search(ctx context.Context, name string) ([]Book, error)
+	GetByISBN(ctx context.Context, ISBN string) (Book, error)
+	ListByAuthor(ctx context.Context, authorID string) ([]Book, error)
+	Drop(ctx context.Context) error
+
+	// Delete soft deletes the book of version, hiding it from all the other
+	// methods but ListDeleted and Restore until it is purged. A book saved
+	// since it was read at version fails with db.ErrConflict.
+	Delete(ctx context.Context, id string, version int64) error
+	// ListDeleted returns the deleted books, latest deleted first.
+	ListDeleted(ctx context.Context) ([]Book, error)
+	Restore(ctx context.Context, id string) error
+	// Purge hard deletes the books deleted before and returns their count.
+	Purge(ctx context.Context, before time.Time) (int, error)
 }
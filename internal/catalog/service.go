@@ -3,50 +3,183 @@ package catalog
 import (
 	"context"
 	"errors"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/money"
 )
 
 var (
 	ErrBookNotFound = errors.New("book not found")
 )
 
+// Every method takes a currency in which book prices are returned.
+// Empty currency returns the base price of the book.
 type Service interface {
 	// Search books based on free text
-	Search(ctx context.Context, query string) ([]Book, error)
+	Search(ctx context.Context, query string, currency money.Currency) ([]Book, error)
 
 	// List available items based on limit and offset.
 	// order takes string in the format "name asc" or "name desc"
 	// or in combination of multiple fields like "name asc, isbn desc"
-	List(ctx context.Context, order string, limit, offset int) ([]Book, int, error)
+	List(ctx context.Context, order string, limit, offset int, currency money.Currency) ([]Book, int, error)
 
 	// Get details about single book
-	Get(ctx context.Context, id string) (Book, error)
+	Get(ctx context.Context, id string, currency money.Currency) (Book, error)
+
+	// Delete takes a book out of search, lists and Get, orders placed keep
+	// their lines. The book is purged after the retention period unless
+	// restored. Publishers, see auth.ManageOwnBooks, only manage the books
+	// of their publisher: others are reported as ErrBookNotFound.
+	Delete(ctx context.Context, id string) error
+
+	// ListDeleted lists the books taken out of the catalog and not purged
+	// yet, latest deleted first.
+	ListDeleted(ctx context.Context) ([]Book, error)
+
+	// Restore puts a deleted book back in the catalog, with its prices.
+	Restore(ctx context.Context, id string) (Book, error)
 }
 
 type basicService struct {
-	r Repo
+	r     Repo
+	rates money.RateSource
 }
 
 // NewCatalogService return basic Service implementation.
-func NewService(r Repo) Service {
-	return basicService{r}
+// rates is used to convert prices of books not explicitly priced in requested currency.
+func NewService(r Repo, rates money.RateSource) Service {
+	return basicService{r: r, rates: rates}
 }
 
 // Search return books that matches with query.
-func (s basicService) Search(ctx context.Context, query string) ([]Book, error) {
-	return s.r.Search(query)
+func (s basicService) Search(ctx context.Context, query string, currency money.Currency) ([]Book, error) {
+	books, err := s.r.Search(ctx, query)
+	if err != nil {
+		return books, err
+	}
+	return books, s.price(ctx, currency, books...)
 }
 
 // Get return a book for the matched ID. Empty book incase of non-error.
-func (s basicService) Get(ctx context.Context, ID string) (Book, error) {
-	return s.r.GetByID(ID)
+func (s basicService) Get(ctx context.Context, ID string, currency money.Currency) (Book, error) {
+	book, err := s.r.GetByID(ctx, ID)
+	if err != nil {
+		return book, err
+	}
+	books := []Book{book}
+	if err := s.price(ctx, currency, books...); err != nil {
+		return Book{}, err
+	}
+	return books[0], nil
 }
 
 // List available items based on limit and offset.
 // order takes string in the format "name asc" or "name desc"
 // or in combination of multiple fields like "name asc, isbn desc"
 // List return all the books in the system
-func (s basicService) List(ctx context.Context, order string, limit, offset int) ([]Book, int, error) {
-	return s.r.List(order, limit, offset)
+func (s basicService) List(ctx context.Context, order string, limit, offset int, currency money.Currency) ([]Book, int, error) {
+	books, total, err := s.r.List(ctx, order, limit, offset)
+	if err != nil {
+		return books, total, err
+	}
+	return books, total, s.price(ctx, currency, books...)
+}
+
+// price replaces Price of every book with its price in currency.
+func (s basicService) price(ctx context.Context, currency money.Currency, books ...Book) error {
+	if currency == "" || len(books) == 0 {
+		return nil
+	}
+	rates, err := s.rates.Rates(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range books {
+		p, err := books[i].PriceIn(currency, rates)
+		if err != nil {
+			return err
+		}
+		books[i].Price = p
+	}
+	return nil
+}
+
+// Delete soft deletes a book the caller manages. The version deleted is
+// the one checked against If-Match, so it fails with
+// db.ErrPreconditionFailed or, if the book was edited in between, with
+// db.ErrConflict.
+func (s basicService) Delete(ctx context.Context, id string) error {
+	book, err := s.r.GetByID(ctx, id)
+	if err == db.ErrNotFound {
+		return ErrBookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !manages(ctx, book) {
+		return ErrBookNotFound
+	}
+	if err := db.CheckIfMatch(ctx, book.Version); err != nil {
+		return err
+	}
+	return s.r.Delete(ctx, id, book.Version)
+}
+
+// ListDeleted lists the deleted books not purged yet, the ones of their
+// publisher for publishers.
+func (s basicService) ListDeleted(ctx context.Context) ([]Book, error) {
+	books, err := s.r.ListDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	own := books[:0]
+	for _, b := range books {
+		if manages(ctx, b) {
+			own = append(own, b)
+		}
+	}
+	return own, nil
+}
+
+// Restore restores a deleted book the caller manages and returns it.
+func (s basicService) Restore(ctx context.Context, id string) (Book, error) {
+	if p, ok := auth.FromContext(ctx); ok && !p.Can(auth.ManageCatalog) {
+		deleted, err := s.ListDeleted(ctx)
+		if err != nil {
+			return Book{}, err
+		}
+		if !containsBook(deleted, id) {
+			return Book{}, ErrBookNotFound
+		}
+	}
+	if err := s.r.Restore(ctx, id); err != nil {
+		if err == db.ErrNotFound {
+			return Book{}, ErrBookNotFound
+		}
+		return Book{}, err
+	}
+	return s.r.GetByID(ctx, id)
+}
+
+// manages reports whether the principal of ctx manages book: any book with
+// auth.ManageCatalog, the books of its publisher otherwise. Calls not
+// authorized by auth.Require, e.g: from bookctl, manage every book.
+func manages(ctx context.Context, book Book) bool {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Can(auth.ManageCatalog) {
+		return true
+	}
+	return p.PublisherID != "" && book.PublisherID == p.PublisherID
+}
+
+func containsBook(books []Book, id string) bool {
+	for _, b := range books {
+		if b.ID == id {
+			return true
+		}
+	}
+	return false
 }
 
 // Middleware is a service middleware that takes service return service
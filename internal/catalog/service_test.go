@@ -0,0 +1,71 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db/inmem"
+)
+
+func TestPublisherManagesOwnBooks(t *testing.T) {
+	ctx := context.Background()
+	repo := inmem.NewCatalogRepo()
+	s := catalog.NewService(repo, nil)
+	own := catalog.Book{Title: "Dune", PublisherID: "penguin"}
+	other := catalog.Book{Title: "Emma", PublisherID: "harper"}
+	repo.Create(ctx, &own)
+	repo.Create(ctx, &other)
+	publisher := auth.WithPrincipal(ctx, auth.Principal{UserID: "2", Roles: auth.Roles{auth.Publisher}, PublisherID: "penguin"})
+
+	listed := func(t *testing.T, ctx context.Context, id string) bool {
+		books, err := s.ListDeleted(ctx)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		for _, b := range books {
+			if b.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("own book", func(t *testing.T) {
+		if err := s.Delete(publisher, own.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if !listed(t, publisher, own.ID) {
+			t.Errorf("expected %v listed deleted, got none", own.ID)
+		}
+		if _, err := s.Restore(publisher, own.ID); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("other publisher's book", func(t *testing.T) {
+		if err := s.Delete(publisher, other.ID); err != catalog.ErrBookNotFound {
+			t.Fatalf("expected %v, got %v", catalog.ErrBookNotFound, err)
+		}
+		if _, err := repo.GetByID(ctx, other.ID); err != nil {
+			t.Fatalf("expected book kept, got %v", err)
+		}
+		// bookctl calls have no principal and manage every book.
+		if err := s.Delete(ctx, other.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if listed(t, publisher, other.ID) {
+			t.Errorf("expected %v not listed deleted, got listed", other.ID)
+		}
+		if !listed(t, ctx, other.ID) {
+			t.Errorf("expected %v listed deleted without principal, got none", other.ID)
+		}
+		if _, err := s.Restore(publisher, other.ID); err != catalog.ErrBookNotFound {
+			t.Errorf("expected %v, got %v", catalog.ErrBookNotFound, err)
+		}
+		if _, err := s.Restore(ctx, other.ID); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingService struct {
+	tracer trace.Tracer
+	next   Service
+}
+
+// TracingMiddleware traces every call of the service in a span, child of the
+// span of its ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next Service) Service {
+		return tracingService{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+func (s tracingService) Search(ctx context.Context, query string, currency money.Currency) (books []Book, err error) {
+	ctx, span := s.tracer.Start(ctx, "catalog.Search", trace.WithAttributes(attribute.String("catalog.query", query)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Search(ctx, query, currency)
+}
+
+func (s tracingService) List(ctx context.Context, order string, limit, offset int, currency money.Currency) (books []Book, total int, err error) {
+	ctx, span := s.tracer.Start(ctx, "catalog.List")
+	defer func() { tracing.End(span, err) }()
+	return s.next.List(ctx, order, limit, offset, currency)
+}
+
+func (s tracingService) Get(ctx context.Context, ID string, currency money.Currency) (book Book, err error) {
+	ctx, span := s.tracer.Start(ctx, "catalog.Get", trace.WithAttributes(attribute.String("book.id", ID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Get(ctx, ID, currency)
+}
+
+func (s tracingService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "catalog.Delete", trace.WithAttributes(attribute.String("book.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Delete(ctx, id)
+}
+
+func (s tracingService) ListDeleted(ctx context.Context) (books []Book, err error) {
+	ctx, span := s.tracer.Start(ctx, "catalog.ListDeleted")
+	defer func() { tracing.End(span, err) }()
+	return s.next.ListDeleted(ctx)
+}
+
+func (s tracingService) Restore(ctx context.Context, id string) (book Book, err error) {
+	ctx, span := s.tracer.Start(ctx, "catalog.Restore", trace.WithAttributes(attribute.String("book.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Restore(ctx, id)
+}
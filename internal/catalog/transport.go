@@ -1,135 +1,126 @@
 package catalog
 
 import (
-	"encoding/json"
 	"net/http"
-	"strings"
 
 	"context"
 
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/money"
 	"github.com/kavirajk/bookshop/transport"
-	"github.com/pkg/errors"
 )
 
 var (
-	ErrEmptyQuery = errors.New("empty query")
-	ErrBadRouting = errors.New("bad routing")
+	ErrBadRouting = transport.ErrBadRouting
 )
 
-func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+// errs are the errors of the catalog sent over HTTP.
+var errs = transport.NewRegistry().
+	Register(ErrBookNotFound, http.StatusNotFound, "book_not_found").
+	Register(money.ErrUnknownCurrency, http.StatusBadRequest, "unknown_currency").
+	Register(money.ErrNoRate, http.StatusBadRequest, "no_exchange_rate")
+
+var encodeResponse = transport.ResponseEncoder(errs)
+
+// MakeRoutes returns the routes of the catalog, mounted under /catalog/v1.
+func MakeRoutes(ctx context.Context, s Service, logger log.Logger) transport.Routes {
 	e := MakeEndpoints(s)
-	options := []httptransport.ServerOption{
-		httptransport.ServerErrorEncoder(encodeError),
-	}
+	options := transport.ServerOptions(errs)
 	searchHandler := httptransport.NewServer(
 		e.SearchEndpoint,
-		decodeSearchRequest,
+		transport.Validated(decodeSearchRequest),
 		encodeResponse,
 		options...,
 	)
 	getHandler := httptransport.NewServer(
 		e.GetEndpoint,
-		decodeGetRequest,
+		transport.Validated(decodeGetRequest),
 		encodeResponse,
 		options...,
 	)
-	r := mux.NewRouter()
+	deleteHandler := httptransport.NewServer(
+		e.DeleteEndpoint,
+		transport.Validated(decodeDeleteRequest),
+		encodeResponse,
+		options...,
+	)
+	listDeletedHandler := httptransport.NewServer(
+		e.ListDeletedEndpoint,
+		transport.Validated(decodeListDeletedRequest),
+		encodeResponse,
+		options...,
+	)
+	restoreHandler := httptransport.NewServer(
+		e.RestoreEndpoint,
+		transport.Validated(decodeDeleteRequest),
+		encodeResponse,
+		options...,
+	)
+
+	return transport.NewRoutes("/catalog/v1", func(r *mux.Router) {
+		r.Handle("/search", searchHandler).Methods("GET")
+		// Before {id} routes, so that "deleted" isn't taken for a book id.
+		r.Handle("/deleted", listDeletedHandler).Methods("GET")
+		r.Handle("/{id}", getHandler).Methods("GET")
 
-	r.Handle("/catalog/v1/search", searchHandler).Methods("GET")
-	r.Handle("/catalog/v1/{id}", getHandler).Methods("GET")
+		// Admin routes of soft deleted catalogs.
+		r.Handle("/{id}", deleteHandler).Methods("DELETE")
+		r.Handle("/{id}/restore", restoreHandler).Methods("POST")
+	})
+}
 
+// MakeHTTPHandler returns handler of the routes of MakeRoutes, e.g: for
+// tests.
+func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+	r := mux.NewRouter()
+	transport.Mount(r, MakeRoutes(ctx, s, logger))
 	return r
 }
 func decodeSearchRequest(ctx context.Context, req *http.Request) (interface{}, error) {
-	q := req.FormValue("q")
-	if strings.TrimSpace(q) == "" {
-		return nil, ErrEmptyQuery
+	currency, err := decodeCurrency(req)
+	if err != nil {
+		return nil, err
 	}
 	return searchRequest{
-		Q: q,
+		Q:        req.FormValue("q"),
+		Currency: currency,
 	}, nil
 }
 
 func decodeGetRequest(ctx context.Context, req *http.Request) (interface{}, error) {
-	vars := mux.Vars(req)
-	id, ok := vars["id"]
-	if !ok {
-		return nil, ErrBadRouting
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	currency, err := decodeCurrency(req)
+	if err != nil {
+		return nil, err
 	}
 	return getRequest{
-		ID: id,
+		ID:       id,
+		Currency: currency,
 	}, nil
 }
 
-// errorer interface should be implemented by all the doman specific errors.
-// easy to set different status code in case of different errors.
-type errorer interface {
-	error() error
-}
-
-// statuser allows any response to get customer status code
-// e.g: 201 for successfull resource creation.
-type statuser interface {
-	status() int
-}
-
-// pager used to paginate any transport response.
-type pager interface {
-	page() (total int, previous, next string)
-}
-
-func encodeResponse(ctx context.Context, w http.ResponseWriter, d interface{}) error {
-	if e, ok := d.(errorer); ok && e.error() != nil {
-		// Now its a business logic error.
-		// Extract base domain error.
-		encodeError(ctx, e.error(), w)
-		return nil
+// decodeCurrency reads optional currency query param. e.g: ?currency=EUR
+func decodeCurrency(req *http.Request) (money.Currency, error) {
+	c := req.FormValue("currency")
+	if c == "" {
+		return "", nil
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-	status := http.StatusOK
-	if s, ok := d.(statuser); ok && s.status() != 0 {
-		status = s.status()
-	}
-
-	f := transport.FormatResponse{
-		Data: d,
-		Meta: transport.MetaResponse{Status: status},
-	}
-
-	if page, ok := d.(pager); ok {
-		t, p, n := page.page()
-		f.Meta.Total = t
-		f.Meta.Previous = p
-		f.Meta.Next = n
-	}
-
-	return json.NewEncoder(w).Encode(f)
+	return money.ParseCurrency(c)
 }
 
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	if err == nil {
-		panic("encodeError with nil error")
+func decodeDeleteRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	// Its important to pass errors.Cause() as we decide status code based on
-	// root error which is domain specific
-	code := codeFrom(errors.Cause(err))
-	w.WriteHeader(code)
-	f := transport.FormatResponse{Meta: transport.MetaResponse{Status: code, Error: err.Error()}}
-	json.NewEncoder(w).Encode(f)
+	return deleteRequest{ID: id}, nil
 }
 
-func codeFrom(err error) int {
-	switch err {
-	case ErrBookNotFound:
-		return http.StatusNotFound
-	case ErrEmptyQuery, ErrBadRouting:
-		return http.StatusBadRequest
-	default:
-		return http.StatusInternalServerError
-	}
+func decodeListDeletedRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return nil, nil
 }
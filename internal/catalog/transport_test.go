@@ -0,0 +1,82 @@
+package catalog_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/transport"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	ctx := context.Background()
+	repo := inmem.NewCatalogRepo()
+	b := catalog.Book{Title: "Dune"}
+	repo.Create(ctx, &b)
+	h := catalog.MakeHTTPHandler(ctx, catalog.NewService(repo, nil), log.NewNopLogger())
+
+	get := func(url string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, url := range []string{"/catalog/v1/" + b.ID, "/catalog/v1/search?q=dune"} {
+		t.Run(url, func(t *testing.T) {
+			w := get(url, nil)
+			etag := w.Header().Get("ETag")
+			if w.Code != http.StatusOK || etag == "" {
+				t.Fatalf("expected 200 with ETag, got %v %q", w.Code, etag)
+			}
+			if cc := w.Header().Get("Cache-Control"); cc != transport.CachePublic {
+				t.Errorf("expected %q, got %q", transport.CachePublic, cc)
+			}
+			if w.Header().Get("Last-Modified") == "" {
+				t.Errorf("expected Last-Modified, got none")
+			}
+
+			w = get(url, map[string]string{"If-None-Match": etag})
+			if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+				t.Errorf("expected empty 304, got %v %q", w.Code, w.Body)
+			}
+			if w = get(url, map[string]string{"If-None-Match": `"stale"`}); w.Code != http.StatusOK {
+				t.Errorf("expected 200, got %v", w.Code)
+			}
+		})
+	}
+
+	t.Run("if modified since", func(t *testing.T) {
+		url := "/catalog/v1/" + b.ID
+		lastModified := get(url, nil).Header().Get("Last-Modified")
+		if w := get(url, map[string]string{"If-Modified-Since": lastModified}); w.Code != http.StatusNotModified {
+			t.Errorf("expected 304, got %v", w.Code)
+		}
+		if w := get(url, map[string]string{"If-Modified-Since": "Mon, 02 Jan 2006 15:04:05 GMT"}); w.Code != http.StatusOK {
+			t.Errorf("expected 200, got %v", w.Code)
+		}
+	})
+
+	t.Run("version etag", func(t *testing.T) {
+		if etag := get("/catalog/v1/"+b.ID, nil).Header().Get("ETag"); etag != transport.ETag(b.Version) {
+			t.Errorf("expected %v, got %v", transport.ETag(b.Version), etag)
+		}
+	})
+
+	t.Run("errors not cached", func(t *testing.T) {
+		w := get("/catalog/v1/missing", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %v", w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != transport.CacheNoStore {
+			t.Errorf("expected %q, got %q", transport.CacheNoStore, cc)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package exchange
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/tracing"
+)
+
+// Endpoints combine all the exchange service endpoints under single type.
+type Endpoints struct {
+	GetRatesEndpoint    endpoint.Endpoint
+	UpdateRatesEndpoint endpoint.Endpoint
+}
+
+// MakeEndpoints returns Endpoints type which is the combination of
+// all the exchange service endpoints. Updating the rates needs
+// auth.ManageRates.
+func MakeEndpoints(s Service) Endpoints {
+	return Endpoints{
+		GetRatesEndpoint:    tracing.Endpoint("exchange.get_rates")(MakeGetRatesEndpoint(s)),
+		UpdateRatesEndpoint: tracing.Endpoint("exchange.update_rates")(auth.Require(auth.ManageRates)(MakeUpdateRatesEndpoint(s))),
+	}
+}
+
+func MakeGetRatesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		rates, e := s.Rates(ctx)
+		if e != nil {
+			return ratesResponse{Error: e}, nil
+		}
+		return ratesResponse{Rates: &rates}, nil
+	}
+}
+
+func MakeUpdateRatesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateRatesRequest)
+		e := s.UpdateRates(ctx, req.Rates)
+		if e != nil {
+			return ratesResponse{Error: e}, nil
+		}
+		return ratesResponse{Rates: &req.Rates}, nil
+	}
+}
+
+type getRatesRequest struct{}
+
+type updateRatesRequest struct {
+	money.Rates
+}
+
+type ratesResponse struct {
+	Status int          `json:"-"`
+	Rates  *money.Rates `json:"rates,omitempty"`
+	Error  error        `json:"error,omitempty"`
+}
+
+func (r ratesResponse) StatusCode() int {
+	return r.Status
+}
+
+func (r ratesResponse) Failed() error {
+	return r.Error
+}
@@ -0,0 +1,49 @@
+package exchange
+
+import (
+	"fmt"
+	"time"
+
+	"context"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/money"
+)
+
+type instrmw struct {
+	requestCount   metrics.Counter
+	requestLatency metrics.Histogram
+	next           Service
+}
+
+func InstrumentingMiddleware(counter metrics.Counter, latency metrics.Histogram) Middleware {
+	return func(next Service) Service {
+		return instrmw{
+			requestCount:   counter,
+			requestLatency: latency,
+			next:           next,
+		}
+	}
+}
+
+func (mw instrmw) Rates(ctx context.Context) (rates money.Rates, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "rates", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	rates, err = mw.next.Rates(ctx)
+	return
+}
+
+func (mw instrmw) UpdateRates(ctx context.Context, rates money.Rates) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "update_rates", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	err = mw.next.UpdateRates(ctx, rates)
+	return
+}
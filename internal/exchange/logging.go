@@ -0,0 +1,48 @@
+package exchange
+
+import (
+	"time"
+
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/money"
+)
+
+type loggingService struct {
+	logger log.Logger
+	next   Service
+}
+
+func LoggingMiddleware(logger log.Logger) Middleware {
+	return func(next Service) Service {
+		return loggingService{
+			logger: logger,
+			next:   next,
+		}
+	}
+}
+
+func (s loggingService) Rates(ctx context.Context) (rates money.Rates, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "rates",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.Rates(ctx)
+}
+
+func (s loggingService) UpdateRates(ctx context.Context, rates money.Rates) (err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "update_rates",
+			"base", rates.Base,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.UpdateRates(ctx, rates)
+}
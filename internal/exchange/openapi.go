@@ -0,0 +1,27 @@
+package exchange
+
+import (
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/openapi"
+)
+
+// OpenAPI returns the API of the routes of MakeHTTPHandler.
+func OpenAPI() openapi.Service {
+	return openapi.Service{
+		Name:   "exchange",
+		Errors: errs.Infos(),
+		Routes: []openapi.Route{
+			{
+				Name: "getRates", Method: "GET", Path: "/exchange/v1/rates",
+				Summary:  "Returns the currency exchange rates",
+				Response: ratesResponse{},
+			},
+			{
+				Name: "updateRates", Method: "PUT", Path: "/exchange/v1/rates",
+				Summary: "Replaces the currency exchange rates",
+				Request: updateRatesRequest{}, Response: ratesResponse{},
+				Permissions: []auth.Permission{auth.ManageRates},
+			},
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kavirajk/bookshop/money"
+)
+
+// Service manages the exchange rates used to convert prices between currencies.
+type Service interface {
+	// Rates returns the current exchange rates snapshot.
+	Rates(ctx context.Context) (money.Rates, error)
+
+	// UpdateRates replaces the current exchange rates with rates.
+	UpdateRates(ctx context.Context, rates money.Rates) error
+}
+
+type basicService struct {
+	mu    sync.RWMutex
+	rates money.Rates
+}
+
+// NewService returns basic Service implementation holding rates in memory.
+// initial rates are usually loaded from a file at startup.
+func NewService(initial money.Rates) Service {
+	return &basicService{rates: initial}
+}
+
+// Rates returns the current exchange rates snapshot.
+func (s *basicService) Rates(_ context.Context) (money.Rates, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rates, nil
+}
+
+// UpdateRates validates and replaces the current exchange rates.
+func (s *basicService) UpdateRates(_ context.Context, rates money.Rates) error {
+	if err := rates.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates = rates
+	return nil
+}
+
+// Middleware is a service middleware that takes service return service
+type Middleware func(Service) Service
@@ -0,0 +1,37 @@
+package exchange
+
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingService struct {
+	tracer trace.Tracer
+	next   Service
+}
+
+// TracingMiddleware traces every call of the service in a span, child of the
+// span of its ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next Service) Service {
+		return tracingService{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+func (s tracingService) Rates(ctx context.Context) (rates money.Rates, err error) {
+	ctx, span := s.tracer.Start(ctx, "exchange.Rates")
+	defer func() { tracing.End(span, err) }()
+	return s.next.Rates(ctx)
+}
+
+func (s tracingService) UpdateRates(ctx context.Context, rates money.Rates) (err error) {
+	ctx, span := s.tracer.Start(ctx, "exchange.UpdateRates")
+	defer func() { tracing.End(span, err) }()
+	return s.next.UpdateRates(ctx, rates)
+}
@@ -0,0 +1,64 @@
+package exchange
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"context"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/transport"
+	"github.com/pkg/errors"
+)
+
+// errs are the errors of the exchange sent over HTTP.
+var errs = transport.NewRegistry().
+	Register(money.ErrInvalidRates, http.StatusBadRequest, "invalid_rates")
+
+var encodeResponse = transport.ResponseEncoder(errs)
+
+// MakeRoutes returns the routes of the exchange, mounted under /exchange/v1.
+func MakeRoutes(ctx context.Context, s Service, logger log.Logger) transport.Routes {
+	e := MakeEndpoints(s)
+	options := transport.ServerOptions(errs)
+	getRatesHandler := httptransport.NewServer(
+		e.GetRatesEndpoint,
+		decodeGetRatesRequest,
+		encodeResponse,
+		options...,
+	)
+	updateRatesHandler := httptransport.NewServer(
+		e.UpdateRatesEndpoint,
+		decodeUpdateRatesRequest,
+		encodeResponse,
+		options...,
+	)
+
+	return transport.NewRoutes("/exchange/v1", func(r *mux.Router) {
+		r.Handle("/rates", getRatesHandler).Methods("GET")
+		r.Handle("/rates", updateRatesHandler).Methods("PUT")
+	})
+}
+
+// MakeHTTPHandler returns handler of the routes of MakeRoutes, e.g: for
+// tests.
+func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+	r := mux.NewRouter()
+	transport.Mount(r, MakeRoutes(ctx, s, logger))
+	return r
+}
+
+func decodeGetRatesRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return getRatesRequest{}, nil
+}
+
+func decodeUpdateRatesRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	var r updateRatesRequest
+	if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+		return nil, errors.Wrap(money.ErrInvalidRates, err.Error())
+	}
+	return r, nil
+}
@@ -6,29 +6,42 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/kavirajk/bookshop/tracing"
 )
 
 // Endpoints combine all the order service endpoints under single type.
 type Endpoints struct {
-	PlaceOrderEndpoint    endpoint.Endpoint
-	GetUserOrdersEndpoint endpoint.Endpoint
-	CancelOrderEndpoint   endpoint.Endpoint
+	PlaceOrderEndpoint     endpoint.Endpoint
+	ShippingQuotesEndpoint endpoint.Endpoint
+	GetUserOrdersEndpoint  endpoint.Endpoint
+	CancelOrderEndpoint    endpoint.Endpoint
+	DeleteEndpoint         endpoint.Endpoint
+	ListDeletedEndpoint    endpoint.Endpoint
+	RestoreEndpoint        endpoint.Endpoint
 }
 
 // MakeEndpoints returns Endpoints type which is the combination of
-// all the order service endpoints.
+// all the order service endpoints. Managing deleted orders needs
+// auth.ManageOrders.
 func MakeEndpoints(s Service) Endpoints {
+	admin := auth.Require(auth.ManageOrders)
 	return Endpoints{
-		PlaceOrderEndpoint:    MakePlaceOrderEndpoint(s),
-		GetUserOrdersEndpoint: MakeGetUserOdersEndpoint(s),
-		CancelOrderEndpoint:   MakeCancelOrderEndpoint(s),
+		PlaceOrderEndpoint:     tracing.Endpoint("order.place_order")(MakePlaceOrderEndpoint(s)),
+		ShippingQuotesEndpoint: tracing.Endpoint("order.shipping_quotes")(MakeShippingQuotesEndpoint(s)),
+		GetUserOrdersEndpoint:  tracing.Endpoint("order.get_user_orders")(MakeGetUserOdersEndpoint(s)),
+		CancelOrderEndpoint:    tracing.Endpoint("order.cancel_order")(MakeCancelOrderEndpoint(s)),
+		DeleteEndpoint:         tracing.Endpoint("order.delete")(admin(MakeDeleteEndpoint(s))),
+		ListDeletedEndpoint:    tracing.Endpoint("order.list_deleted")(admin(MakeListDeletedEndpoint(s))),
+		RestoreEndpoint:        tracing.Endpoint("order.restore")(admin(MakeRestoreEndpoint(s))),
 	}
 }
 
 func MakePlaceOrderEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(placeOrderRequest)
-		order, e := s.PlaceOrder(ctx, req.BookID)
+		order, e := s.PlaceOrder(ctx, req.Cart)
 		if e != nil {
 			return placeOrderResponse{Order: nil, Error: e}, nil
 		}
@@ -36,6 +49,17 @@ func MakePlaceOrderEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+func MakeShippingQuotesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(placeOrderRequest)
+		quotes, e := s.ShippingQuotes(ctx, req.Cart)
+		if e != nil {
+			return shippingQuotesResponse{Error: e}, nil
+		}
+		return shippingQuotesResponse{Quotes: quotes}, nil
+	}
+}
+
 func MakeGetUserOdersEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(getUserOrdersRequest)
@@ -59,20 +83,49 @@ func MakeCancelOrderEndpoint(s Service) endpoint.Endpoint {
 }
 
 type placeOrderRequest struct {
+	Cart
+
+	// BookID orders single copy of a book. Kept for clients
+	// from before the cart with multiple items.
 	BookID string `json:"book_id"`
 }
 
+func (r placeOrderRequest) Validate() error {
+	return r.Cart.Validate()
+}
+
 type placeOrderResponse struct {
 	Status int    `json:"-"`
 	Order  *Order `json:"order,omitempty"`
 	Error  error  `json:"error,omitempty"`
 }
 
-func (r placeOrderResponse) status() int {
+func (r placeOrderResponse) StatusCode() int {
 	return r.Status
 }
 
-func (r placeOrderResponse) error() error {
+func (r placeOrderResponse) Failed() error {
+	return r.Error
+}
+
+func (r placeOrderResponse) Version() int64 {
+	if r.Order == nil {
+		return 0
+	}
+	return r.Order.Version
+}
+
+type shippingQuotesResponse struct {
+	Status int              `json:"-"`
+	Quotes []shipping.Quote `json:"quotes"`
+	Error  error            `json:"error,omitempty"`
+}
+
+func (r shippingQuotesResponse) StatusCode() int {
+	return r.Status
+}
+
+func (r shippingQuotesResponse) Failed() error {
 	return r.Error
 }
 
@@ -85,7 +138,7 @@ type getUserOrdersResponse struct {
 	Error  error   `json:"error,omitempty"`
 }
 
-func (r getUserOrdersResponse) error() error {
+func (r getUserOrdersResponse) Failed() error {
 	return r.Error
 }
 
@@ -97,3 +150,73 @@ type cancelOrderRequest struct {
 type cancelOrderResponse struct {
 	Error error `json:"error,omitempty"`
 }
+
+func MakeDeleteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteRequest)
+		e := s.Delete(ctx, req.ID)
+		if e != nil {
+			return deleteResponse{Error: e}, nil
+		}
+		return deleteResponse{}, nil
+	}
+}
+
+func MakeListDeletedEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		orders, e := s.ListDeleted(ctx)
+		if e != nil {
+			return listDeletedResponse{Orders: make([]Order, 0), Error: e}, nil
+		}
+		return listDeletedResponse{Orders: orders}, nil
+	}
+}
+
+func MakeRestoreEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteRequest)
+		order, e := s.Restore(ctx, req.ID)
+		if e != nil {
+			return restoreResponse{Error: e}, nil
+		}
+		return restoreResponse{Order: &order}, nil
+	}
+}
+
+// deleteRequest holds the id of the order, restore decodes it too.
+type deleteRequest struct {
+	ID string `json:"id"`
+}
+
+type deleteResponse struct {
+	Error error `json:"error,omitempty"`
+}
+
+func (r deleteResponse) Failed() error {
+	return r.Error
+}
+
+type listDeletedResponse struct {
+	Orders []Order `json:"orders"`
+	Error  error   `json:"error,omitempty"`
+}
+
+func (r listDeletedResponse) Failed() error {
+	return r.Error
+}
+
+type restoreResponse struct {
+	Order *Order `json:"order,omitempty"`
+	Error error  `json:"error,omitempty"`
+}
+
+func (r restoreResponse) Failed() error {
+	return r.Error
+}
+
+func (r restoreResponse) Version() int64 {
+	if r.Order == nil {
+		return 0
+	}
+	return r.Order.Version
+}
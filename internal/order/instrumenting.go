@@ -7,6 +7,7 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/shipping"
 )
 
 type instrmw struct {
@@ -25,14 +26,25 @@ func InstrumentingMiddleware(counter metrics.Counter, latency metrics.Histogram)
 	}
 }
 
-func (mw instrmw) PlaceOrder(ctx context.Context, bookID string) (order Order, err error) {
+func (mw instrmw) PlaceOrder(ctx context.Context, cart Cart) (order Order, err error) {
 	defer func(begin time.Time) {
 		lvs := []string{"method", "place_order", "error", fmt.Sprint(err != nil)}
 		mw.requestCount.With(lvs...).Add(1)
 		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	order, err = mw.next.PlaceOrder(ctx, bookID)
+	order, err = mw.next.PlaceOrder(ctx, cart)
+	return
+}
+
+func (mw instrmw) ShippingQuotes(ctx context.Context, cart Cart) (quotes []shipping.Quote, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "shipping_quotes", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	quotes, err = mw.next.ShippingQuotes(ctx, cart)
 	return
 }
 
@@ -57,3 +69,130 @@ func (mw instrmw) CancelOrder(ctx context.Context, userID string, orderID string
 	err = mw.next.CancelOrder(ctx, userID, orderID)
 	return
 }
+
+func (mw instrmw) Delete(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "delete", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	err = mw.next.Delete(ctx, id)
+	return
+}
+
+func (mw instrmw) ListDeleted(ctx context.Context) (orders []Order, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "list-deleted", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	orders, err = mw.next.ListDeleted(ctx)
+	return
+}
+
+func (mw instrmw) Restore(ctx context.Context, id string) (order Order, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "restore", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	order, err = mw.next.Restore(ctx, id)
+	return
+}
+
+type instrumentingRepo struct {
+	duration metrics.Histogram
+	next     Repo
+}
+
+// InstrumentingRepo returns r timing its queries in duration, labelled by
+// method and error, see metrics.Queries.
+func InstrumentingRepo(r Repo, duration metrics.Histogram) Repo {
+	return instrumentingRepo{duration: duration.With("repo", "orders"), next: r}
+}
+
+func (r instrumentingRepo) observe(method string, begin time.Time, err error) {
+	r.duration.With("method", method, "error", fmt.Sprint(err != nil)).Observe(time.Since(begin).Seconds())
+}
+
+func (r instrumentingRepo) Create(ctx context.Context, order *Order) (err error) {
+	defer func(begin time.Time) { r.observe("create", begin, err) }(time.Now())
+	err = r.next.Create(ctx, order)
+	return
+}
+
+func (r instrumentingRepo) Save(ctx context.Context, order *Order) (err error) {
+	defer func(begin time.Time) { r.observe("save", begin, err) }(time.Now())
+	err = r.next.Save(ctx, order)
+	return
+}
+
+func (r instrumentingRepo) GetByID(ctx context.Context, ID string) (order Order, err error) {
+	defer func(begin time.Time) { r.observe("get_by_id", begin, err) }(time.Now())
+	order, err = r.next.GetByID(ctx, ID)
+	return
+}
+
+func (r instrumentingRepo) ListByUser(ctx context.Context, userID string) (orders []Order, err error) {
+	defer func(begin time.Time) { r.observe("list_by_user", begin, err) }(time.Now())
+	orders, err = r.next.ListByUser(ctx, userID)
+	return
+}
+
+func (r instrumentingRepo) Drop(ctx context.Context) (err error) {
+	defer func(begin time.Time) { r.observe("drop", begin, err) }(time.Now())
+	err = r.next.Drop(ctx)
+	return
+}
+
+func (r instrumentingRepo) Delete(ctx context.Context, id string, version int64) (err error) {
+	defer func(begin time.Time) { r.observe("delete", begin, err) }(time.Now())
+	err = r.next.Delete(ctx, id, version)
+	return
+}
+
+func (r instrumentingRepo) ListDeleted(ctx context.Context) (orders []Order, err error) {
+	defer func(begin time.Time) { r.observe("list_deleted", begin, err) }(time.Now())
+	orders, err = r.next.ListDeleted(ctx)
+	return
+}
+
+func (r instrumentingRepo) Restore(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) { r.observe("restore", begin, err) }(time.Now())
+	err = r.next.Restore(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) Purge(ctx context.Context, before time.Time) (n int, err error) {
+	defer func(begin time.Time) { r.observe("purge", begin, err) }(time.Now())
+	n, err = r.next.Purge(ctx, before)
+	return
+}
+
+type businessmw struct {
+	Service
+	placed  metrics.Counter
+	revenue metrics.Counter
+}
+
+// BusinessMetricsMiddleware counts the orders placed in placed and adds
+// their total price to revenue, labelled by currency, in major units.
+func BusinessMetricsMiddleware(placed, revenue metrics.Counter) Middleware {
+	return func(next Service) Service {
+		return businessmw{Service: next, placed: placed, revenue: revenue}
+	}
+}
+
+func (mw businessmw) PlaceOrder(ctx context.Context, cart Cart) (Order, error) {
+	order, err := mw.Service.PlaceOrder(ctx, cart)
+	if err != nil {
+		return order, err
+	}
+	mw.placed.Add(1)
+	total, _ := order.TotalPrice.Rat().Float64()
+	mw.revenue.With("currency", string(order.TotalPrice.Currency)).Add(total)
+	return order, nil
+}
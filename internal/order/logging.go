@@ -6,6 +6,8 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/shipping"
 )
 
 type loggingService struct {
@@ -22,21 +24,35 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 	}
 }
 
-func (s loggingService) PlaceOrder(ctx context.Context, bookID string) (order Order, err error) {
+func (s loggingService) PlaceOrder(ctx context.Context, cart Cart) (order Order, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "place_order",
+			"order_id", order.ID,
+			"user_id", order.CreatedByID,
 			"err", err,
 			"took", time.Since(begin),
 		)
 	}(time.Now())
-	return s.next.PlaceOrder(ctx, bookID)
+	return s.next.PlaceOrder(ctx, cart)
+}
+
+func (s loggingService) ShippingQuotes(ctx context.Context, cart Cart) (quotes []shipping.Quote, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "shipping_quotes",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.ShippingQuotes(ctx, cart)
 }
 
 func (s loggingService) GetUserOrders(ctx context.Context, userID string) (orders []Order, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "get_user_orders",
+			"user_id", userID,
 			"err", err,
 			"took", time.Since(begin),
 		)
@@ -46,11 +62,51 @@ func (s loggingService) GetUserOrders(ctx context.Context, userID string) (order
 
 func (s loggingService) CancelOrder(ctx context.Context, userID string, orderID string) (err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "cancel_order",
+			"user_id", userID,
+			"order_id", orderID,
 			"err", err,
 			"took", time.Since(begin),
 		)
 	}(time.Now())
 	return s.next.CancelOrder(ctx, userID, orderID)
 }
+
+func (s loggingService) Delete(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "delete",
+			"order_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Delete(ctx, id)
+}
+
+func (s loggingService) ListDeleted(ctx context.Context) (orders []Order, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "list-deleted",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.ListDeleted(ctx)
+}
+
+func (s loggingService) Restore(ctx context.Context, id string) (order Order, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "restore",
+			"order_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Restore(ctx, id)
+}
@@ -0,0 +1,53 @@
+package order
+
+import (
+	"net/http"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/openapi"
+)
+
+// OpenAPI returns the API of the routes of MakeHTTPHandler.
+func OpenAPI() openapi.Service {
+	admin := []auth.Permission{auth.ManageOrders}
+	return openapi.Service{
+		Name:   "orders",
+		Errors: errs.Infos(),
+		Routes: []openapi.Route{
+			{
+				Name: "placeOrder", Method: "POST", Path: "/orders/v1/place",
+				Summary: "Places an order of the cart",
+				Request: placeOrderRequest{}, Response: placeOrderResponse{}, Status: http.StatusCreated,
+			},
+			{
+				Name: "shippingQuotes", Method: "POST", Path: "/orders/v1/shipping-quotes",
+				Summary: "Quotes the shipping methods of the print books of the cart, cheapest first",
+				Request: placeOrderRequest{}, Response: shippingQuotesResponse{},
+			},
+			{
+				Name: "listDeletedOrders", Method: "GET", Path: "/orders/v1/deleted",
+				Summary:  "Lists the soft deleted orders",
+				Response: listDeletedResponse{}, Permissions: admin,
+			},
+			{
+				Name: "getUserOrders", Method: "GET", Path: "/orders/v1/{user-id}",
+				Summary:  "Lists the orders of the user",
+				Response: getUserOrdersResponse{},
+			},
+			{
+				Name: "cancelOrder", Method: "POST", Path: "/orders/v1/{user-id}/cancel/{id}",
+				Summary: "Cancels the order of the user",
+			},
+			{
+				Name: "deleteOrder", Method: "DELETE", Path: "/orders/v1/{id}",
+				Summary:     "Soft deletes the order",
+				Permissions: admin,
+			},
+			{
+				Name: "restoreOrder", Method: "POST", Path: "/orders/v1/{id}/restore",
+				Summary:  "Restores the soft deleted order",
+				Response: restoreResponse{}, Permissions: admin,
+			},
+		},
+	}
+}
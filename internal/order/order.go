@@ -1,15 +1,190 @@
 package order
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/money"
 	"github.com/kavirajk/bookshop/user"
+	"github.com/kavirajk/bookshop/validate"
+)
+
+// Bounds of a cart.
+const (
+	MaxCartItems = 50
+	MaxQuantity  = 100
 )
 
 type Order struct {
-	ID          string         `json:"id"`
-	CreatedBy   *user.User     `json:"created_by"`
-	CreatedByID string         `json:"-"`
-	Items       []catalog.Book `json:"items" gorm:"many_to_many"`
-	TotalPrice  float64        `json:"total_price"`
-	Currency    string         `json:"currency"`
+	ID          string       `json:"id"`
+	CreatedBy   *user.User   `json:"created_by"`
+	CreatedByID string       `json:"-"`
+	Lines       []Line       `json:"lines"`
+	Adjustments []Adjustment `json:"adjustments"`
+	Coupon      string       `json:"coupon,omitempty"`
+
+	BillingCountry string      `json:"billing_country,omitempty"`
+	BillingRegion  string      `json:"billing_region,omitempty"`
+	TaxTotal       money.Money `json:"tax_total" gorm:"embedded;embedded_prefix:tax_total_"`
+
+	// Shipping is left empty for orders of only digital books.
+	ShippingAddress user.PostalAddress `json:"shipping_address" gorm:"embedded;embedded_prefix:shipping_"`
+	ShippingMethod  string             `json:"shipping_method,omitempty"`
+	ShippingCost    money.Money        `json:"shipping_cost" gorm:"embedded;embedded_prefix:shipping_cost_"`
+
+	TotalPrice money.Money `json:"total_price" gorm:"embedded;embedded_prefix:total_price_"`
+
+	// ExchangeRates is the snapshot of rates used to price the lines in
+	// order currency, so that the total can be reproduced later.
+	ExchangeRates money.Rates `json:"exchange_rates" gorm:"type:text"`
+
+	// Version is bumped on every save, see db.ErrConflict.
+	Version int64 `json:"version"`
+
+	// DeletedAt is set once the order is soft deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Line is a single book on the order. Book details are copied so that
+// order stays the same even if the book changes later.
+type Line struct {
+	ID        string         `json:"id"`
+	OrderID   string         `json:"-"`
+	BookID    string         `json:"book_id"`
+	Title     string         `json:"title"`
+	Format    catalog.Format `json:"format"`
+	Quantity  int            `json:"quantity"`
+	UnitPrice money.Money    `json:"unit_price" gorm:"embedded;embedded_prefix:unit_price_"`
+
+	// Discount is the share of the order adjustments taken off this line.
+	Discount money.Money `json:"discount" gorm:"embedded;embedded_prefix:discount_"`
+
+	// Tax on the discounted line amount. Inclusive tax is already part of
+	// UnitPrice, exclusive tax is added to the order total.
+	TaxName      string      `json:"tax_name,omitempty"`
+	TaxRate      string      `json:"tax_rate"`
+	TaxInclusive bool        `json:"tax_inclusive"`
+	TaxAmount    money.Money `json:"tax_amount" gorm:"embedded;embedded_prefix:tax_amount_"`
+}
+
+// Amount returns price of the line, UnitPrice times Quantity.
+func (l *Line) Amount() (money.Money, error) {
+	return l.UnitPrice.Mul(int64(l.Quantity))
+}
+
+// Net returns line amount after discount.
+func (l *Line) Net() (money.Money, error) {
+	amount, err := l.Amount()
+	if err != nil || l.Discount.Currency == "" {
+		// Discount not set yet.
+		return amount, err
+	}
+	return amount.Sub(l.Discount)
+}
+
+// Adjustment changes the order total e.g: a discount given by a promotion.
+// Discounts have negative Amount.
+type Adjustment struct {
+	ID          string      `json:"id"`
+	OrderID     string      `json:"-"`
+	PromotionID string      `json:"promotion_id,omitempty"`
+	Description string      `json:"description"`
+	Amount      money.Money `json:"amount" gorm:"embedded;embedded_prefix:amount_"`
+}
+
+// Cart is what user asks to order.
+type Cart struct {
+	Items          []CartItem     `json:"items"`
+	Currency       money.Currency `json:"currency"`
+	Coupon         string         `json:"coupon"`
+	BillingCountry string         `json:"billing_country"`
+	BillingRegion  string         `json:"billing_region"`
+
+	// Needed only when cart has print books. Client picks the address from
+	// user's address book and the method from the shipping quotes.
+	ShippingAddress user.PostalAddress `json:"shipping_address"`
+	ShippingMethod  string             `json:"shipping_method"`
+}
+
+// CartItem is a book and how many copies of it.
+type CartItem struct {
+	BookID   string `json:"book_id"`
+	Quantity int    `json:"quantity"`
+}
+
+// Validate does basic validation of the cart.
+func (c *Cart) Validate() error {
+	var v validate.Validator
+	v.Check(len(c.Items) > 0, "items", validate.Missing, "must have at least one item")
+	v.Check(len(c.Items) <= MaxCartItems, "items", validate.Length, fmt.Sprintf("must have at most %d items", MaxCartItems))
+	for i, it := range c.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		v.Required(field+".book_id", it.BookID)
+		v.Range(field+".quantity", it.Quantity, 1, MaxQuantity)
+	}
+	v.Length("coupon", c.Coupon, 1, 64)
+	return v.Err()
+}
+
+// NeedsShipping reports whether any line of the order has to be shipped.
+func (o *Order) NeedsShipping() bool {
+	for _, l := range o.Lines {
+		if l.Format != catalog.Ebook {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateTotal sets TotalPrice to the sum of all the lines, adjustments,
+// shipping cost and exclusive taxes in the order currency, and TaxTotal to the sum of all
+// line taxes. Total never goes below zero.
+func (o *Order) UpdateTotal() error {
+	c := o.TotalPrice.Currency
+	if c == "" && len(o.Lines) > 0 {
+		c = o.Lines[0].UnitPrice.Currency
+	}
+	total := money.Zero(c)
+	taxTotal := money.Zero(c)
+	for i := range o.Lines {
+		l := &o.Lines[i]
+		amount, err := l.Amount()
+		if err != nil {
+			return err
+		}
+		if total, err = total.Add(amount); err != nil {
+			return err
+		}
+		if l.TaxAmount.IsZero() {
+			continue
+		}
+		if taxTotal, err = taxTotal.Add(l.TaxAmount); err != nil {
+			return err
+		}
+		if !l.TaxInclusive {
+			if total, err = total.Add(l.TaxAmount); err != nil {
+				return err
+			}
+		}
+	}
+	for _, a := range o.Adjustments {
+		var err error
+		if total, err = total.Add(a.Amount); err != nil {
+			return err
+		}
+	}
+	if total.IsNegative() {
+		total = money.Zero(c)
+	}
+	// Discounts never take off shipping.
+	if !o.ShippingCost.IsZero() {
+		var err error
+		if total, err = total.Add(o.ShippingCost); err != nil {
+			return err
+		}
+	}
+	o.TotalPrice = total
+	o.TaxTotal = taxTotal
+	return nil
 }
@@ -1,10 +1,25 @@
 package order
 
+import (
+	"context"
+	"time"
+)
+
 // Repo abstracts all the persistant storage operations of Order Service
 type Repo interface {
-	Create(order *Order) error
-	Save(order *Order) error
-	GetByID(ID string) (Order, error)
-	ListByUser(userID string) ([]Order, error)
-	Drop() error
+	Create(ctx context.Context, order *Order) error
+	Save(ctx context.Context, order *Order) error
+	GetByID(ctx context.Context, ID string) (Order, error)
+	ListByUser(ctx context.Context, userID string) ([]Order, error)
+	Drop(ctx context.Context) error
+
+	// Delete soft deletes the order, hiding it from all the other methods
+	// but ListDeleted and Restore until it is purged. Like Save it fails
+	// with db.ErrConflict unless the order still has version.
+	Delete(ctx context.Context, id string, version int64) error
+	// ListDeleted returns the deleted orders, latest deleted first.
+	ListDeleted(ctx context.Context) ([]Order, error)
+	Restore(ctx context.Context, id string) error
+	// Purge hard deletes the orders deleted before and returns their count.
+	Purge(ctx context.Context, before time.Time) (int, error)
 }
@@ -2,7 +2,17 @@ package order
 
 import (
 	"context"
-	"errors"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/kavirajk/bookshop/tax"
+	"github.com/kavirajk/bookshop/user"
+	"github.com/kavirajk/bookshop/validate"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -10,28 +20,263 @@ var (
 )
 
 type Service interface {
-	// PlaceOrder creates an order for particular book.
-	PlaceOrder(ctx context.Context, bookID string) (Order, error)
+	// PlaceOrder creates an order for the books in cart priced in cart currency,
+	// applying the live promotions, coupon and taxes of billing location.
+	// Empty currency uses the base currency of the first book. The order is
+	// made by the user of the request, if it has an auth token.
+	PlaceOrder(ctx context.Context, cart Cart) (Order, error)
+
+	// ShippingQuotes returns the shipping methods available for the cart
+	// and its shipping address, priced in cart currency. It is empty when
+	// cart has only digital books.
+	ShippingQuotes(ctx context.Context, cart Cart) ([]shipping.Quote, error)
 
 	// GetUserOrders returns list of orders placed by an user.
 	GetUserOrders(ctx context.Context, userID string) ([]Order, error)
 
 	// CancelOrder cancels the particular order of an user.
 	CancelOrder(ctx context.Context, userID string, orderID string) error
+
+	// Delete soft deletes an order, hiding it from the orders of its user.
+	// Coupons it redeemed stay used. It is purged with its lines after the
+	// retention period unless restored.
+	Delete(ctx context.Context, id string) error
+
+	// ListDeleted lists the deleted orders not purged yet, latest deleted first.
+	ListDeleted(ctx context.Context) ([]Order, error)
+
+	// Restore shows a deleted order to its user again.
+	Restore(ctx context.Context, id string) (Order, error)
 }
 
 type basicService struct {
-	r Repo
+	r          Repo
+	uow        db.UnitOfWork
+	books      catalog.Repo
+	rates      money.RateSource
+	promotions promotion.Service
+	taxes      tax.Calculator
+	shipping   shipping.Calculator
 }
 
 // NewOrderService return basic Service implementation.
-func NewService(r Repo) Service {
-	return basicService{r}
+// Coupons are redeemed and the order is stored in a single unit of work uow.
+func NewService(r Repo, uow db.UnitOfWork, books catalog.Repo, rates money.RateSource, promotions promotion.Service, taxes tax.Calculator, shipping shipping.Calculator) Service {
+	return basicService{r: r, uow: uow, books: books, rates: rates, promotions: promotions, taxes: taxes, shipping: shipping}
+}
+
+// PlaceOrder creates an order for the books in cart priced in cart currency.
+// Rates used for the conversion, the discounts given and the taxes of every
+// line are recorded on the order. Print books are shipped to the cart
+// shipping address with the chosen method.
+func (s basicService) PlaceOrder(ctx context.Context, cart Cart) (Order, error) {
+	if err := cart.Validate(); err != nil {
+		return Order{}, err
+	}
+	// Guests check out without auth token.
+	principal, err := auth.PrincipalFrom(ctx)
+	if err != nil && err != auth.ErrUnauthorized {
+		return Order{}, err
+	}
+	rates, err := s.rates.Rates(ctx)
+	if err != nil {
+		return Order{}, err
+	}
+	items, currency, used, err := s.cartItems(ctx, cart, rates)
+	if err != nil {
+		return Order{}, err
+	}
+
+	var quote shipping.Quote
+	if p, ok := parcel(cart.ShippingAddress, items); ok {
+		var v validate.Validator
+		if err := v.Nested("shipping_address", cart.ShippingAddress.Validate()); err != nil {
+			return Order{}, err
+		}
+		v.Required("shipping_method", cart.ShippingMethod)
+		if err := v.Err(); err != nil {
+			return Order{}, err
+		}
+		p.Destination = shipping.Destination{
+			Country: cart.ShippingAddress.Country,
+			Region:  cart.ShippingAddress.Region,
+		}
+		if quote, err = s.shipping.Quote(ctx, cart.ShippingMethod, p); err != nil {
+			return Order{}, err
+		}
+		used = append(used, quote.Cost.Currency)
+	}
+
+	discounts, err := s.promotions.Apply(ctx, items, cart.Coupon, currency)
+	if err != nil {
+		return Order{}, err
+	}
+
+	order := Order{
+		CreatedByID:    principal.UserID,
+		Coupon:         promotion.NormalizeCoupon(cart.Coupon),
+		BillingCountry: cart.BillingCountry,
+		BillingRegion:  cart.BillingRegion,
+		ShippingCost:   money.Zero(currency),
+		TotalPrice:     money.Zero(currency),
+		ExchangeRates:  rates.Subset(append(used, currency)...),
+	}
+	if quote.Method != "" {
+		order.ShippingAddress = cart.ShippingAddress
+		order.ShippingMethod = quote.Method
+		if order.ShippingCost, err = rates.Convert(quote.Cost, currency, money.RoundHalfEven); err != nil {
+			return Order{}, err
+		}
+	}
+	for _, it := range items {
+		format := it.Book.Format
+		if format == "" {
+			format = catalog.Print
+		}
+		order.Lines = append(order.Lines, Line{
+			BookID:    it.Book.ID,
+			Title:     it.Book.Title,
+			Format:    format,
+			Quantity:  it.Quantity,
+			UnitPrice: it.UnitPrice,
+			Discount:  money.Zero(currency),
+		})
+	}
+	for _, d := range discounts {
+		order.Adjustments = append(order.Adjustments, Adjustment{
+			PromotionID: d.PromotionID,
+			Description: d.Description,
+			Amount:      d.Amount.Neg(),
+		})
+		for i, a := range d.ItemAmounts {
+			if order.Lines[i].Discount, err = order.Lines[i].Discount.Add(a); err != nil {
+				return Order{}, err
+			}
+		}
+	}
+	if err := s.applyTaxes(ctx, &order); err != nil {
+		return Order{}, err
+	}
+	if err := order.UpdateTotal(); err != nil {
+		return Order{}, err
+	}
+
+	// Redeem before storing the order, so that usage limits can't be
+	// exceeded by concurrent orders. Both happen in one unit of work, so a
+	// failing order doesn't use up coupons.
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.promotions.Redeem(ctx, discounts); err != nil {
+			return err
+		}
+		return s.r.Create(ctx, &order)
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+// ShippingQuotes returns shipping methods available for the print books of
+// the cart, cheapest first. Only country and region of the shipping address
+// are needed to quote.
+func (s basicService) ShippingQuotes(ctx context.Context, cart Cart) ([]shipping.Quote, error) {
+	if err := cart.Validate(); err != nil {
+		return nil, err
+	}
+	rates, err := s.rates.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, currency, _, err := s.cartItems(ctx, cart, rates)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := parcel(cart.ShippingAddress, items)
+	if !ok {
+		return []shipping.Quote{}, nil
+	}
+	if p.Destination.Country == "" {
+		return nil, validate.Field("shipping_address.country", validate.Missing, "is required")
+	}
+	quotes, err := s.shipping.Quotes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	for i := range quotes {
+		if quotes[i].Cost, err = rates.Convert(quotes[i].Cost, currency, money.RoundHalfEven); err != nil {
+			return nil, err
+		}
+	}
+	return quotes, nil
+}
+
+// cartItems looks up the books of the cart and prices them in cart currency.
+// It also returns the currency used, and the base currencies of the books.
+func (s basicService) cartItems(ctx context.Context, cart Cart, rates money.Rates) ([]promotion.Item, money.Currency, []money.Currency, error) {
+	currency := cart.Currency
+	items := make([]promotion.Item, 0, len(cart.Items))
+	used := make([]money.Currency, 0, len(cart.Items)+2)
+	for _, it := range cart.Items {
+		book, err := s.books.GetByID(ctx, it.BookID)
+		if err == db.ErrNotFound {
+			return nil, "", nil, catalog.ErrBookNotFound
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if currency == "" {
+			currency = book.Price.Currency
+		}
+		price, err := book.PriceIn(currency, rates)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		items = append(items, promotion.Item{Book: book, Quantity: it.Quantity, UnitPrice: price})
+		used = append(used, book.Price.Currency)
+	}
+	return items, currency, used, nil
 }
 
-// PlaceOrder creates an order for particular book.
-func (s basicService) PlaceOrder(ctx context.Context, bookID string) (Order, error) {
-	return Order{}, nil
+// parcel returns the parcel of print books in items shipped to address,
+// false if every book is digital.
+func parcel(address user.PostalAddress, items []promotion.Item) (shipping.Parcel, bool) {
+	p := shipping.Parcel{
+		Destination: shipping.Destination{Country: address.Country, Region: address.Region},
+	}
+	ship := false
+	for _, it := range items {
+		if it.Book.Digital() {
+			continue
+		}
+		ship = true
+		p.WeightGrams += it.Book.WeightGrams * it.Quantity
+	}
+	return p, ship
+}
+
+// applyTaxes calculates tax of every line for the order billing location.
+func (s basicService) applyTaxes(ctx context.Context, order *Order) error {
+	lines := make([]tax.Line, len(order.Lines))
+	for i := range order.Lines {
+		net, err := order.Lines[i].Net()
+		if err != nil {
+			return err
+		}
+		lines[i] = tax.Line{
+			Class:  tax.ProductClass(order.Lines[i].Format),
+			Amount: net,
+		}
+	}
+	loc := tax.Location{Country: order.BillingCountry, Region: order.BillingRegion}
+	taxes, err := s.taxes.Calculate(ctx, loc, lines)
+	if err != nil {
+		return err
+	}
+	for i, t := range taxes {
+		l := &order.Lines[i]
+		l.TaxName, l.TaxRate, l.TaxInclusive, l.TaxAmount = t.Name, t.Rate, t.Inclusive, t.Amount
+	}
+	return nil
 }
 
 // GetUserOrders return all the orders placed by particular user.
@@ -45,4 +290,37 @@ func (s basicService) CancelOrder(ctx context.Context, userID string, orderID st
 	return nil
 }
 
+// Delete soft deletes an order. It fails with db.ErrPreconditionFailed if
+// ctx expects another version of the order, and with db.ErrConflict if the
+// order is saved before it is deleted.
+func (s basicService) Delete(ctx context.Context, id string) error {
+	order, err := s.r.GetByID(ctx, id)
+	if err == db.ErrNotFound {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := db.CheckIfMatch(ctx, order.Version); err != nil {
+		return err
+	}
+	return s.r.Delete(ctx, id, order.Version)
+}
+
+// ListDeleted lists the deleted orders of the repo.
+func (s basicService) ListDeleted(ctx context.Context) ([]Order, error) {
+	return s.r.ListDeleted(ctx)
+}
+
+// Restore restores a deleted order and returns it.
+func (s basicService) Restore(ctx context.Context, id string) (Order, error) {
+	if err := s.r.Restore(ctx, id); err != nil {
+		if err == db.ErrNotFound {
+			return Order{}, ErrOrderNotFound
+		}
+		return Order{}, err
+	}
+	return s.r.GetByID(ctx, id)
+}
+
 type Middleware func(Service) Service
@@ -0,0 +1,69 @@
+package order_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/exchange"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/order"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/kavirajk/bookshop/tax"
+	"github.com/pkg/errors"
+)
+
+// downBooks fails every lookup with err.
+type downBooks struct {
+	catalog.Repo
+	err error
+}
+
+func (r downBooks) GetByID(context.Context, string) (catalog.Book, error) {
+	return catalog.Book{}, r.err
+}
+
+func TestPlaceOrderBookLookup(t *testing.T) {
+	ctx := context.Background()
+	taxes, _ := tax.NewTable(nil)
+	rates, _ := shipping.NewTable(nil)
+	errDown := errors.New("catalog down")
+	cart := order.Cart{Items: []order.CartItem{{BookID: "go", Quantity: 1}}}
+
+	for _, c := range []struct {
+		name  string
+		books catalog.Repo
+		want  error
+	}{
+		{"missing book", inmem.NewCatalogRepo(), catalog.ErrBookNotFound},
+		{"failing catalog", downBooks{err: errDown}, errDown},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := order.NewService(inmem.NewOrderRepo(), db.NoTx, c.books, exchange.NewService(money.Rates{Base: "USD"}),
+				promotion.NewService(inmem.NewPromotionRepo()), taxes, rates)
+			if _, err := s.PlaceOrder(ctx, cart); err != c.want {
+				t.Errorf("expected %v, got %v", c.want, err)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderOverflow(t *testing.T) {
+	ctx := context.Background()
+	taxes, _ := tax.NewTable(nil)
+	rates, _ := shipping.NewTable(nil)
+	books := inmem.NewCatalogRepo()
+	book := catalog.Book{Title: "Dune", Format: catalog.Ebook, Price: money.New(math.MaxInt64/2+1, money.USD)}
+	books.Create(ctx, &book)
+	s := order.NewService(inmem.NewOrderRepo(), db.NoTx, books, exchange.NewService(money.Rates{Base: "USD"}),
+		promotion.NewService(inmem.NewPromotionRepo()), taxes, rates)
+
+	cart := order.Cart{Items: []order.CartItem{{BookID: book.ID, Quantity: 2}}}
+	if _, err := s.PlaceOrder(ctx, cart); errors.Cause(err) != money.ErrInvalidAmount {
+		t.Errorf("expected %v, got %v", money.ErrInvalidAmount, err)
+	}
+}
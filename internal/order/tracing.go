@@ -0,0 +1,68 @@
+package order
+
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingService struct {
+	tracer trace.Tracer
+	next   Service
+}
+
+// TracingMiddleware traces every call of the service in a span, child of the
+// span of its ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next Service) Service {
+		return tracingService{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+func (s tracingService) PlaceOrder(ctx context.Context, cart Cart) (order Order, err error) {
+	ctx, span := s.tracer.Start(ctx, "order.PlaceOrder")
+	defer func() { tracing.End(span, err) }()
+	return s.next.PlaceOrder(ctx, cart)
+}
+
+func (s tracingService) ShippingQuotes(ctx context.Context, cart Cart) (quotes []shipping.Quote, err error) {
+	ctx, span := s.tracer.Start(ctx, "order.ShippingQuotes")
+	defer func() { tracing.End(span, err) }()
+	return s.next.ShippingQuotes(ctx, cart)
+}
+
+func (s tracingService) GetUserOrders(ctx context.Context, userID string) (orders []Order, err error) {
+	ctx, span := s.tracer.Start(ctx, "order.GetUserOrders", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.GetUserOrders(ctx, userID)
+}
+
+func (s tracingService) CancelOrder(ctx context.Context, userID string, orderID string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "order.CancelOrder", trace.WithAttributes(attribute.String("user.id", userID), attribute.String("order.id", orderID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.CancelOrder(ctx, userID, orderID)
+}
+
+func (s tracingService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "order.Delete", trace.WithAttributes(attribute.String("order.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Delete(ctx, id)
+}
+
+func (s tracingService) ListDeleted(ctx context.Context) (orders []Order, err error) {
+	ctx, span := s.tracer.Start(ctx, "order.ListDeleted")
+	defer func() { tracing.End(span, err) }()
+	return s.next.ListDeleted(ctx)
+}
+
+func (s tracingService) Restore(ctx context.Context, id string) (order Order, err error) {
+	ctx, span := s.tracer.Start(ctx, "order.Restore", trace.WithAttributes(attribute.String("order.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Restore(ctx, id)
+}
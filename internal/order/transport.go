@@ -1,7 +1,6 @@
 package order
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"context"
@@ -9,57 +8,123 @@ import (
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/shipping"
 	"github.com/kavirajk/bookshop/transport"
-	"github.com/pkg/errors"
 )
 
 var (
-	ErrBadRouting = errors.New("bad routing")
+	ErrBadRouting = transport.ErrBadRouting
 )
 
-func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+// errs are the errors of the orders sent over HTTP, including the ones of
+// the services placing an order depends on.
+var errs = transport.NewRegistry().
+	Register(ErrOrderNotFound, http.StatusNotFound, "order_not_found").
+	Register(catalog.ErrBookNotFound, http.StatusNotFound, "book_not_found").
+	Register(shipping.ErrUnknownMethod, http.StatusBadRequest, "unknown_shipping_method").
+	Register(money.ErrUnknownCurrency, http.StatusBadRequest, "unknown_currency").
+	Register(money.ErrNoRate, http.StatusBadRequest, "no_exchange_rate").
+	Register(promotion.ErrInvalidCoupon, http.StatusUnprocessableEntity, "invalid_coupon").
+	Register(promotion.ErrCouponExpired, http.StatusUnprocessableEntity, "coupon_expired").
+	Register(promotion.ErrCouponExhausted, http.StatusUnprocessableEntity, "coupon_exhausted").
+	Register(shipping.ErrNoRate, http.StatusUnprocessableEntity, "no_shipping_rate")
+
+var encodeResponse = transport.ResponseEncoder(errs)
+
+// MakeRoutes returns the routes of the orders, mounted under /orders/v1.
+func MakeRoutes(ctx context.Context, s Service, logger log.Logger) transport.Routes {
 	e := MakeEndpoints(s)
-	options := []httptransport.ServerOption{
-		httptransport.ServerErrorEncoder(encodeError),
-	}
+	options := transport.ServerOptions(errs)
 	placeOrderHandler := httptransport.NewServer(
 		e.PlaceOrderEndpoint,
-		decodePlaceOrderRequest,
+		transport.Validated(decodePlaceOrderRequest),
+		encodeResponse,
+		options...,
+	)
+	shippingQuotesHandler := httptransport.NewServer(
+		e.ShippingQuotesEndpoint,
+		transport.Validated(decodePlaceOrderRequest),
 		encodeResponse,
 		options...,
 	)
 	getUserOrdersHandler := httptransport.NewServer(
 		e.GetUserOrdersEndpoint,
-		decodeGetUserOrdersRequest,
+		transport.Validated(decodeGetUserOrdersRequest),
 		encodeResponse,
 		options...,
 	)
 	cancelOrdersHandler := httptransport.NewServer(
 		e.CancelOrderEndpoint,
-		decodeCancelOrderRequest,
+		transport.Validated(decodeCancelOrderRequest),
 		encodeResponse,
 		options...,
 	)
 
-	r := mux.NewRouter()
+	deleteHandler := httptransport.NewServer(
+		e.DeleteEndpoint,
+		transport.Validated(decodeDeleteRequest),
+		encodeResponse,
+		options...,
+	)
+	listDeletedHandler := httptransport.NewServer(
+		e.ListDeletedEndpoint,
+		transport.Validated(decodeListDeletedRequest),
+		encodeResponse,
+		options...,
+	)
+	restoreHandler := httptransport.NewServer(
+		e.RestoreEndpoint,
+		transport.Validated(decodeDeleteRequest),
+		encodeResponse,
+		options...,
+	)
 
-	r.Handle("/orders/v1/place", placeOrderHandler).Methods("POST")
-	r.Handle("/orders/v1/{user-id}", getUserOrdersHandler).Methods("GET")
-	r.Handle("/orders/v1/{user-id}/cancel/{id}", cancelOrdersHandler).Methods("POST")
+	return transport.NewRoutes("/orders/v1", func(r *mux.Router) {
+		r.Handle("/place", placeOrderHandler).Methods("POST")
+		r.Handle("/shipping-quotes", shippingQuotesHandler).Methods("POST")
+		// Before {id} routes, so that "deleted" isn't taken for a user id.
+		r.Handle("/deleted", listDeletedHandler).Methods("GET")
+		r.Handle("/{user-id}", getUserOrdersHandler).Methods("GET")
+		r.Handle("/{user-id}/cancel/{id}", cancelOrdersHandler).Methods("POST")
+
+		// Admin routes of soft deleted orders.
+		r.Handle("/{id}", deleteHandler).Methods("DELETE")
+		r.Handle("/{id}/restore", restoreHandler).Methods("POST")
+	})
+}
 
+// MakeHTTPHandler returns handler of the routes of MakeRoutes, e.g: for
+// tests.
+func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+	r := mux.NewRouter()
+	transport.Mount(r, MakeRoutes(ctx, s, logger))
 	return r
 }
 func decodePlaceOrderRequest(ctx context.Context, req *http.Request) (interface{}, error) {
 	var r placeOrderRequest
-	err := json.NewDecoder(req.Body).Decode(&r)
-	return r, err
+	if err := transport.DecodeJSON(req, &r); err != nil {
+		return nil, err
+	}
+	if r.BookID != "" && len(r.Items) == 0 {
+		r.Items = []CartItem{{BookID: r.BookID, Quantity: 1}}
+	}
+	if r.Currency != "" {
+		c, err := money.ParseCurrency(string(r.Currency))
+		if err != nil {
+			return nil, err
+		}
+		r.Currency = c
+	}
+	return r, nil
 }
 
 func decodeGetUserOrdersRequest(ctx context.Context, req *http.Request) (interface{}, error) {
-	vars := mux.Vars(req)
-	userID, ok := vars["user-id"]
-	if !ok {
-		return nil, errors.Wrap(ErrBadRouting, "user-id")
+	userID, err := transport.Var(req, "user-id")
+	if err != nil {
+		return nil, err
 	}
 	return getUserOrdersRequest{
 		UserID: userID,
@@ -67,14 +132,13 @@ func decodeGetUserOrdersRequest(ctx context.Context, req *http.Request) (interfa
 }
 
 func decodeCancelOrderRequest(ctx context.Context, req *http.Request) (interface{}, error) {
-	vars := mux.Vars(req)
-	userID, ok := vars["user-id"]
-	if !ok {
-		return nil, errors.Wrap(ErrBadRouting, "user-id")
+	userID, err := transport.Var(req, "user-id")
+	if err != nil {
+		return nil, err
 	}
-	ID, ok := vars["id"]
-	if !ok {
-		return nil, errors.Wrap(ErrBadRouting, "id")
+	ID, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
 	}
 
 	return cancelOrderRequest{
@@ -83,72 +147,14 @@ func decodeCancelOrderRequest(ctx context.Context, req *http.Request) (interface
 	}, nil
 }
 
-// errorer interface should be implemented by all the doman specific errors.
-// easy to set different status code in case of different errors.
-type errorer interface {
-	error() error
-}
-
-// statuser allows any response to get customer status code
-// e.g: 201 for successfull resource creation.
-type statuser interface {
-	status() int
-}
-
-// pager used to paginate any transport response.
-type pager interface {
-	page() (total int, previous, next string)
-}
-
-func encodeResponse(ctx context.Context, w http.ResponseWriter, d interface{}) error {
-	if e, ok := d.(errorer); ok && e.error() != nil {
-		// Now its a business logic error.
-		// Extract base domain error.
-		encodeError(ctx, e.error(), w)
-		return nil
-	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-	status := http.StatusOK
-	if s, ok := d.(statuser); ok && s.status() != 0 {
-		status = s.status()
+func decodeDeleteRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
 	}
-
-	f := transport.FormatResponse{
-		Data: d,
-		Meta: transport.MetaResponse{Status: status},
-	}
-
-	if page, ok := d.(pager); ok {
-		t, p, n := page.page()
-		f.Meta.Total = t
-		f.Meta.Previous = p
-		f.Meta.Next = n
-	}
-
-	return json.NewEncoder(w).Encode(f)
+	return deleteRequest{ID: id}, nil
 }
 
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	if err == nil {
-		panic("encodeError with nil error")
-	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	// Its important to pass errors.Cause() as we decide status code based on
-	// root error which is domain specific
-	code := codeFrom(errors.Cause(err))
-	w.WriteHeader(code)
-	f := transport.FormatResponse{Meta: transport.MetaResponse{Status: code, Error: err.Error()}}
-	json.NewEncoder(w).Encode(f)
-}
-
-func codeFrom(err error) int {
-	switch err {
-	case ErrOrderNotFound:
-		return http.StatusNotFound
-	case ErrBadRouting:
-		return http.StatusBadRequest
-	default:
-		return http.StatusInternalServerError
-	}
+func decodeListDeletedRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return nil, nil
 }
@@ -1,4 +1,19 @@
 package payment
 
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/money"
+)
+
+// Payment represents a single charge made against an order.
+type Payment struct {
+	ID      string      `json:"id"`
+	OrderID string      `json:"order_id"`
+	Amount  money.Money `json:"amount" gorm:"embedded;embedded_prefix:amount_"`
+}
+
 type Service interface {
+	// Pay charges amount for the order.
+	Pay(ctx context.Context, orderID string, amount money.Money) (Payment, error)
 }
@@ -0,0 +1,114 @@
+package promotion
+
+import (
+	"net/http"
+
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/tracing"
+)
+
+// Endpoints combine all the promotion service endpoints under single type.
+type Endpoints struct {
+	CreateEndpoint endpoint.Endpoint
+	UpdateEndpoint endpoint.Endpoint
+	GetEndpoint    endpoint.Endpoint
+	ListEndpoint   endpoint.Endpoint
+}
+
+// MakeEndpoints returns Endpoints type which is the combination of
+// all the promotion service endpoints. They all need
+// auth.ManagePromotions, promotions carry their coupon codes.
+func MakeEndpoints(s Service) Endpoints {
+	admin := auth.Require(auth.ManagePromotions)
+	return Endpoints{
+		CreateEndpoint: tracing.Endpoint("promotion.create")(admin(MakeCreateEndpoint(s))),
+		UpdateEndpoint: tracing.Endpoint("promotion.update")(admin(MakeUpdateEndpoint(s))),
+		GetEndpoint:    tracing.Endpoint("promotion.get")(admin(MakeGetEndpoint(s))),
+		ListEndpoint:   tracing.Endpoint("promotion.list")(admin(MakeListEndpoint(s))),
+	}
+}
+
+func MakeCreateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createRequest)
+		p, e := s.Create(ctx, req.Promotion)
+		if e != nil {
+			return promotionResponse{Error: e}, nil
+		}
+		return promotionResponse{Promotion: &p, Status: http.StatusCreated}, nil
+	}
+}
+
+func MakeUpdateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateRequest)
+		req.Promotion.ID = req.ID
+		p, e := s.Update(ctx, req.Promotion)
+		if e != nil {
+			return promotionResponse{Error: e}, nil
+		}
+		return promotionResponse{Promotion: &p}, nil
+	}
+}
+
+func MakeGetEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getRequest)
+		p, e := s.Get(ctx, req.ID)
+		if e != nil {
+			return promotionResponse{Error: e}, nil
+		}
+		return promotionResponse{Promotion: &p}, nil
+	}
+}
+
+func MakeListEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		promos, e := s.List(ctx)
+		if e != nil {
+			return listResponse{Promotions: make([]Promotion, 0), Error: e}, nil
+		}
+		return listResponse{Promotions: promos}, nil
+	}
+}
+
+type createRequest struct {
+	Promotion
+}
+
+type updateRequest struct {
+	ID string `json:"-"`
+	Promotion
+}
+
+type getRequest struct {
+	ID string `json:"id"`
+}
+
+type listRequest struct{}
+
+type promotionResponse struct {
+	Status    int        `json:"-"`
+	Promotion *Promotion `json:"promotion,omitempty"`
+	Error     error      `json:"error,omitempty"`
+}
+
+func (r promotionResponse) StatusCode() int {
+	return r.Status
+}
+
+func (r promotionResponse) Failed() error {
+	return r.Error
+}
+
+type listResponse struct {
+	Promotions []Promotion `json:"promotions"`
+	Error      error       `json:"error,omitempty"`
+}
+
+func (r listResponse) Failed() error {
+	return r.Error
+}
@@ -0,0 +1,207 @@
+package promotion
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidCoupon   = errors.New("invalid coupon")
+	ErrCouponExpired   = errors.New("coupon expired")
+	ErrCouponExhausted = errors.New("coupon usage limit reached")
+)
+
+// Item is a book on the order the promotions are evaluated against.
+// UnitPrice must be in the order currency.
+type Item struct {
+	Book      catalog.Book
+	Quantity  int
+	UnitPrice money.Money
+}
+
+// Discount is the amount a promotion takes off an order.
+// ItemAmounts tells how much of Amount was taken off each item,
+// in the same order as the evaluated items.
+type Discount struct {
+	PromotionID string
+	Description string
+	Amount      money.Money
+	ItemAmounts []money.Money
+}
+
+// Evaluate applies promotions to items and returns the discounts given,
+// in the order they were applied.
+//
+// Evaluation is deterministic: promotions are sorted by Priority and then
+// by ID. Each promotion is applied to what is left after the previous ones,
+// so an item never gets discounted below zero. An Exclusive promotion that
+// gives a discount stops the evaluation.
+//
+// Automatic promotions always take part, coupon promotions only when coupon
+// matches. A non-empty coupon that matches nothing usable is an error.
+func Evaluate(promos []Promotion, items []Item, coupon string, currency money.Currency, now time.Time) ([]Discount, error) {
+	coupon = NormalizeCoupon(coupon)
+
+	candidates := make([]Promotion, 0, len(promos))
+	couponOK := coupon == ""
+	couponErr := ErrInvalidCoupon
+	for _, p := range promos {
+		if p.Coupon != "" && p.Coupon != coupon {
+			continue
+		}
+		if !p.Live(now) {
+			if p.Coupon != "" && p.Active && !p.ExpiresAt.IsZero() && !now.Before(p.ExpiresAt) {
+				couponErr = ErrCouponExpired
+			}
+			continue
+		}
+		if p.Exhausted() {
+			if p.Coupon != "" {
+				couponErr = ErrCouponExhausted
+			}
+			continue
+		}
+		if p.Coupon != "" {
+			couponOK = true
+		}
+		candidates = append(candidates, p)
+	}
+	if !couponOK {
+		return nil, couponErr
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority < candidates[j].Priority
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	remaining := make([]money.Money, len(items))
+	for i, it := range items {
+		var err error
+		if remaining[i], err = it.UnitPrice.Mul(int64(it.Quantity)); err != nil {
+			return nil, err
+		}
+	}
+
+	discounts := make([]Discount, 0)
+	for i := range candidates {
+		p := &candidates[i]
+		matching := make([]int, 0, len(items))
+		for j := range items {
+			if p.Matches(&items[j].Book) {
+				matching = append(matching, j)
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+
+		var off []money.Money
+		switch p.Kind {
+		case Percentage:
+			off = percentOff(p, matching, remaining)
+		case Fixed:
+			off = fixedOff(p, matching, remaining, currency)
+		case BuyXGetY:
+			off = buyXGetYOff(p, matching, items, remaining)
+		}
+
+		total := money.Zero(currency)
+		perItem := make([]money.Money, len(items))
+		for j := range perItem {
+			perItem[j] = money.Zero(currency)
+		}
+		for j, o := range off {
+			remaining[matching[j]], _ = remaining[matching[j]].Sub(o)
+			perItem[matching[j]] = o
+			total, _ = total.Add(o)
+		}
+		if total.IsZero() {
+			continue
+		}
+		discounts = append(discounts, Discount{
+			PromotionID: p.ID,
+			Description: p.Name,
+			Amount:      total,
+			ItemAmounts: perItem,
+		})
+		if p.Exclusive {
+			break
+		}
+	}
+	return discounts, nil
+}
+
+// percentOff returns discount of each matching item.
+func percentOff(p *Promotion, matching []int, remaining []money.Money) []money.Money {
+	r := big.NewRat(int64(p.Percent), 100)
+	off := make([]money.Money, len(matching))
+	for j, i := range matching {
+		off[j] = remaining[i].MulRat(r, money.RoundHalfUp)
+	}
+	return off
+}
+
+// fixedOff takes the fixed amount off matching items in order,
+// never going below zero. Amount in other currency doesn't apply.
+func fixedOff(p *Promotion, matching []int, remaining []money.Money, currency money.Currency) []money.Money {
+	off := make([]money.Money, len(matching))
+	left := p.Amount
+	for j, i := range matching {
+		off[j] = money.Zero(currency)
+		if left.Currency != currency || left.IsZero() {
+			continue
+		}
+		if c, _ := left.Cmp(remaining[i]); c > 0 {
+			off[j] = remaining[i]
+		} else {
+			off[j] = left
+		}
+		left, _ = left.Sub(off[j])
+	}
+	return off
+}
+
+// buyXGetYOff expands matching items into units sorted by price, highest
+// first. In every group of BuyQuantity+GetQuantity units the last
+// GetQuantity (cheapest) units are free.
+func buyXGetYOff(p *Promotion, matching []int, items []Item, remaining []money.Money) []money.Money {
+	type unit struct {
+		pos   int // position in matching
+		price money.Money
+	}
+	units := make([]unit, 0)
+	for j, i := range matching {
+		for q := 0; q < items[i].Quantity; q++ {
+			units = append(units, unit{pos: j, price: items[i].UnitPrice})
+		}
+	}
+	sort.SliceStable(units, func(a, b int) bool {
+		return units[a].price.Amount > units[b].price.Amount
+	})
+
+	off := make([]money.Money, len(matching))
+	for j, i := range matching {
+		off[j] = money.Zero(remaining[i].Currency)
+	}
+	group := p.BuyQuantity + p.GetQuantity
+	for n := group; n <= len(units); n += group {
+		for _, u := range units[n-p.GetQuantity : n] {
+			off[u.pos], _ = off[u.pos].Add(u.price)
+		}
+	}
+	// cap by what is left on the item after earlier promotions.
+	for j, i := range matching {
+		if c, _ := off[j].Cmp(remaining[i]); c > 0 {
+			off[j] = remaining[i]
+		}
+	}
+	return off
+}
@@ -0,0 +1,109 @@
+package promotion_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/promotion"
+)
+
+var now = time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func items() []promotion.Item {
+	return []promotion.Item{
+		{
+			Book:      catalog.Book{ID: "go", Genres: []catalog.Genre{{ID: "programming"}}},
+			Quantity:  1,
+			UnitPrice: money.New(4000, money.USD),
+		},
+		{
+			Book:      catalog.Book{ID: "dune", Genres: []catalog.Genre{{ID: "scifi"}}, PublisherID: "ace"},
+			Quantity:  3,
+			UnitPrice: money.New(1000, money.USD),
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Run("deterministic order", func(t *testing.T) {
+		promos := []promotion.Promotion{
+			{ID: "b", Name: "5 off", Kind: promotion.Fixed, Amount: money.New(500, money.USD), Priority: 2, Active: true},
+			{ID: "a", Name: "10%", Kind: promotion.Percentage, Percent: 10, Priority: 1, Active: true},
+		}
+		ds, err := promotion.Evaluate(promos, items(), "", money.USD, now)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(ds) != 2 || ds[0].PromotionID != "a" || ds[1].PromotionID != "b" {
+			t.Fatalf("expected [a b], got %v", ds)
+		}
+		// 10% of 70.00, then 5.00 off what is left.
+		if ds[0].Amount.Decimal() != "7.00" || ds[1].Amount.Decimal() != "5.00" {
+			t.Errorf("expected 7.00 and 5.00, got %v and %v", ds[0].Amount, ds[1].Amount)
+		}
+	})
+
+	t.Run("buy x get y scoped by genre", func(t *testing.T) {
+		promos := []promotion.Promotion{
+			{ID: "a", Name: "3 for 2", Kind: promotion.BuyXGetY, BuyQuantity: 2, GetQuantity: 1, GenreID: "scifi", Active: true},
+		}
+		ds, err := promotion.Evaluate(promos, items(), "", money.USD, now)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(ds) != 1 || ds[0].Amount.Decimal() != "10.00" {
+			t.Errorf("expected single 10.00 discount, got %v", ds)
+		}
+	})
+
+	t.Run("exclusive stops evaluation", func(t *testing.T) {
+		promos := []promotion.Promotion{
+			{ID: "a", Name: "ace 50%", Kind: promotion.Percentage, Percent: 50, PublisherID: "ace", Exclusive: true, Active: true},
+			{ID: "b", Name: "10%", Kind: promotion.Percentage, Percent: 10, Priority: 1, Active: true},
+		}
+		ds, _ := promotion.Evaluate(promos, items(), "", money.USD, now)
+		if len(ds) != 1 || ds[0].Amount.Decimal() != "15.00" {
+			t.Errorf("expected single 15.00 discount, got %v", ds)
+		}
+	})
+
+	t.Run("never below zero", func(t *testing.T) {
+		promos := []promotion.Promotion{
+			{ID: "a", Name: "100 off", Kind: promotion.Fixed, Amount: money.New(10000, money.USD), Active: true},
+		}
+		ds, _ := promotion.Evaluate(promos, items(), "", money.USD, now)
+		if len(ds) != 1 || ds[0].Amount.Decimal() != "70.00" {
+			t.Errorf("expected single 70.00 discount, got %v", ds)
+		}
+	})
+}
+
+func TestEvaluateCoupon(t *testing.T) {
+	promos := []promotion.Promotion{
+		{ID: "a", Name: "summer", Kind: promotion.Percentage, Percent: 20, Coupon: "SUMMER", Active: true},
+		{ID: "b", Name: "spring", Kind: promotion.Percentage, Percent: 20, Coupon: "SPRING", Active: true, ExpiresAt: now.Add(-time.Hour)},
+		{ID: "c", Name: "launch", Kind: promotion.Percentage, Percent: 20, Coupon: "LAUNCH", Active: true, UsageLimit: 1, Used: 1},
+	}
+	cases := []struct {
+		coupon string
+		err    error
+		count  int
+	}{
+		{"", nil, 0},
+		{"summer", nil, 1},
+		{"SPRING", promotion.ErrCouponExpired, 0},
+		{"LAUNCH", promotion.ErrCouponExhausted, 0},
+		{"WINTER", promotion.ErrInvalidCoupon, 0},
+	}
+	for _, c := range cases {
+		ds, err := promotion.Evaluate(promos, items(), c.coupon, money.USD, now)
+		if err != c.err {
+			t.Errorf("%q: expected %v, got %v", c.coupon, c.err, err)
+		}
+		if len(ds) != c.count {
+			t.Errorf("%q: expected %d discounts, got %d", c.coupon, c.count, len(ds))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package promotion
+
+import (
+	"fmt"
+	"time"
+
+	"context"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/money"
+)
+
+type instrmw struct {
+	requestCount   metrics.Counter
+	requestLatency metrics.Histogram
+	next           Service
+}
+
+func InstrumentingMiddleware(counter metrics.Counter, latency metrics.Histogram) Middleware {
+	return func(next Service) Service {
+		return instrmw{
+			requestCount:   counter,
+			requestLatency: latency,
+			next:           next,
+		}
+	}
+}
+
+func (mw instrmw) Create(ctx context.Context, p Promotion) (promo Promotion, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "create", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	promo, err = mw.next.Create(ctx, p)
+	return
+}
+
+func (mw instrmw) Update(ctx context.Context, p Promotion) (promo Promotion, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "update", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	promo, err = mw.next.Update(ctx, p)
+	return
+}
+
+func (mw instrmw) Get(ctx context.Context, id string) (promo Promotion, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "get", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	promo, err = mw.next.Get(ctx, id)
+	return
+}
+
+func (mw instrmw) List(ctx context.Context) (promos []Promotion, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "list", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	promos, err = mw.next.List(ctx)
+	return
+}
+
+func (mw instrmw) Apply(ctx context.Context, items []Item, coupon string, currency money.Currency) (discounts []Discount, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "apply", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	discounts, err = mw.next.Apply(ctx, items, coupon, currency)
+	return
+}
+
+func (mw instrmw) Redeem(ctx context.Context, discounts []Discount) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "redeem", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	err = mw.next.Redeem(ctx, discounts)
+	return
+}
+
+type instrumentingRepo struct {
+	duration metrics.Histogram
+	next     Repo
+}
+
+// InstrumentingRepo returns r timing its queries in duration, labelled by
+// method and error, see metrics.Queries.
+func InstrumentingRepo(r Repo, duration metrics.Histogram) Repo {
+	return instrumentingRepo{duration: duration.With("repo", "promotions"), next: r}
+}
+
+func (r instrumentingRepo) observe(method string, begin time.Time, err error) {
+	r.duration.With("method", method, "error", fmt.Sprint(err != nil)).Observe(time.Since(begin).Seconds())
+}
+
+func (r instrumentingRepo) Create(ctx context.Context, p *Promotion) (err error) {
+	defer func(begin time.Time) { r.observe("create", begin, err) }(time.Now())
+	err = r.next.Create(ctx, p)
+	return
+}
+
+func (r instrumentingRepo) Save(ctx context.Context, p *Promotion) (err error) {
+	defer func(begin time.Time) { r.observe("save", begin, err) }(time.Now())
+	err = r.next.Save(ctx, p)
+	return
+}
+
+func (r instrumentingRepo) GetByID(ctx context.Context, ID string) (p Promotion, err error) {
+	defer func(begin time.Time) { r.observe("get_by_id", begin, err) }(time.Now())
+	p, err = r.next.GetByID(ctx, ID)
+	return
+}
+
+func (r instrumentingRepo) List(ctx context.Context) (promotions []Promotion, err error) {
+	defer func(begin time.Time) { r.observe("list", begin, err) }(time.Now())
+	promotions, err = r.next.List(ctx)
+	return
+}
+
+func (r instrumentingRepo) ListApplicable(ctx context.Context, coupon string) (promotions []Promotion, err error) {
+	defer func(begin time.Time) { r.observe("list_applicable", begin, err) }(time.Now())
+	promotions, err = r.next.ListApplicable(ctx, coupon)
+	return
+}
+
+func (r instrumentingRepo) Redeem(ctx context.Context, ID string) (err error) {
+	defer func(begin time.Time) { r.observe("redeem", begin, err) }(time.Now())
+	err = r.next.Redeem(ctx, ID)
+	return
+}
+
+func (r instrumentingRepo) Drop(ctx context.Context) (err error) {
+	defer func(begin time.Time) { r.observe("drop", begin, err) }(time.Now())
+	err = r.next.Drop(ctx)
+	return
+}
@@ -0,0 +1,95 @@
+package promotion
+
+import (
+	"time"
+
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/money"
+)
+
+type loggingService struct {
+	logger log.Logger
+	next   Service
+}
+
+func LoggingMiddleware(logger log.Logger) Middleware {
+	return func(next Service) Service {
+		return loggingService{
+			logger: logger,
+			next:   next,
+		}
+	}
+}
+
+func (s loggingService) Create(ctx context.Context, p Promotion) (promo Promotion, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "create",
+			"promotion_id", promo.ID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.Create(ctx, p)
+}
+
+func (s loggingService) Update(ctx context.Context, p Promotion) (promo Promotion, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "update",
+			"promotion_id", p.ID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.Update(ctx, p)
+}
+
+func (s loggingService) Get(ctx context.Context, id string) (promo Promotion, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "get",
+			"promotion_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.Get(ctx, id)
+}
+
+func (s loggingService) List(ctx context.Context) (promos []Promotion, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "list",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.List(ctx)
+}
+
+func (s loggingService) Apply(ctx context.Context, items []Item, coupon string, currency money.Currency) (discounts []Discount, err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "apply",
+			"coupon", coupon,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.Apply(ctx, items, coupon, currency)
+}
+
+func (s loggingService) Redeem(ctx context.Context, discounts []Discount) (err error) {
+	defer func(begin time.Time) {
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "redeem",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.next.Redeem(ctx, discounts)
+}
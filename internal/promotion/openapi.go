@@ -0,0 +1,40 @@
+package promotion
+
+import (
+	"net/http"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/openapi"
+)
+
+// OpenAPI returns the API of the routes of MakeHTTPHandler.
+func OpenAPI() openapi.Service {
+	admin := []auth.Permission{auth.ManagePromotions}
+	return openapi.Service{
+		Name:   "promotions",
+		Errors: errs.Infos(),
+		Routes: []openapi.Route{
+			{
+				Name: "listPromotions", Method: "GET", Path: "/promotions/v1/list",
+				Summary:  "Lists the promotions",
+				Response: listResponse{}, Permissions: admin,
+			},
+			{
+				Name: "createPromotion", Method: "POST", Path: "/promotions/v1/create",
+				Summary: "Creates a promotion",
+				Request: createRequest{}, Response: promotionResponse{}, Status: http.StatusCreated,
+				Permissions: admin,
+			},
+			{
+				Name: "getPromotion", Method: "GET", Path: "/promotions/v1/{id}",
+				Summary:  "Returns the promotion",
+				Response: promotionResponse{}, Permissions: admin,
+			},
+			{
+				Name: "updatePromotion", Method: "PUT", Path: "/promotions/v1/{id}",
+				Summary: "Updates the promotion",
+				Request: updateRequest{}, Response: promotionResponse{}, Permissions: admin,
+			},
+		},
+	}
+}
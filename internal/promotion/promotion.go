@@ -0,0 +1,139 @@
+package promotion
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidPromotion = errors.New("invalid promotion")
+)
+
+// Kind tells how a promotion calculates its discount.
+type Kind string
+
+const (
+	// Percentage takes Percent off the matching items.
+	Percentage Kind = "percentage"
+	// Fixed takes Amount off the matching items.
+	Fixed Kind = "fixed"
+	// BuyXGetY gives GetQuantity cheapest matching items free
+	// for every BuyQuantity matching items bought.
+	BuyXGetY Kind = "buy_x_get_y"
+)
+
+// Promotion is a discount rule. Promotions without Coupon are applied
+// automatically, others only when the coupon code is given.
+// Empty GenreID, AuthorID and PublisherID means it applies to all books.
+type Promotion struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Kind        Kind        `json:"kind"`
+	Percent     int         `json:"percent,omitempty"`
+	Amount      money.Money `json:"amount" gorm:"embedded;embedded_prefix:amount_"`
+	BuyQuantity int         `json:"buy_quantity,omitempty"`
+	GetQuantity int         `json:"get_quantity,omitempty"`
+	GenreID     string      `json:"genre_id,omitempty"`
+	AuthorID    string      `json:"author_id,omitempty"`
+	PublisherID string      `json:"publisher_id,omitempty"`
+	Coupon      string      `json:"coupon,omitempty" gorm:"index"`
+	UsageLimit  int         `json:"usage_limit,omitempty"`
+	Used        int         `json:"used"`
+	Priority    int         `json:"priority"`
+	Exclusive   bool        `json:"exclusive"`
+	Active      bool        `json:"active"`
+	StartsAt    time.Time   `json:"starts_at"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
+
+// Validate does basic validation before saving into db.
+func (p *Promotion) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.Wrap(ErrInvalidPromotion, "name")
+	}
+	switch p.Kind {
+	case Percentage:
+		if p.Percent <= 0 || p.Percent > 100 {
+			return errors.Wrap(ErrInvalidPromotion, "percent")
+		}
+	case Fixed:
+		if !p.Amount.Currency.Valid() || p.Amount.Amount <= 0 {
+			return errors.Wrap(ErrInvalidPromotion, "amount")
+		}
+	case BuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return errors.Wrap(ErrInvalidPromotion, "buy_quantity, get_quantity")
+		}
+	default:
+		return errors.Wrap(ErrInvalidPromotion, "kind")
+	}
+	if p.UsageLimit < 0 {
+		return errors.Wrap(ErrInvalidPromotion, "usage_limit")
+	}
+	if !p.ExpiresAt.IsZero() && p.ExpiresAt.Before(p.StartsAt) {
+		return errors.Wrap(ErrInvalidPromotion, "expires_at")
+	}
+	p.Coupon = NormalizeCoupon(p.Coupon)
+	return nil
+}
+
+// Live reports whether promotion can be applied at time now.
+func (p *Promotion) Live(now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if !p.StartsAt.IsZero() && now.Before(p.StartsAt) {
+		return false
+	}
+	if !p.ExpiresAt.IsZero() && !now.Before(p.ExpiresAt) {
+		return false
+	}
+	return true
+}
+
+// Exhausted reports whether promotion reached its usage limit.
+func (p *Promotion) Exhausted() bool {
+	return p.UsageLimit > 0 && p.Used >= p.UsageLimit
+}
+
+// Matches reports whether book is in the scope of the promotion.
+func (p *Promotion) Matches(b *catalog.Book) bool {
+	if p.PublisherID != "" && p.PublisherID != b.PublisherID {
+		return false
+	}
+	if p.GenreID != "" {
+		found := false
+		for _, g := range b.Genres {
+			if g.ID == p.GenreID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if p.AuthorID != "" {
+		found := false
+		for _, a := range b.Authors {
+			if a.ID == p.AuthorID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeCoupon returns canonical form of coupon code.
+// Coupon codes are case insensitive.
+func NormalizeCoupon(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
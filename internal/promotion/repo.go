@@ -0,0 +1,20 @@
+package promotion
+
+import "context"
+
+// Repo abstracts all the persistant storage operations of Promotion service.
+type Repo interface {
+	Create(ctx context.Context, p *Promotion) error
+	Save(ctx context.Context, p *Promotion) error
+	GetByID(ctx context.Context, ID string) (Promotion, error)
+	List(ctx context.Context) ([]Promotion, error)
+
+	// ListApplicable returns active promotions that are either automatic
+	// or redeemable with coupon.
+	ListApplicable(ctx context.Context, coupon string) ([]Promotion, error)
+
+	// Redeem atomically increments usage of the promotion, failing with
+	// ErrCouponExhausted if it has reached its usage limit.
+	Redeem(ctx context.Context, ID string) error
+	Drop(ctx context.Context) error
+}
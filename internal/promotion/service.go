@@ -0,0 +1,116 @@
+package promotion
+
+import (
+	"context"
+	"time"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+)
+
+// Service manages promotions and applies them to orders.
+type Service interface {
+	// Create adds new promotion.
+	Create(ctx context.Context, p Promotion) (Promotion, error)
+
+	// Update replaces an existing promotion.
+	Update(ctx context.Context, p Promotion) (Promotion, error)
+
+	// Get returns single promotion.
+	Get(ctx context.Context, id string) (Promotion, error)
+
+	// List returns all the promotions including inactive ones.
+	List(ctx context.Context) ([]Promotion, error)
+
+	// Apply evaluates live promotions and coupon against items priced in currency.
+	Apply(ctx context.Context, items []Item, coupon string, currency money.Currency) ([]Discount, error)
+
+	// Redeem records usage of the promotions discounts came from.
+	Redeem(ctx context.Context, discounts []Discount) error
+}
+
+type basicService struct {
+	r   Repo
+	now func() time.Time
+}
+
+// NewService return basic Service implementation.
+func NewService(r Repo) Service {
+	return basicService{r: r, now: time.Now}
+}
+
+// Create validates and stores new promotion.
+func (s basicService) Create(ctx context.Context, p Promotion) (Promotion, error) {
+	if err := p.Validate(); err != nil {
+		return Promotion{}, err
+	}
+	if err := s.r.Create(ctx, &p); err != nil {
+		return Promotion{}, err
+	}
+	return p, nil
+}
+
+// Update validates and saves an existing promotion.
+// Usage count is owned by Redeem and never changed by Update.
+func (s basicService) Update(ctx context.Context, p Promotion) (Promotion, error) {
+	old, err := s.r.GetByID(ctx, p.ID)
+	if err == db.ErrNotFound {
+		return Promotion{}, ErrPromotionNotFound
+	}
+	if err != nil {
+		return Promotion{}, errors.Wrap(err, "get promotion")
+	}
+	if err := p.Validate(); err != nil {
+		return Promotion{}, err
+	}
+	p.Used = old.Used
+	if err := s.r.Save(ctx, &p); err != nil {
+		return Promotion{}, err
+	}
+	return p, nil
+}
+
+// Get returns promotion for the matched ID.
+func (s basicService) Get(ctx context.Context, id string) (Promotion, error) {
+	p, err := s.r.GetByID(ctx, id)
+	if err == db.ErrNotFound {
+		return Promotion{}, ErrPromotionNotFound
+	}
+	if err != nil {
+		return Promotion{}, errors.Wrap(err, "get promotion")
+	}
+	return p, nil
+}
+
+// List returns all the promotions.
+func (s basicService) List(ctx context.Context) ([]Promotion, error) {
+	return s.r.List(ctx)
+}
+
+// Apply returns discounts given by live promotions. See Evaluate for the rules.
+func (s basicService) Apply(ctx context.Context, items []Item, coupon string, currency money.Currency) ([]Discount, error) {
+	promos, err := s.r.ListApplicable(ctx, NormalizeCoupon(coupon))
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(promos, items, coupon, currency, s.now())
+}
+
+// Redeem increments usage of every promotion in discounts.
+// Fails with ErrCouponExhausted if any of them hit the usage limit meanwhile.
+func (s basicService) Redeem(ctx context.Context, discounts []Discount) error {
+	for _, d := range discounts {
+		if err := s.r.Redeem(ctx, d.PromotionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Middleware is a service middleware that takes service return service
+type Middleware func(Service) Service
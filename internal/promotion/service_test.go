@@ -0,0 +1,46 @@
+package promotion_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/pkg/errors"
+)
+
+var errDown = errors.New("database is down")
+
+// downRepo fails every read, as a repo losing its database would.
+type downRepo struct {
+	promotion.Repo
+}
+
+func (downRepo) GetByID(context.Context, string) (promotion.Promotion, error) {
+	return promotion.Promotion{}, errDown
+}
+
+func TestGetErrors(t *testing.T) {
+	ctx := context.Background()
+	p := promotion.Promotion{ID: "spring", Name: "Spring", Kind: promotion.Percentage, Percent: 10, Active: true}
+
+	t.Run("not found", func(t *testing.T) {
+		s := promotion.NewService(inmem.NewPromotionRepo())
+		if _, err := s.Get(ctx, p.ID); err != promotion.ErrPromotionNotFound {
+			t.Errorf("expected %v, got %v", promotion.ErrPromotionNotFound, err)
+		}
+		if _, err := s.Update(ctx, p); err != promotion.ErrPromotionNotFound {
+			t.Errorf("expected %v, got %v", promotion.ErrPromotionNotFound, err)
+		}
+	})
+
+	t.Run("repo failing", func(t *testing.T) {
+		s := promotion.NewService(downRepo{inmem.NewPromotionRepo()})
+		if _, err := s.Get(ctx, p.ID); errors.Cause(err) != errDown {
+			t.Errorf("expected %v, got %v", errDown, err)
+		}
+		if _, err := s.Update(ctx, p); errors.Cause(err) != errDown {
+			t.Errorf("expected %v, got %v", errDown, err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package promotion
+
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingService struct {
+	tracer trace.Tracer
+	next   Service
+}
+
+// TracingMiddleware traces every call of the service in a span, child of the
+// span of its ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next Service) Service {
+		return tracingService{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+func (s tracingService) Create(ctx context.Context, p Promotion) (promo Promotion, err error) {
+	ctx, span := s.tracer.Start(ctx, "promotion.Create")
+	defer func() { tracing.End(span, err) }()
+	return s.next.Create(ctx, p)
+}
+
+func (s tracingService) Update(ctx context.Context, p Promotion) (promo Promotion, err error) {
+	ctx, span := s.tracer.Start(ctx, "promotion.Update", trace.WithAttributes(attribute.String("promotion.id", p.ID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Update(ctx, p)
+}
+
+func (s tracingService) Get(ctx context.Context, id string) (promo Promotion, err error) {
+	ctx, span := s.tracer.Start(ctx, "promotion.Get", trace.WithAttributes(attribute.String("promotion.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Get(ctx, id)
+}
+
+func (s tracingService) List(ctx context.Context) (promos []Promotion, err error) {
+	ctx, span := s.tracer.Start(ctx, "promotion.List")
+	defer func() { tracing.End(span, err) }()
+	return s.next.List(ctx)
+}
+
+func (s tracingService) Apply(ctx context.Context, items []Item, coupon string, currency money.Currency) (discounts []Discount, err error) {
+	ctx, span := s.tracer.Start(ctx, "promotion.Apply")
+	defer func() { tracing.End(span, err) }()
+	return s.next.Apply(ctx, items, coupon, currency)
+}
+
+func (s tracingService) Redeem(ctx context.Context, discounts []Discount) (err error) {
+	ctx, span := s.tracer.Start(ctx, "promotion.Redeem")
+	defer func() { tracing.End(span, err) }()
+	return s.next.Redeem(ctx, discounts)
+}
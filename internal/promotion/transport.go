@@ -0,0 +1,99 @@
+package promotion
+
+import (
+	"net/http"
+
+	"context"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/transport"
+)
+
+var (
+	ErrBadRouting = transport.ErrBadRouting
+)
+
+// errs are the errors of the promotions sent over HTTP.
+var errs = transport.NewRegistry().
+	Register(ErrPromotionNotFound, http.StatusNotFound, "promotion_not_found").
+	Register(ErrInvalidPromotion, http.StatusBadRequest, "invalid_promotion")
+
+var encodeResponse = transport.ResponseEncoder(errs)
+
+// MakeRoutes returns the routes of the promotions, mounted under /promotions/v1.
+func MakeRoutes(ctx context.Context, s Service, logger log.Logger) transport.Routes {
+	e := MakeEndpoints(s)
+	options := transport.ServerOptions(errs)
+	createHandler := httptransport.NewServer(
+		e.CreateEndpoint,
+		decodeCreateRequest,
+		encodeResponse,
+		options...,
+	)
+	updateHandler := httptransport.NewServer(
+		e.UpdateEndpoint,
+		decodeUpdateRequest,
+		encodeResponse,
+		options...,
+	)
+	getHandler := httptransport.NewServer(
+		e.GetEndpoint,
+		decodeGetRequest,
+		encodeResponse,
+		options...,
+	)
+	listHandler := httptransport.NewServer(
+		e.ListEndpoint,
+		decodeListRequest,
+		encodeResponse,
+		options...,
+	)
+
+	return transport.NewRoutes("/promotions/v1", func(r *mux.Router) {
+		r.Handle("/list", listHandler).Methods("GET")
+		r.Handle("/create", createHandler).Methods("POST")
+		r.Handle("/{id}", getHandler).Methods("GET")
+		r.Handle("/{id}", updateHandler).Methods("PUT")
+	})
+}
+
+// MakeHTTPHandler returns handler of the routes of MakeRoutes, e.g: for
+// tests.
+func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+	r := mux.NewRouter()
+	transport.Mount(r, MakeRoutes(ctx, s, logger))
+	return r
+}
+
+func decodeCreateRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	var r createRequest
+	err := transport.DecodeJSON(req, &r)
+	return r, err
+}
+
+func decodeUpdateRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	var r updateRequest
+	if err := transport.DecodeJSON(req, &r); err != nil {
+		return nil, err
+	}
+	r.ID = id
+	return r, nil
+}
+
+func decodeGetRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	return getRequest{ID: id}, nil
+}
+
+func decodeListRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return listRequest{}, nil
+}
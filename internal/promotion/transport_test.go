@@ -0,0 +1,54 @@
+package promotion_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/transport"
+)
+
+func TestReadPermissions(t *testing.T) {
+	ctx := context.Background()
+	s := promotion.NewService(inmem.NewPromotionRepo())
+	p, err := s.Create(ctx, promotion.Promotion{Name: "Spring", Kind: promotion.Percentage, Percent: 10, Coupon: "SPRING10", Active: true})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	principals := map[string]auth.Principal{
+		"customer": {UserID: "joey"},
+		"admin":    {UserID: "monica", Roles: auth.Roles{auth.Admin}},
+	}
+	authn := auth.AuthenticatorFunc(func(ctx context.Context, token string) (auth.Principal, error) {
+		if p, ok := principals[token]; ok {
+			return p, nil
+		}
+		return auth.Principal{}, auth.ErrUnauthorized
+	})
+	h := transport.Authenticate(promotion.MakeHTTPHandler(ctx, s, log.NewNopLogger()), authn)
+
+	for _, url := range []string{"/promotions/v1/list", "/promotions/v1/" + p.ID} {
+		t.Run(url, func(t *testing.T) {
+			for token, status := range map[string]int{"": http.StatusUnauthorized, "customer": http.StatusForbidden, "admin": http.StatusOK} {
+				req := httptest.NewRequest("GET", url, nil)
+				if token != "" {
+					req.Header.Set("Authorization", "Token "+token)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				if w.Code != status {
+					t.Errorf("expected %v with token %q, got %v", status, token, w.Code)
+				}
+				if status != http.StatusOK && strings.Contains(w.Body.String(), p.Coupon) {
+					t.Errorf("expected no coupon with token %q, got %s", token, w.Body)
+				}
+			}
+		})
+	}
+}
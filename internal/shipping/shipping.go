@@ -0,0 +1,51 @@
+// shipping calculates the cost of shipping parcels of print books.
+package shipping
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoRate        = errors.New("no shipping rate")
+	ErrUnknownMethod = errors.New("unknown shipping method")
+)
+
+// Destination is where a parcel is shipped to.
+type Destination struct {
+	Country string // ISO 3166-1 alpha-2 code e.g: GB
+	Region  string // state or province e.g: CA for California, optional.
+}
+
+// Normalize returns destination with upper case codes.
+func (d Destination) Normalize() Destination {
+	return Destination{
+		Country: strings.ToUpper(strings.TrimSpace(d.Country)),
+		Region:  strings.ToUpper(strings.TrimSpace(d.Region)),
+	}
+}
+
+// Parcel is what gets shipped to a destination.
+type Parcel struct {
+	Destination Destination
+	WeightGrams int
+}
+
+// Quote is the cost of shipping a parcel with a method.
+type Quote struct {
+	Method string      `json:"method"` // e.g: standard
+	Name   string      `json:"name"`   // e.g: Standard (3-5 days)
+	Cost   money.Money `json:"cost"`
+}
+
+// Calculator calculates shipping cost of parcels.
+type Calculator interface {
+	// Quotes returns every method that can ship the parcel, cheapest first.
+	Quotes(ctx context.Context, p Parcel) ([]Quote, error)
+
+	// Quote returns cost of shipping the parcel with method.
+	Quote(ctx context.Context, method string, p Parcel) (Quote, error)
+}
@@ -0,0 +1,211 @@
+package shipping
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidRateTable = errors.New("invalid shipping rate table")
+)
+
+// anyCountry in Zone.Countries matches every country not listed by some
+// other zone of the method.
+const anyCountry = "*"
+
+// Bracket is the price of parcels weighing up to MaxGrams.
+type Bracket struct {
+	MaxGrams int         `json:"max_grams"`
+	Price    money.Money `json:"price"`
+}
+
+// Zone is a set of destinations sharing the same weight brackets.
+// Empty Regions matches any region of the countries.
+type Zone struct {
+	Countries []string  `json:"countries"`
+	Regions   []string  `json:"regions,omitempty"`
+	Brackets  []Bracket `json:"brackets"`
+}
+
+// Method is a way of shipping e.g: standard or express, with its rates
+// by destination zone.
+type Method struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Zones []Zone `json:"zones"`
+}
+
+// Table is a Calculator backed by rate tables of methods. For a destination
+// the most specific zone wins: region match over country wide, country
+// wide over anyCountry. Parcels heavier than the largest bracket of the
+// zone can't be shipped with that method.
+type Table struct {
+	methods []Method
+}
+
+// NewTable validates methods and returns Table.
+func NewTable(methods []Method) (*Table, error) {
+	t := &Table{methods: make([]Method, 0, len(methods))}
+	seen := make(map[string]bool)
+	for i, m := range methods {
+		if m.Code == "" || seen[m.Code] {
+			return nil, errors.Wrapf(ErrInvalidRateTable, "method %d: code", i)
+		}
+		seen[m.Code] = true
+		if m.Name == "" {
+			m.Name = m.Code
+		}
+		zones := make([]Zone, len(m.Zones))
+		for j, z := range m.Zones {
+			var err error
+			if zones[j], err = normalizeZone(z); err != nil {
+				return nil, errors.Wrapf(err, "method %s zone %d", m.Code, j)
+			}
+		}
+		m.Zones = zones
+		t.methods = append(t.methods, m)
+	}
+	return t, nil
+}
+
+// LoadTableFile reads methods from json file at path.
+// e.g: {"methods": [{"code": "standard", "name": "Standard", "zones": [
+// {"countries": ["GB"], "brackets": [{"max_grams": 500, "price": {"amount": "2.99", "currency": "GBP"}}]}]}]}
+func LoadTableFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c struct {
+		Methods []Method `json:"methods"`
+	}
+	if err := json.NewDecoder(f).Decode(&c); err != nil {
+		return nil, errors.Wrap(ErrInvalidRateTable, err.Error())
+	}
+	return NewTable(c.Methods)
+}
+
+// Quotes returns every method that can ship the parcel, cheapest first.
+// Methods are compared only when priced in the same currency, otherwise
+// they are kept in the table order.
+func (t *Table) Quotes(_ context.Context, p Parcel) ([]Quote, error) {
+	quotes := make([]Quote, 0, len(t.methods))
+	for i := range t.methods {
+		q, err := t.quote(&t.methods[i], p)
+		if err != nil {
+			continue
+		}
+		quotes = append(quotes, q)
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		c, err := quotes[i].Cost.Cmp(quotes[j].Cost)
+		return err == nil && c < 0
+	})
+	return quotes, nil
+}
+
+// Quote returns cost of shipping the parcel with method.
+func (t *Table) Quote(_ context.Context, method string, p Parcel) (Quote, error) {
+	for i := range t.methods {
+		if t.methods[i].Code == method {
+			return t.quote(&t.methods[i], p)
+		}
+	}
+	return Quote{}, errors.Wrap(ErrUnknownMethod, method)
+}
+
+func (t *Table) quote(m *Method, p Parcel) (Quote, error) {
+	dest := p.Destination.Normalize()
+	z := lookup(m.Zones, dest)
+	if z == nil {
+		return Quote{}, errors.Wrapf(ErrNoRate, "%s to %s", m.Code, dest.Country)
+	}
+	for _, b := range z.Brackets {
+		if p.WeightGrams <= b.MaxGrams {
+			return Quote{Method: m.Code, Name: m.Name, Cost: b.Price}, nil
+		}
+	}
+	return Quote{}, errors.Wrapf(ErrNoRate, "%s: %dg is too heavy", m.Code, p.WeightGrams)
+}
+
+// lookup returns most specific zone for destination, nil if none.
+func lookup(zones []Zone, dest Destination) *Zone {
+	var best *Zone
+	bestScore := -1
+	for i := range zones {
+		z := &zones[i]
+		score := -1
+		for _, c := range z.Countries {
+			if c == dest.Country {
+				score = 1
+				break
+			}
+			if c == anyCountry {
+				score = 0
+			}
+		}
+		if score < 0 {
+			continue
+		}
+		if len(z.Regions) > 0 {
+			if !contains(z.Regions, dest.Region) {
+				continue
+			}
+			score += 2
+		}
+		if score > bestScore {
+			best, bestScore = z, score
+		}
+	}
+	return best
+}
+
+// normalizeZone upper cases the codes, sorts brackets by weight and checks
+// that every bracket is priced in a single currency.
+func normalizeZone(z Zone) (Zone, error) {
+	if len(z.Countries) == 0 {
+		return z, errors.Wrap(ErrInvalidRateTable, "countries")
+	}
+	if len(z.Brackets) == 0 {
+		return z, errors.Wrap(ErrInvalidRateTable, "brackets")
+	}
+	n := Zone{
+		Countries: make([]string, len(z.Countries)),
+		Regions:   make([]string, len(z.Regions)),
+		Brackets:  append([]Bracket(nil), z.Brackets...),
+	}
+	for i, c := range z.Countries {
+		n.Countries[i] = Destination{Country: c}.Normalize().Country
+	}
+	for i, r := range z.Regions {
+		n.Regions[i] = Destination{Region: r}.Normalize().Region
+	}
+	sort.Slice(n.Brackets, func(i, j int) bool {
+		return n.Brackets[i].MaxGrams < n.Brackets[j].MaxGrams
+	})
+	for _, b := range n.Brackets {
+		if b.MaxGrams <= 0 || b.Price.IsNegative() {
+			return z, errors.Wrap(ErrInvalidRateTable, "bracket")
+		}
+		if b.Price.Currency != n.Brackets[0].Price.Currency {
+			return z, errors.Wrap(money.ErrCurrencyMismatch, "bracket")
+		}
+	}
+	return n, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
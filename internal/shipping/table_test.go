@@ -0,0 +1,85 @@
+package shipping_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/pkg/errors"
+)
+
+func table(t *testing.T) *shipping.Table {
+	table, err := shipping.NewTable([]shipping.Method{
+		{
+			Code: "standard", Name: "Standard",
+			Zones: []shipping.Zone{
+				{Countries: []string{"us"}, Brackets: []shipping.Bracket{
+					{MaxGrams: 2000, Price: money.New(899, money.USD)},
+					{MaxGrams: 500, Price: money.New(499, money.USD)},
+				}},
+				{Countries: []string{"US"}, Regions: []string{"ak", "HI"}, Brackets: []shipping.Bracket{
+					{MaxGrams: 2000, Price: money.New(1999, money.USD)},
+				}},
+				{Countries: []string{"*"}, Brackets: []shipping.Bracket{
+					{MaxGrams: 2000, Price: money.New(2499, money.USD)},
+				}},
+			},
+		},
+		{
+			Code: "express", Name: "Express",
+			Zones: []shipping.Zone{
+				{Countries: []string{"US"}, Brackets: []shipping.Bracket{
+					{MaxGrams: 1000, Price: money.New(1499, money.USD)},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	return table
+}
+
+func TestQuote(t *testing.T) {
+	tb := table(t)
+	cases := []struct {
+		name   string
+		method string
+		parcel shipping.Parcel
+		want   string
+		err    error
+	}{
+		{"lightest bracket", "standard", shipping.Parcel{Destination: shipping.Destination{Country: "US"}, WeightGrams: 300}, "4.99", nil},
+		{"next bracket", "standard", shipping.Parcel{Destination: shipping.Destination{Country: "US"}, WeightGrams: 501}, "8.99", nil},
+		{"region wins", "standard", shipping.Parcel{Destination: shipping.Destination{Country: "us", Region: "hi"}, WeightGrams: 300}, "19.99", nil},
+		{"rest of world", "standard", shipping.Parcel{Destination: shipping.Destination{Country: "IN"}, WeightGrams: 300}, "24.99", nil},
+		{"too heavy", "standard", shipping.Parcel{Destination: shipping.Destination{Country: "US"}, WeightGrams: 2001}, "", shipping.ErrNoRate},
+		{"not served", "express", shipping.Parcel{Destination: shipping.Destination{Country: "IN"}, WeightGrams: 300}, "", shipping.ErrNoRate},
+		{"unknown method", "pigeon", shipping.Parcel{Destination: shipping.Destination{Country: "US"}}, "", shipping.ErrUnknownMethod},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q, err := tb.Quote(context.Background(), c.method, c.parcel)
+			if errors.Cause(err) != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if err == nil && q.Cost.Decimal() != c.want {
+				t.Errorf("expected %v, got %v", c.want, q.Cost.Decimal())
+			}
+		})
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	quotes, err := table(t).Quotes(context.Background(), shipping.Parcel{
+		Destination: shipping.Destination{Country: "US"},
+		WeightGrams: 800,
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(quotes) != 2 || quotes[0].Method != "standard" || quotes[1].Method != "express" {
+		t.Errorf("expected [standard express], got %v", quotes)
+	}
+}
@@ -0,0 +1,134 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidRule = errors.New("invalid tax rule")
+)
+
+// Rule is a tax rate for a country, optionally narrowed down to a region
+// and a product class. Empty Region or Class matches any.
+type Rule struct {
+	Name      string       `json:"name"`
+	Country   string       `json:"country"`
+	Region    string       `json:"region,omitempty"`
+	Class     ProductClass `json:"class,omitempty"`
+	Rate      string       `json:"rate"` // percent e.g: "20" or "7.25"
+	Inclusive bool         `json:"inclusive"`
+}
+
+type rule struct {
+	Rule
+	rate *big.Rat
+}
+
+// Table is a Calculator backed by a table of rules. For every line the most
+// specific rule wins: region match over country wide, class match over any
+// class. Lines with no matching rule are not taxed.
+type Table struct {
+	rules []rule
+}
+
+// NewTable validates rules and returns Table.
+func NewTable(rules []Rule) (*Table, error) {
+	t := &Table{rules: make([]rule, 0, len(rules))}
+	for i, r := range rules {
+		loc := Location{Country: r.Country, Region: r.Region}.Normalize()
+		r.Country, r.Region = loc.Country, loc.Region
+		if r.Country == "" {
+			return nil, errors.Wrapf(ErrInvalidRule, "rule %d: country", i)
+		}
+		rate, ok := new(big.Rat).SetString(r.Rate)
+		if !ok || rate.Sign() < 0 {
+			return nil, errors.Wrapf(ErrInvalidRule, "rule %d: rate", i)
+		}
+		t.rules = append(t.rules, rule{Rule: r, rate: rate})
+	}
+	return t, nil
+}
+
+// LoadTableFile reads rules from json file at path.
+// e.g: {"rules": [{"name": "VAT", "country": "GB", "class": "print", "rate": "0", "inclusive": true}]}
+func LoadTableFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.NewDecoder(f).Decode(&c); err != nil {
+		return nil, errors.Wrap(ErrInvalidRule, err.Error())
+	}
+	return NewTable(c.Rules)
+}
+
+// Calculate returns tax of every line for the billing location.
+func (t *Table) Calculate(_ context.Context, loc Location, lines []Line) ([]LineTax, error) {
+	loc = loc.Normalize()
+	taxes := make([]LineTax, len(lines))
+	for i, l := range lines {
+		r := t.lookup(loc, l.Class)
+		if r == nil {
+			taxes[i] = LineTax{Rate: "0", Amount: money.Zero(l.Amount.Currency)}
+			continue
+		}
+		taxes[i] = LineTax{
+			Name:      r.Name,
+			Rate:      r.Rate,
+			Inclusive: r.Inclusive,
+			Amount:    amount(l.Amount, r.rate, r.Inclusive),
+		}
+	}
+	return taxes, nil
+}
+
+// lookup returns most specific rule for location and class, nil if none.
+func (t *Table) lookup(loc Location, class ProductClass) *rule {
+	var best *rule
+	bestScore := -1
+	for i := range t.rules {
+		r := &t.rules[i]
+		if r.Country != loc.Country {
+			continue
+		}
+		score := 0
+		if r.Region != "" {
+			if r.Region != loc.Region {
+				continue
+			}
+			score += 2
+		}
+		if r.Class != "" {
+			if r.Class != class {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best
+}
+
+// amount returns tax on m at percent rate. For inclusive pricing tax is
+// the part of m, m * rate / (100 + rate), otherwise m * rate / 100.
+func amount(m money.Money, rate *big.Rat, inclusive bool) money.Money {
+	hundred := big.NewRat(100, 1)
+	base := hundred
+	if inclusive {
+		base = new(big.Rat).Add(hundred, rate)
+	}
+	return m.MulRat(new(big.Rat).Quo(rate, base), money.RoundHalfUp)
+}
@@ -0,0 +1,48 @@
+package tax_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/tax"
+)
+
+func TestTable(t *testing.T) {
+	table, err := tax.NewTable([]tax.Rule{
+		{Name: "VAT", Country: "GB", Rate: "20", Inclusive: true},
+		{Name: "VAT", Country: "GB", Class: tax.Print, Rate: "0", Inclusive: true},
+		{Name: "Sales tax", Country: "US", Region: "CA", Rate: "7.25"},
+		{Name: "GST", Country: "in", Rate: "18"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	cases := []struct {
+		name string
+		loc  tax.Location
+		line tax.Line
+		want string
+		incl bool
+	}{
+		{"inclusive ebook", tax.Location{Country: "GB"}, tax.Line{Class: tax.Ebook, Amount: money.New(1200, money.GBP)}, "2.00", true},
+		{"class specific wins", tax.Location{Country: "gb"}, tax.Line{Class: tax.Print, Amount: money.New(1200, money.GBP)}, "0.00", true},
+		{"region exclusive", tax.Location{Country: "US", Region: "ca"}, tax.Line{Class: tax.Print, Amount: money.New(1000, money.USD)}, "0.73", false},
+		{"no region rule", tax.Location{Country: "US", Region: "OR"}, tax.Line{Class: tax.Print, Amount: money.New(1000, money.USD)}, "0.00", false},
+		{"country wide", tax.Location{Country: "IN", Region: "KA"}, tax.Line{Class: tax.Ebook, Amount: money.New(10000, money.INR)}, "18.00", false},
+	}
+	for _, c := range cases {
+		taxes, err := table.Calculate(context.Background(), c.loc, []tax.Line{c.line})
+		if err != nil {
+			t.Errorf("%s: expected nil error, got %v", c.name, err)
+			continue
+		}
+		if taxes[0].Amount.Decimal() != c.want {
+			t.Errorf("%s: expected %s, got %s", c.name, c.want, taxes[0].Amount.Decimal())
+		}
+		if taxes[0].Inclusive != c.incl {
+			t.Errorf("%s: expected inclusive %v, got %v", c.name, c.incl, taxes[0].Inclusive)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// tax calculates taxes like VAT/GST on order lines.
+package tax
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kavirajk/bookshop/money"
+)
+
+// ProductClass groups products taxed the same way. e.g: ebooks and print
+// books often have different rates in the same region.
+type ProductClass string
+
+const (
+	Print ProductClass = "print"
+	Ebook ProductClass = "ebook"
+)
+
+// Location is the billing location taxes are calculated for.
+type Location struct {
+	Country string // ISO 3166-1 alpha-2 code e.g: GB
+	Region  string // state or province e.g: CA for California, optional.
+}
+
+// Normalize returns location with upper case codes.
+func (l Location) Normalize() Location {
+	return Location{
+		Country: strings.ToUpper(strings.TrimSpace(l.Country)),
+		Region:  strings.ToUpper(strings.TrimSpace(l.Region)),
+	}
+}
+
+// Line is a taxable amount of a product class.
+// Amount should already have the discounts taken off.
+type Line struct {
+	Class  ProductClass
+	Amount money.Money
+}
+
+// LineTax is tax calculated for a Line.
+type LineTax struct {
+	Name      string      // e.g: VAT
+	Rate      string      // percent e.g: "20"
+	Inclusive bool        // Amount is already part of the line price.
+	Amount    money.Money // tax amount
+}
+
+// Calculator calculates tax for every line billed to a location.
+// Returned slice is in the same order as lines.
+type Calculator interface {
+	Calculate(ctx context.Context, loc Location, lines []Line) ([]LineTax, error)
+}
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"strings"
+
+	"github.com/kavirajk/bookshop/validate"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAddressNotFound = errors.New("address not found")
+)
+
+// PostalAddress is the postal part of an address. Orders keep a copy of it
+// so that editing the address book never changes past orders.
+type PostalAddress struct {
+	Name       string `json:"name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"` // ISO 3166-1 alpha-2 code e.g: GB
+	Phone      string `json:"phone,omitempty"`
+}
+
+// Validate does basic validation of the postal address.
+func (a *PostalAddress) Validate() error {
+	var v validate.Validator
+	v.Required("name", a.Name)
+	v.Required("line1", a.Line1)
+	v.Required("city", a.City)
+	v.Required("postal_code", a.PostalCode)
+	v.Length("postal_code", a.PostalCode, 1, 16)
+	if v.Required("country", a.Country) {
+		v.Check(isCountryCode(a.Country), "country", validate.Format, "must be an ISO 3166-1 alpha-2 code")
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	a.Country = strings.ToUpper(a.Country)
+	return nil
+}
+
+// isCountryCode reports whether s looks like an ISO 3166-1 alpha-2 code,
+// e.g: GB.
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether address is not given at all.
+func (a *PostalAddress) IsZero() bool {
+	return *a == PostalAddress{}
+}
+
+// Address is an entry in user's address book.
+type Address struct {
+	ID     string `json:"id"`
+	UserID string `json:"-"`
+	PostalAddress
+}
@@ -0,0 +1,24 @@
+package user
+
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/pkg/errors"
+)
+
+// Authenticator authenticates the requests by the auth token of their user,
+// see transport.Authenticate.
+func Authenticator(s Service) auth.Authenticator {
+	return auth.AuthenticatorFunc(func(ctx context.Context, token string) (auth.Principal, error) {
+		u, err := s.AuthToken(ctx, token)
+		if errors.Cause(err) == db.ErrNotFound {
+			return auth.Principal{}, ErrUnauthorized
+		}
+		if err != nil {
+			return auth.Principal{}, err
+		}
+		return u.Principal(), nil
+	})
+}
@@ -7,6 +7,9 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/tracing"
+	"github.com/kavirajk/bookshop/validate"
 )
 
 // Endpoints combine all the user service endpoints under single type.
@@ -16,17 +19,37 @@ type Endpoints struct {
 	ResetPasswordEndpoint  endpoint.Endpoint
 	ChangePasswordEndpoint endpoint.Endpoint
 	ListEndpoint           endpoint.Endpoint
+	AddressesEndpoint      endpoint.Endpoint
+	AddAddressEndpoint     endpoint.Endpoint
+	UpdateAddressEndpoint  endpoint.Endpoint
+	RemoveAddressEndpoint  endpoint.Endpoint
+	DeleteEndpoint         endpoint.Endpoint
+	ListDeletedEndpoint    endpoint.Endpoint
+	RestoreEndpoint        endpoint.Endpoint
+	RolesEndpoint          endpoint.Endpoint
+	SetRolesEndpoint       endpoint.Endpoint
 }
 
 // MakeEndpoints returns Endpoints type which is the combination of
-// all the user service endpoints.
+// all the user service endpoints. Listing and managing users needs
+// auth.ManageUsers.
 func MakeEndpoints(s Service) Endpoints {
+	admin := auth.Require(auth.ManageUsers)
 	return Endpoints{
-		RegisterEndpoint:       MakeRegisterEndpoint(s),
-		LoginEndpoint:          MakeLoginEndpoint(s),
-		ResetPasswordEndpoint:  MakeResetPasswordEndpoint(s),
-		ChangePasswordEndpoint: MakeChangePasswordEndpoint(s),
-		ListEndpoint:           MakeListEndpoint(s),
+		RegisterEndpoint:       tracing.Endpoint("user.register")(MakeRegisterEndpoint(s)),
+		LoginEndpoint:          tracing.Endpoint("user.login")(MakeLoginEndpoint(s)),
+		ResetPasswordEndpoint:  tracing.Endpoint("user.reset_password")(MakeResetPasswordEndpoint(s)),
+		ChangePasswordEndpoint: tracing.Endpoint("user.change_password")(MakeChangePasswordEndpoint(s)),
+		ListEndpoint:           tracing.Endpoint("user.list")(admin(MakeListEndpoint(s))),
+		AddressesEndpoint:      tracing.Endpoint("user.addresses")(MakeAddressesEndpoint(s)),
+		AddAddressEndpoint:     tracing.Endpoint("user.add_address")(MakeAddAddressEndpoint(s)),
+		UpdateAddressEndpoint:  tracing.Endpoint("user.update_address")(MakeUpdateAddressEndpoint(s)),
+		RemoveAddressEndpoint:  tracing.Endpoint("user.remove_address")(MakeRemoveAddressEndpoint(s)),
+		DeleteEndpoint:         tracing.Endpoint("user.delete")(admin(MakeDeleteEndpoint(s))),
+		ListDeletedEndpoint:    tracing.Endpoint("user.list_deleted")(admin(MakeListDeletedEndpoint(s))),
+		RestoreEndpoint:        tracing.Endpoint("user.restore")(admin(MakeRestoreEndpoint(s))),
+		RolesEndpoint:          tracing.Endpoint("user.roles")(admin(MakeRolesEndpoint())),
+		SetRolesEndpoint:       tracing.Endpoint("user.set_roles")(admin(MakeSetRolesEndpoint(s))),
 	}
 }
 
@@ -55,9 +78,6 @@ func MakeLoginEndpoint(s Service) endpoint.Endpoint {
 func MakeResetPasswordEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(resetPasswordRequest)
-		if req.NewPassword != req.ConfirmNewPassword {
-			return nil, ErrPasswordMismatch
-		}
 		e := s.ResetPassword(ctx, req.Key, req.NewPassword)
 		if e != nil {
 			return resetPasswordResponse{Error: e}, nil
@@ -76,9 +96,6 @@ func MakeChangePasswordEndpoint(s Service) endpoint.Endpoint {
 		if e != nil {
 			return nil, e
 		}
-		if req.NewPassword != req.ConfirmNewPassword {
-			return nil, ErrPasswordMismatch
-		}
 		e = s.ChangePassword(ctx, u.ID, req.OldPassword, req.NewPassword)
 		if e != nil {
 			return changePasswordResponse{Error: e}, nil
@@ -117,60 +134,171 @@ func MakeListEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+func MakeAddressesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(addressesRequest)
+		u, e := authUser(ctx, s, req.Token)
+		if e != nil {
+			return nil, e
+		}
+		addresses, e := s.Addresses(ctx, u.ID)
+		if e != nil {
+			return addressesResponse{Error: e}, nil
+		}
+		return addressesResponse{Addresses: addresses}, nil
+	}
+}
+
+func MakeAddAddressEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(addressRequest)
+		u, e := authUser(ctx, s, req.Token)
+		if e != nil {
+			return nil, e
+		}
+		a, e := s.AddAddress(ctx, u.ID, req.Address)
+		if e != nil {
+			return addressResponse{Error: e}, nil
+		}
+		return addressResponse{Address: &a, Status: http.StatusCreated}, nil
+	}
+}
+
+func MakeUpdateAddressEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(addressRequest)
+		u, e := authUser(ctx, s, req.Token)
+		if e != nil {
+			return nil, e
+		}
+		a, e := s.UpdateAddress(ctx, u.ID, req.Address)
+		if e != nil {
+			return addressResponse{Error: e}, nil
+		}
+		return addressResponse{Address: &a}, nil
+	}
+}
+
+func MakeRemoveAddressEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(removeAddressRequest)
+		u, e := authUser(ctx, s, req.Token)
+		if e != nil {
+			return nil, e
+		}
+		if e := s.RemoveAddress(ctx, u.ID, req.ID); e != nil {
+			return addressResponse{Error: e}, nil
+		}
+		return addressResponse{}, nil
+	}
+}
+
+// authUser returns the user owning the auth token.
+func authUser(ctx context.Context, s Service, token string) (User, error) {
+	if token == "" {
+		return User{}, ErrUnauthorized
+	}
+	return s.AuthToken(ctx, token)
+}
+
 type registerRequest struct {
 	NewUser
 }
 
+func (r registerRequest) Validate() error {
+	return r.NewUser.Validate()
+}
+
 type registerResponse struct {
 	Status int   `json:"-"`
 	User   *User `json:"user,omitempty"`
 	Error  error `json:"error,omitempty"`
 }
 
-func (r registerResponse) status() int {
+func (r registerResponse) StatusCode() int {
 	return r.Status
 }
 
-func (r registerResponse) error() error {
+func (r registerResponse) Failed() error {
 	return r.Error
 }
 
+func (r registerResponse) Version() int64 {
+	if r.User == nil {
+		return 0
+	}
+	return r.User.Version
+}
+
 type loginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+func (r loginRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("email", r.Email) {
+		v.Email("email", r.Email)
+	}
+	v.Required("password", r.Password)
+	return v.Err()
+}
+
 type loginResponse struct {
 	Status int   `json:"-"`
 	User   *User `json:"user,omitempty"`
 	Error  error `json:"error,omitempty"`
 }
 
-func (l loginResponse) status() int {
+func (l loginResponse) StatusCode() int {
 	return l.Status
 }
 
-func (r loginResponse) error() error {
+func (r loginResponse) Failed() error {
 	return r.Error
 }
 
+func (r loginResponse) Version() int64 {
+	if r.User == nil {
+		return 0
+	}
+	return r.User.Version
+}
+
 type resetPasswordRequest struct {
 	Key                string `json:"key"`
 	NewPassword        string `json:"new_password"`
 	ConfirmNewPassword string `json:"confirm_new_password"`
 }
 
+func (r resetPasswordRequest) Validate() error {
+	var v validate.Validator
+	v.Required("key", r.Key)
+	validateNewPassword(&v, r.NewPassword, r.ConfirmNewPassword)
+	return v.Err()
+}
+
+// validateNewPassword checks the new password and its confirmation.
+func validateNewPassword(v *validate.Validator, password, confirm string) {
+	if v.Required("new_password", password) {
+		v.Length("new_password", password, MinPasswordLength, MaxPasswordLength)
+	}
+	if v.Required("confirm_new_password", confirm) {
+		v.Equal("confirm_new_password", confirm, password, "new_password")
+	}
+}
+
 type resetPasswordResponse struct {
 	Status  int    `json:"-"`
 	Message string `json:"message,omitempty"`
 	Error   error  `json:"error,omitempty"`
 }
 
-func (r resetPasswordResponse) status() int {
+func (r resetPasswordResponse) StatusCode() int {
 	return r.Status
 }
 
-func (r resetPasswordResponse) error() error {
+func (r resetPasswordResponse) Failed() error {
 	return r.Error
 }
 
@@ -186,17 +314,24 @@ type changePasswordRequest struct {
 	ConfirmNewPassword string `json:"confirm_new_password"`
 }
 
+func (r changePasswordRequest) Validate() error {
+	var v validate.Validator
+	v.Required("old_password", r.OldPassword)
+	validateNewPassword(&v, r.NewPassword, r.ConfirmNewPassword)
+	return v.Err()
+}
+
 type changePasswordResponse struct {
 	Status  int    `json:"-"`
 	Message string `json:"message,omitempty"`
 	Error   error  `json:"error,omitempty"`
 }
 
-func (c changePasswordResponse) status() int {
+func (c changePasswordResponse) StatusCode() int {
 	return c.Status
 }
 
-func (r changePasswordResponse) error() error {
+func (r changePasswordResponse) Failed() error {
 	return r.Error
 }
 
@@ -208,6 +343,14 @@ type listRequest struct {
 	URL *url.URL `json:"-"`
 }
 
+func (r listRequest) Validate() error {
+	var v validate.Validator
+	v.Check(r.Order == "" || listOrder.MatchString(r.Order), "order", validate.Format, "must be a field name, optionally followed by asc or desc")
+	v.Range("limit", r.Limit, 1, maxPageLimit)
+	v.Check(r.Offset >= 0, "offset", validate.Range, "must not be negative")
+	return v.Err()
+}
+
 type listResponse struct {
 	Status int    `json:"-"`
 	Users  []User `json:"users"`
@@ -218,14 +361,179 @@ type listResponse struct {
 	Next  string `json:"-"`
 }
 
-func (r listResponse) status() int {
+func (r listResponse) StatusCode() int {
 	return r.Status
 }
 
-func (r listResponse) error() error {
+func (r listResponse) Failed() error {
 	return r.Error
 }
 
-func (r listResponse) page() (int, string, string) {
+func (r listResponse) Page() (int, string, string) {
 	return r.Total, r.Prev, r.Next
 }
+
+type addressesRequest struct {
+	Token string `json:"-"`
+}
+
+type addressesResponse struct {
+	Status    int       `json:"-"`
+	Addresses []Address `json:"addresses"`
+	Error     error     `json:"error,omitempty"`
+}
+
+func (r addressesResponse) StatusCode() int {
+	return r.Status
+}
+
+func (r addressesResponse) Failed() error {
+	return r.Error
+}
+
+type addressRequest struct {
+	Token string `json:"-"`
+	Address
+}
+
+func (r addressRequest) Validate() error {
+	return r.PostalAddress.Validate()
+}
+
+type removeAddressRequest struct {
+	Token string `json:"-"`
+	ID    string `json:"-"`
+}
+
+type addressResponse struct {
+	Status  int      `json:"-"`
+	Address *Address `json:"address,omitempty"`
+	Error   error    `json:"error,omitempty"`
+}
+
+func (r addressResponse) StatusCode() int {
+	return r.Status
+}
+
+func (r addressResponse) Failed() error {
+	return r.Error
+}
+
+func MakeDeleteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteRequest)
+		e := s.Delete(ctx, req.ID)
+		if e != nil {
+			return deleteResponse{Error: e}, nil
+		}
+		return deleteResponse{}, nil
+	}
+}
+
+func MakeListDeletedEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		users, e := s.ListDeleted(ctx)
+		if e != nil {
+			return listDeletedResponse{Users: make([]User, 0), Error: e}, nil
+		}
+		return listDeletedResponse{Users: users}, nil
+	}
+}
+
+func MakeRestoreEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteRequest)
+		user, e := s.Restore(ctx, req.ID)
+		if e != nil {
+			return restoreResponse{Error: e}, nil
+		}
+		return restoreResponse{User: &user}, nil
+	}
+}
+
+// deleteRequest names the user to delete, or to restore.
+type deleteRequest struct {
+	ID string `json:"id"`
+}
+
+type deleteResponse struct {
+	Error error `json:"error,omitempty"`
+}
+
+func (r deleteResponse) Failed() error {
+	return r.Error
+}
+
+type listDeletedResponse struct {
+	Users []User `json:"users"`
+	Error error  `json:"error,omitempty"`
+}
+
+func (r listDeletedResponse) Failed() error {
+	return r.Error
+}
+
+type restoreResponse struct {
+	User  *User `json:"user,omitempty"`
+	Error error `json:"error,omitempty"`
+}
+
+func (r restoreResponse) Failed() error {
+	return r.Error
+}
+
+func (r restoreResponse) Version() int64 {
+	if r.User == nil {
+		return 0
+	}
+	return r.User.Version
+}
+
+// MakeRolesEndpoint returns endpoint listing the roles with the permissions
+// they grant.
+func MakeRolesEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return rolesResponse{Roles: auth.Permissions}, nil
+	}
+}
+
+func MakeSetRolesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setRolesRequest)
+		user, e := s.SetRoles(ctx, req.ID, req.Roles, req.PublisherID)
+		if e != nil {
+			return setRolesResponse{Error: e}, nil
+		}
+		return setRolesResponse{User: &user}, nil
+	}
+}
+
+type rolesResponse struct {
+	Roles map[auth.Role][]auth.Permission `json:"roles"`
+}
+
+type setRolesRequest struct {
+	ID          string     `json:"-"`
+	Roles       auth.Roles `json:"roles"`
+	PublisherID string     `json:"publisher_id"`
+}
+
+func (r setRolesRequest) Validate() error {
+	return validateRoles(r.Roles, r.PublisherID)
+}
+
+type setRolesResponse struct {
+	User  *User `json:"user,omitempty"`
+	Error error `json:"error,omitempty"`
+}
+
+func (r setRolesResponse) Failed() error {
+	return r.Error
+}
+
+func (r setRolesResponse) Version() int64 {
+	if r.User == nil {
+		return 0
+	}
+	return r.User.Version
+}
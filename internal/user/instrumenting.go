@@ -7,6 +7,8 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/pkg/errors"
 )
 
 type instrmw struct {
@@ -90,3 +92,268 @@ func (mw instrmw) List(ctx context.Context, order string, limit, offset int) (us
 	users, total, err = mw.next.List(ctx, order, limit, offset)
 	return
 }
+
+func (mw instrmw) Addresses(ctx context.Context, userID string) (addresses []Address, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "addresses", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	addresses, err = mw.next.Addresses(ctx, userID)
+	return
+}
+
+func (mw instrmw) AddAddress(ctx context.Context, userID string, address Address) (a Address, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "add-address", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	a, err = mw.next.AddAddress(ctx, userID, address)
+	return
+}
+
+func (mw instrmw) UpdateAddress(ctx context.Context, userID string, address Address) (a Address, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "update-address", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	a, err = mw.next.UpdateAddress(ctx, userID, address)
+	return
+}
+
+func (mw instrmw) RemoveAddress(ctx context.Context, userID, addressID string) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "remove-address", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	err = mw.next.RemoveAddress(ctx, userID, addressID)
+	return
+}
+
+func (mw instrmw) Delete(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "delete", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	err = mw.next.Delete(ctx, id)
+	return
+}
+
+func (mw instrmw) ListDeleted(ctx context.Context) (users []User, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "list-deleted", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	users, err = mw.next.ListDeleted(ctx)
+	return
+}
+
+func (mw instrmw) Restore(ctx context.Context, id string) (user User, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "restore", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	user, err = mw.next.Restore(ctx, id)
+	return
+}
+
+func (mw instrmw) SetRoles(ctx context.Context, id string, roles auth.Roles, publisherID string) (user User, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "set_roles", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	user, err = mw.next.SetRoles(ctx, id, roles, publisherID)
+	return
+}
+
+type instrumentingRepo struct {
+	duration metrics.Histogram
+	next     Repo
+}
+
+// InstrumentingRepo returns r timing its queries in duration, labelled by
+// method and error, see metrics.Queries.
+func InstrumentingRepo(r Repo, duration metrics.Histogram) Repo {
+	return instrumentingRepo{duration: duration.With("repo", "users"), next: r}
+}
+
+func (r instrumentingRepo) observe(method string, begin time.Time, err error) {
+	r.duration.With("method", method, "error", fmt.Sprint(err != nil)).Observe(time.Since(begin).Seconds())
+}
+
+func (r instrumentingRepo) Create(ctx context.Context, user *User) (err error) {
+	defer func(begin time.Time) { r.observe("create", begin, err) }(time.Now())
+	err = r.next.Create(ctx, user)
+	return
+}
+
+func (r instrumentingRepo) Save(ctx context.Context, user *User) (err error) {
+	defer func(begin time.Time) { r.observe("save", begin, err) }(time.Now())
+	err = r.next.Save(ctx, user)
+	return
+}
+
+func (r instrumentingRepo) GetByID(ctx context.Context, id string) (user User, err error) {
+	defer func(begin time.Time) { r.observe("get_by_id", begin, err) }(time.Now())
+	user, err = r.next.GetByID(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) GetByUserName(ctx context.Context, username string) (user User, err error) {
+	defer func(begin time.Time) { r.observe("get_by_user_name", begin, err) }(time.Now())
+	user, err = r.next.GetByUserName(ctx, username)
+	return
+}
+
+func (r instrumentingRepo) GetByEmail(ctx context.Context, email string) (user User, err error) {
+	defer func(begin time.Time) { r.observe("get_by_email", begin, err) }(time.Now())
+	user, err = r.next.GetByEmail(ctx, email)
+	return
+}
+
+func (r instrumentingRepo) GetByToken(ctx context.Context, token string) (user User, err error) {
+	defer func(begin time.Time) { r.observe("get_by_token", begin, err) }(time.Now())
+	user, err = r.next.GetByToken(ctx, token)
+	return
+}
+
+func (r instrumentingRepo) GetByResetKey(ctx context.Context, key string) (user User, err error) {
+	defer func(begin time.Time) { r.observe("get_by_reset_key", begin, err) }(time.Now())
+	user, err = r.next.GetByResetKey(ctx, key)
+	return
+}
+
+func (r instrumentingRepo) List(ctx context.Context, order string, limit, offset int) (users []User, total int, err error) {
+	defer func(begin time.Time) { r.observe("list", begin, err) }(time.Now())
+	users, total, err = r.next.List(ctx, order, limit, offset)
+	return
+}
+
+func (r instrumentingRepo) Drop(ctx context.Context) (err error) {
+	defer func(begin time.Time) { r.observe("drop", begin, err) }(time.Now())
+	err = r.next.Drop(ctx)
+	return
+}
+
+func (r instrumentingRepo) FailLogin(ctx context.Context, id string) (failed int, err error) {
+	defer func(begin time.Time) { r.observe("fail_login", begin, err) }(time.Now())
+	failed, err = r.next.FailLogin(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) LockOut(ctx context.Context, id string, until time.Time) (err error) {
+	defer func(begin time.Time) { r.observe("lock_out", begin, err) }(time.Now())
+	err = r.next.LockOut(ctx, id, until)
+	return
+}
+
+func (r instrumentingRepo) ResetFailedLogins(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) { r.observe("reset_failed_logins", begin, err) }(time.Now())
+	err = r.next.ResetFailedLogins(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) Delete(ctx context.Context, id string, version int64) (err error) {
+	defer func(begin time.Time) { r.observe("delete", begin, err) }(time.Now())
+	err = r.next.Delete(ctx, id, version)
+	return
+}
+
+func (r instrumentingRepo) ListDeleted(ctx context.Context) (users []User, err error) {
+	defer func(begin time.Time) { r.observe("list_deleted", begin, err) }(time.Now())
+	users, err = r.next.ListDeleted(ctx)
+	return
+}
+
+func (r instrumentingRepo) Restore(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) { r.observe("restore", begin, err) }(time.Now())
+	err = r.next.Restore(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) Purge(ctx context.Context, before time.Time) (n int, err error) {
+	defer func(begin time.Time) { r.observe("purge", begin, err) }(time.Now())
+	n, err = r.next.Purge(ctx, before)
+	return
+}
+
+func (r instrumentingRepo) CreateAddress(ctx context.Context, address *Address) (err error) {
+	defer func(begin time.Time) { r.observe("create_address", begin, err) }(time.Now())
+	err = r.next.CreateAddress(ctx, address)
+	return
+}
+
+func (r instrumentingRepo) SaveAddress(ctx context.Context, address *Address) (err error) {
+	defer func(begin time.Time) { r.observe("save_address", begin, err) }(time.Now())
+	err = r.next.SaveAddress(ctx, address)
+	return
+}
+
+func (r instrumentingRepo) GetAddress(ctx context.Context, id string) (address Address, err error) {
+	defer func(begin time.Time) { r.observe("get_address", begin, err) }(time.Now())
+	address, err = r.next.GetAddress(ctx, id)
+	return
+}
+
+func (r instrumentingRepo) ListAddresses(ctx context.Context, userID string) (addresses []Address, err error) {
+	defer func(begin time.Time) { r.observe("list_addresses", begin, err) }(time.Now())
+	addresses, err = r.next.ListAddresses(ctx, userID)
+	return
+}
+
+func (r instrumentingRepo) DeleteAddress(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) { r.observe("delete_address", begin, err) }(time.Now())
+	err = r.next.DeleteAddress(ctx, id)
+	return
+}
+
+type businessmw struct {
+	Service
+	registrations metrics.Counter
+	failedLogins  metrics.Counter
+}
+
+// BusinessMetricsMiddleware counts the users registered in registrations
+// and the logins with wrong credentials in failedLogins, labelled by
+// reason: unknown_user, wrong_password or locked.
+func BusinessMetricsMiddleware(registrations, failedLogins metrics.Counter) Middleware {
+	return func(next Service) Service {
+		return businessmw{Service: next, registrations: registrations, failedLogins: failedLogins}
+	}
+}
+
+func (mw businessmw) Register(ctx context.Context, new NewUser) (User, error) {
+	user, err := mw.Service.Register(ctx, new)
+	if err == nil {
+		mw.registrations.Add(1)
+	}
+	return user, err
+}
+
+func (mw businessmw) Login(ctx context.Context, email, password string) (User, error) {
+	user, err := mw.Service.Login(ctx, email, password)
+	switch errors.Cause(err) {
+	case ErrUserNotFound:
+		mw.failedLogins.With("reason", "unknown_user").Add(1)
+	case ErrUnauthorized:
+		mw.failedLogins.With("reason", "wrong_password").Add(1)
+	case ErrAccountLocked:
+		mw.failedLogins.With("reason", "locked").Add(1)
+	}
+	return user, err
+}
@@ -0,0 +1,57 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrAccountLocked = errors.New("account locked after too many failed logins")
+
+// Lockout locks accounts out after Threshold failed logins in a row, for
+// Duration, doubled by every failed login after, up to Max. Locked out
+// accounts can't log in even with the right password until the lock ends.
+// Zero Threshold never locks.
+type Lockout struct {
+	Threshold int
+	Duration  time.Duration
+	Max       time.Duration
+}
+
+// DefaultLockout locks an account for a minute after 5 failed logins, up to
+// an hour.
+var DefaultLockout = Lockout{Threshold: 5, Duration: time.Minute, Max: time.Hour}
+
+// lockedFor returns how long u is still locked out at now, 0 if it isn't.
+func (u *User) lockedFor(now time.Time) time.Duration {
+	if u.LockedUntil == nil || !now.Before(*u.LockedUntil) {
+		return 0
+	}
+	return u.LockedUntil.Sub(now)
+}
+
+// Until returns until when an account is locked out after failed logins
+// in a row at now, false if it isn't locked.
+func (l Lockout) Until(failed int, now time.Time) (time.Time, bool) {
+	if l.Threshold <= 0 || failed < l.Threshold {
+		return time.Time{}, false
+	}
+	d := l.Duration
+	for i := l.Threshold; i < failed && d < l.Max; i++ {
+		d *= 2
+	}
+	if l.Max > 0 && d > l.Max {
+		d = l.Max
+	}
+	return now.Add(d), true
+}
+
+// unlock forgets the failed logins of u, it returns whether there were any.
+func (u *User) unlock() bool {
+	if u.FailedLogins == 0 && u.LockedUntil == nil {
+		return false
+	}
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+	return true
+}
@@ -0,0 +1,117 @@
+package user_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/user"
+	"github.com/pkg/errors"
+)
+
+func TestLockoutUntil(t *testing.T) {
+	now := time.Now()
+	l := user.Lockout{Threshold: 3, Duration: time.Minute, Max: 5 * time.Minute}
+	for _, c := range []struct {
+		failed int
+		want   time.Duration // 0 if not locked
+	}{
+		{failed: 2},
+		{failed: 3, want: time.Minute},
+		{failed: 4, want: 2 * time.Minute},
+		{failed: 5, want: 4 * time.Minute},
+		{failed: 6, want: 5 * time.Minute},
+		{failed: 100, want: 5 * time.Minute},
+	} {
+		until, locked := l.Until(c.failed, now)
+		if locked != (c.want > 0) || (locked && until.Sub(now) != c.want) {
+			t.Errorf("expected %v failed logins locked for %v, got %v for %v", c.failed, c.want, locked, until.Sub(now))
+		}
+	}
+	if _, locked := (user.Lockout{}).Until(100, now); locked {
+		t.Errorf("expected zero threshold never locked, got locked")
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	newUser := user.NewUser{FirstName: "Joey", LastName: "Tribbiani", Email: "joey@golang.org", Password: "howyoudoin", ConfirmPassword: "howyoudoin"}
+	setup := func(t *testing.T, threshold int) (user.Service, user.Repo) {
+		repo := inmem.NewUserRepo()
+		s := user.NewService(repo, user.Lockout{Threshold: threshold, Duration: time.Minute, Max: time.Hour})
+		if _, err := s.Register(ctx, newUser); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		return s, repo
+	}
+
+	t.Run("locked after threshold", func(t *testing.T) {
+		s, _ := setup(t, 3)
+		for i := 0; i < 3; i++ {
+			if _, err := s.Login(ctx, newUser.Email, "wrong"); err != user.ErrUnauthorized {
+				t.Fatalf("expected %v, got %v", user.ErrUnauthorized, err)
+			}
+		}
+		if _, err := s.Login(ctx, newUser.Email, newUser.Password); errors.Cause(err) != user.ErrAccountLocked {
+			t.Errorf("expected %v, got %v", user.ErrAccountLocked, err)
+		}
+	})
+
+	t.Run("login resets failed logins", func(t *testing.T) {
+		s, repo := setup(t, 3)
+		s.Login(ctx, newUser.Email, "wrong")
+		if _, err := s.Login(ctx, newUser.Email, newUser.Password); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		u, _ := repo.GetByEmail(ctx, newUser.Email)
+		if u.FailedLogins != 0 || u.LockedUntil != nil {
+			t.Errorf("expected no failed logins, got %v until %v", u.FailedLogins, u.LockedUntil)
+		}
+	})
+
+	t.Run("failed login while logging in", func(t *testing.T) {
+		s, repo := setup(t, 3)
+		s.Login(ctx, newUser.Email, "wrong")
+		s = user.NewService(failingRepo{repo}, user.Lockout{Threshold: 3, Duration: time.Minute, Max: time.Hour})
+		u, err := s.Login(ctx, newUser.Email, newUser.Password)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if u.FailedLogins != 0 || u.LockedUntil != nil {
+			t.Errorf("expected no failed logins, got %v until %v", u.FailedLogins, u.LockedUntil)
+		}
+	})
+
+	t.Run("concurrent failed logins all count", func(t *testing.T) {
+		s, repo := setup(t, 10)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.Login(ctx, newUser.Email, "wrong")
+			}()
+		}
+		wg.Wait()
+		u, _ := repo.GetByEmail(ctx, newUser.Email)
+		if u.FailedLogins != 10 || u.LockedUntil == nil {
+			t.Errorf("expected 10 failed logins locked, got %v until %v", u.FailedLogins, u.LockedUntil)
+		}
+	})
+}
+
+// failingRepo counts a failed login of the user right after it is read,
+// as a concurrent Login with a wrong password would.
+type failingRepo struct {
+	user.Repo
+}
+
+func (r failingRepo) GetByEmail(ctx context.Context, email string) (user.User, error) {
+	u, err := r.Repo.GetByEmail(ctx, email)
+	if err == nil {
+		r.Repo.FailLogin(ctx, u.ID)
+	}
+	return u, err
+}
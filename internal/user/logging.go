@@ -5,6 +5,8 @@ import (
 
 	"context"
 	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/logging"
 )
 
 type loggingService struct {
@@ -23,8 +25,9 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 
 func (s loggingService) Register(ctx context.Context, nuser NewUser) (user User, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "register",
+			"user_id", user.ID,
 			"err", err,
 			"took", time.Since(begin),
 		)
@@ -34,8 +37,9 @@ func (s loggingService) Register(ctx context.Context, nuser NewUser) (user User,
 
 func (s loggingService) Login(ctx context.Context, email, password string) (user User, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
+		_ = logging.FromContext(ctx, s.logger).Log(
 			"method", "login",
+			"user_id", user.ID,
 			"err", err,
 			"took", time.Since(begin),
 		)
@@ -45,8 +49,9 @@ func (s loggingService) Login(ctx context.Context, email, password string) (user
 
 func (s loggingService) AuthToken(ctx context.Context, token string) (user User, err error) {
 	defer func(begin time.Time) {
-		_ = s.logger.Log(
-			"method", "login",
+		_ = logging.FromContext(ctx, s.logger).Log(
+			"method", "auth_token",
+			"user_id", user.ID,
 			"err", err,
 			"took", time.Since(begin),
 		)
@@ -56,7 +61,7 @@ func (s loggingService) AuthToken(ctx context.Context, token string) (user User,
 
 func (s loggingService) ResetPassword(ctx context.Context, key, newpass string) (err error) {
 	defer func(begin time.Time) {
-		s.logger.Log(
+		logging.FromContext(ctx, s.logger).Log(
 			"method", "reset-password",
 			"err", err,
 			"took", time.Since(begin),
@@ -67,8 +72,9 @@ func (s loggingService) ResetPassword(ctx context.Context, key, newpass string)
 
 func (s loggingService) ChangePassword(ctx context.Context, userID string, oldpass, newpass string) (err error) {
 	defer func(begin time.Time) {
-		s.logger.Log(
+		logging.FromContext(ctx, s.logger).Log(
 			"method", "change-password",
+			"user_id", userID,
 			"err", err,
 			"took", time.Since(begin),
 		)
@@ -79,7 +85,7 @@ func (s loggingService) ChangePassword(ctx context.Context, userID string, oldpa
 
 func (s loggingService) List(ctx context.Context, order string, limit, offset int) (users []User, total int, err error) {
 	defer func(begin time.Time) {
-		s.logger.Log(
+		logging.FromContext(ctx, s.logger).Log(
 			"method", "list",
 			"err", err,
 			"took", time.Since(begin),
@@ -88,3 +94,111 @@ func (s loggingService) List(ctx context.Context, order string, limit, offset in
 
 	return s.next.List(ctx, order, limit, offset)
 }
+
+func (s loggingService) Addresses(ctx context.Context, userID string) (addresses []Address, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "addresses",
+			"user_id", userID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Addresses(ctx, userID)
+}
+
+func (s loggingService) AddAddress(ctx context.Context, userID string, address Address) (a Address, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "add-address",
+			"user_id", userID,
+			"address_id", a.ID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.AddAddress(ctx, userID, address)
+}
+
+func (s loggingService) UpdateAddress(ctx context.Context, userID string, address Address) (a Address, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "update-address",
+			"user_id", userID,
+			"address_id", address.ID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.UpdateAddress(ctx, userID, address)
+}
+
+func (s loggingService) RemoveAddress(ctx context.Context, userID, addressID string) (err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "remove-address",
+			"user_id", userID,
+			"address_id", addressID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.RemoveAddress(ctx, userID, addressID)
+}
+
+func (s loggingService) Delete(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "delete",
+			"user_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Delete(ctx, id)
+}
+
+func (s loggingService) ListDeleted(ctx context.Context) (users []User, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "list-deleted",
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.ListDeleted(ctx)
+}
+
+func (s loggingService) Restore(ctx context.Context, id string) (user User, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "restore",
+			"user_id", id,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.Restore(ctx, id)
+}
+
+func (s loggingService) SetRoles(ctx context.Context, id string, roles auth.Roles, publisherID string) (user User, err error) {
+	defer func(begin time.Time) {
+		logging.FromContext(ctx, s.logger).Log(
+			"method", "set-roles",
+			"user_id", id,
+			"roles", roles.String(),
+			"publisher_id", publisherID,
+			"err", err,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+
+	return s.next.SetRoles(ctx, id, roles, publisherID)
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/openapi"
+)
+
+// OpenAPI returns the API of the routes of MakeHTTPHandler.
+func OpenAPI() openapi.Service {
+	admin := []auth.Permission{auth.ManageUsers}
+	return openapi.Service{
+		Name:   "users",
+		Errors: errs.Infos(),
+		Routes: []openapi.Route{
+			{
+				Name: "register", Method: "POST", Path: "/users/v1/register",
+				Summary: "Registers a new user",
+				Request: registerRequest{}, Response: registerResponse{}, Status: http.StatusCreated,
+			},
+			{
+				Name: "login", Method: "POST", Path: "/users/v1/login",
+				Summary: "Logs the user in with email and password",
+				Request: loginRequest{}, Response: loginResponse{},
+			},
+			{
+				Name: "resetPassword", Method: "POST", Path: "/users/v1/reset-password",
+				Summary: "Resets the password with the key sent to the user",
+				Request: resetPasswordRequest{}, Response: resetPasswordResponse{},
+			},
+			{
+				Name: "changePassword", Method: "POST", Path: "/users/v1/change-password",
+				Summary: "Changes the password of the user",
+				Request: changePasswordRequest{}, Response: changePasswordResponse{}, Auth: true,
+			},
+			{
+				Name: "listUsers", Method: "GET", Path: "/users/v1/list",
+				Summary: "Lists the users a page at a time",
+				Query: []openapi.Param{
+					{Name: "order", Description: "Field to order by, optionally followed by asc or desc, e.g: email desc"},
+					{Name: "limit", Description: "Users per page, 20 by default", Type: "integer"},
+					{Name: "offset", Description: "Users to skip", Type: "integer"},
+				},
+				Response: listResponse{}, Permissions: admin,
+			},
+			{
+				Name: "listAddresses", Method: "GET", Path: "/users/v1/addresses",
+				Summary:  "Lists the address book of the user",
+				Response: addressesResponse{}, Auth: true,
+			},
+			{
+				Name: "addAddress", Method: "POST", Path: "/users/v1/addresses",
+				Summary: "Adds an address to the address book of the user",
+				Request: Address{}, Response: addressResponse{}, Status: http.StatusCreated, Auth: true,
+			},
+			{
+				Name: "updateAddress", Method: "PUT", Path: "/users/v1/addresses/{id}",
+				Summary: "Updates an address of the user",
+				Request: Address{}, Response: addressResponse{}, Auth: true,
+			},
+			{
+				Name: "removeAddress", Method: "DELETE", Path: "/users/v1/addresses/{id}",
+				Summary: "Removes an address of the user",
+				Auth:    true,
+			},
+			{
+				Name: "listDeletedUsers", Method: "GET", Path: "/users/v1/deleted",
+				Summary:  "Lists the soft deleted users",
+				Response: listDeletedResponse{}, Permissions: admin,
+			},
+			{
+				Name: "deleteUser", Method: "DELETE", Path: "/users/v1/{id}",
+				Summary:     "Soft deletes the user",
+				Permissions: admin,
+			},
+			{
+				Name: "restoreUser", Method: "POST", Path: "/users/v1/{id}/restore",
+				Summary:  "Restores the soft deleted user",
+				Response: restoreResponse{}, Permissions: admin,
+			},
+			{
+				Name: "listRoles", Method: "GET", Path: "/users/v1/roles",
+				Summary:  "Lists the roles with the permissions they grant",
+				Response: rolesResponse{}, Permissions: admin,
+			},
+			{
+				Name: "setRoles", Method: "PUT", Path: "/users/v1/{id}/roles",
+				Summary: "Replaces the roles of the user",
+				Request: setRolesRequest{}, Response: setRolesResponse{}, Permissions: admin,
+			},
+		},
+	}
+}
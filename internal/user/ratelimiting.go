@@ -0,0 +1,41 @@
+package user
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kavirajk/bookshop/ratelimit"
+)
+
+type ratelimitmw struct {
+	Service
+	byIP      ratelimit.Limiter
+	byAccount ratelimit.Limiter
+}
+
+// RateLimitingMiddleware throttles the guesses of credentials: logins and
+// password resets by client IP with byIP, see ratelimit.ClientIP, and
+// logins by account with byAccount. Throttled calls fail with
+// ratelimit.Error before reaching next.
+func RateLimitingMiddleware(byIP, byAccount ratelimit.Limiter) Middleware {
+	return func(next Service) Service {
+		return ratelimitmw{Service: next, byIP: byIP, byAccount: byAccount}
+	}
+}
+
+func (mw ratelimitmw) Login(ctx context.Context, email, password string) (User, error) {
+	if err := mw.byIP.Allow(ctx, ratelimit.ClientIP(ctx)); err != nil {
+		return User{}, err
+	}
+	if err := mw.byAccount.Allow(ctx, strings.ToLower(strings.TrimSpace(email))); err != nil {
+		return User{}, err
+	}
+	return mw.Service.Login(ctx, email, password)
+}
+
+func (mw ratelimitmw) ResetPassword(ctx context.Context, key, newpass string) error {
+	if err := mw.byIP.Allow(ctx, ratelimit.ClientIP(ctx)); err != nil {
+		return err
+	}
+	return mw.Service.ResetPassword(ctx, key, newpass)
+}
@@ -1,14 +1,47 @@
 package user
 
+import (
+	"context"
+	"time"
+)
+
 // Repo abstracts all the persistant storage operations of User service.
 type Repo interface {
-	Create(user *User) error
-	Save(user *User) error
-	GetByID(id string) (User, error)
-	GetByUserName(username string) (User, error)
-	GetByEmail(email string) (User, error)
-	GetByToken(token string) (User, error)
-	GetByResetKey(email string) (User, error)
-	List(order string, limit, offset int) (users []User, total int, err error)
-	Drop() error
+	Create(ctx context.Context, user *User) error
+	Save(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id string) (User, error)
+	GetByUserName(ctx context.Context, username string) (User, error)
+	GetByEmail(ctx context.Context, email string) (User, error)
+	GetByToken(ctx context.Context, token string) (User, error)
+	GetByResetKey(ctx context.Context, email string) (User, error)
+	List(ctx context.Context, order string, limit, offset int) (users []User, total int, err error)
+	Drop(ctx context.Context) error
+
+	// FailLogin atomically counts a failed login of the user, and returns
+	// its failed logins in a row, this one included.
+	FailLogin(ctx context.Context, id string) (int, error)
+	// LockOut locks the user out until, unless it is locked out longer
+	// already.
+	LockOut(ctx context.Context, id string, until time.Time) error
+	// ResetFailedLogins atomically forgets the failed logins and the
+	// lockout of the user. Unlike Save, it never conflicts with concurrent
+	// failed logins.
+	ResetFailedLogins(ctx context.Context, id string) error
+
+	// Delete soft deletes the user if it still has version, hiding it from
+	// all the other methods but ListDeleted and Restore until it is purged.
+	// It fails with db.ErrConflict if the user has another version.
+	Delete(ctx context.Context, id string, version int64) error
+	// ListDeleted returns the deleted users, latest deleted first.
+	ListDeleted(ctx context.Context) ([]User, error)
+	Restore(ctx context.Context, id string) error
+	// Purge hard deletes the users deleted before and returns their count.
+	Purge(ctx context.Context, before time.Time) (int, error)
+
+	// Address book of users.
+	CreateAddress(ctx context.Context, address *Address) error
+	SaveAddress(ctx context.Context, address *Address) error
+	GetAddress(ctx context.Context, id string) (Address, error)
+	ListAddresses(ctx context.Context, userID string) ([]Address, error)
+	DeleteAddress(ctx context.Context, id string) error
 }
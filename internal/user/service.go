@@ -2,12 +2,17 @@ package user
 
 import (
 	"errors"
+	"time"
 
 	"context"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/ratelimit"
 )
 
 var (
-	ErrUnauthorized    = errors.New("unauthorized")
+	ErrUnauthorized    = auth.ErrUnauthorized
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrInvalidResetKey = errors.New("invalid resetkey")
 	ErrUserNotFound    = errors.New("user not found")
@@ -16,6 +21,9 @@ var (
 // Service defines all the services provided user package.
 type Service interface {
 	Register(ctx context.Context, user NewUser) (User, error)
+
+	// Login fails with ErrAccountLocked, as ratelimit.Error telling when
+	// to retry, once too many logins failed, see Lockout.
 	Login(ctx context.Context, email, password string) (User, error)
 
 	// Used to authenticate via token
@@ -31,46 +39,99 @@ type Service interface {
 	// order takes string in the format "username asc" or " username desc"
 	// or in combination of multiple fields like "username asc, email desc"
 	List(ctx context.Context, order string, limit, offset int) (users []User, total int, err error)
+
+	// Addresses returns the address book of user.
+	Addresses(ctx context.Context, userID string) ([]Address, error)
+
+	// AddAddress adds new address to user's address book.
+	AddAddress(ctx context.Context, userID string, address Address) (Address, error)
+
+	// UpdateAddress changes an address of user's address book.
+	UpdateAddress(ctx context.Context, userID string, address Address) (Address, error)
+
+	// RemoveAddress removes an address from user's address book.
+	RemoveAddress(ctx context.Context, userID, addressID string) error
+
+	// Delete soft deletes a user: it can neither log in nor use its auth
+	// token anymore. It is purged with its address book after the
+	// retention period unless restored.
+	Delete(ctx context.Context, id string) error
+
+	// ListDeleted lists the users waiting to be purged, latest deleted first.
+	ListDeleted(ctx context.Context) ([]User, error)
+
+	// Restore lets a deleted user log in again, with its roles and address
+	// book as they were.
+	Restore(ctx context.Context, id string) (User, error)
+
+	// SetRoles replaces the roles of a user. publisherID is the publisher
+	// whose books the user manages with the publisher role.
+	SetRoles(ctx context.Context, id string, roles auth.Roles, publisherID string) (User, error)
 }
 
 // service is a simple implementation of Service interface.
 type service struct {
-	repo Repo
+	repo    Repo
+	lockout Lockout
 }
 
-// NewService takes User Repo and returns new User Service.
-func NewService(repo Repo) Service {
-	return service{repo: repo}
+// NewService takes User Repo and returns new User Service, locking
+// accounts out after failed logins as lockout says.
+func NewService(repo Repo, lockout Lockout) Service {
+	return service{repo: repo, lockout: lockout}
 }
 
 // Register registers the new user.
 // in case of non-nil error return User is always empty
-func (s service) Register(_ context.Context, nuser NewUser) (User, error) {
+func (s service) Register(ctx context.Context, nuser NewUser) (User, error) {
 	if err := nuser.Validate(); err != nil {
 		return User{}, err
 	}
 	user := nuser.User()
-	if err := s.repo.Create(&user); err != nil {
+	if err := s.repo.Create(ctx, &user); err != nil {
 		return User{}, err
 	}
 	return user, nil
 }
 
 // Login is used to authenticate any user with email and password.
-func (s service) Login(_ context.Context, email, password string) (User, error) {
-	user, err := s.repo.GetByEmail(email)
+func (s service) Login(ctx context.Context, email, password string) (User, error) {
+	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
 		return User{}, ErrUserNotFound
 	}
+	now := time.Now()
+	if wait := user.lockedFor(now); wait > 0 {
+		return User{}, ratelimit.Error{Err: ErrAccountLocked, Wait: wait}
+	}
 	if user.Password != calculatePassHash(password, user.Salt) {
+		// Counted in the repo, so that concurrent guesses all count.
+		failed, err := s.repo.FailLogin(ctx, user.ID)
+		if err != nil {
+			return User{}, err
+		}
+		if until, ok := s.lockout.Until(failed, now); ok {
+			if err := s.repo.LockOut(ctx, user.ID, until); err != nil {
+				return User{}, err
+			}
+		}
 		return User{}, ErrUnauthorized
 	}
+	if user.unlock() {
+		// Not saved, a concurrent failed login would make it conflict.
+		if err := s.repo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return User{}, err
+		}
+		if user, err = s.repo.GetByID(ctx, user.ID); err != nil {
+			return User{}, err
+		}
+	}
 	return user, nil
 }
 
 // AuthToken is used to get user associated with token.
-func (s service) AuthToken(_ context.Context, token string) (User, error) {
-	user, err := s.repo.GetByToken(token)
+func (s service) AuthToken(ctx context.Context, token string) (User, error) {
+	user, err := s.repo.GetByToken(ctx, token)
 	if err != nil {
 		return User{}, err
 	}
@@ -80,7 +141,7 @@ func (s service) AuthToken(_ context.Context, token string) (User, error) {
 // ResetPassword is used to change the users' password with key and newPass.
 // Typical use-case would be forgot password.
 func (s service) ResetPassword(ctx context.Context, key, newPass string) error {
-	user, err := s.repo.GetByResetKey(key)
+	user, err := s.repo.GetByResetKey(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -93,7 +154,7 @@ func (s service) ResetPassword(ctx context.Context, key, newPass string) error {
 // ChangePassword is used to change the user's password with oldpassword.
 // Typical use-case would be to use it in profile page
 func (s service) ChangePassword(ctx context.Context, userID, oldPass, newPass string) error {
-	user, err := s.repo.GetByID(userID)
+	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -105,17 +166,136 @@ func (s service) ChangePassword(ctx context.Context, userID, oldPass, newPass st
 
 // ListUser lists all the available users in the system.
 func (s service) List(ctx context.Context, order string, limit, offset int) ([]User, int, error) {
-	return s.repo.List(order, limit, offset)
+	return s.repo.List(ctx, order, limit, offset)
+}
+
+// Addresses returns the address book of user.
+func (s service) Addresses(ctx context.Context, userID string) ([]Address, error) {
+	return s.repo.ListAddresses(ctx, userID)
+}
+
+// AddAddress validates and adds new address to user's address book.
+func (s service) AddAddress(ctx context.Context, userID string, address Address) (Address, error) {
+	if err := address.Validate(); err != nil {
+		return Address{}, err
+	}
+	address.ID = ""
+	address.UserID = userID
+	if err := s.repo.CreateAddress(ctx, &address); err != nil {
+		return Address{}, err
+	}
+	return address, nil
+}
+
+// UpdateAddress validates and saves an address of user's address book.
+// Address of some other user is reported as ErrAddressNotFound.
+func (s service) UpdateAddress(ctx context.Context, userID string, address Address) (Address, error) {
+	if _, err := s.ownAddress(ctx, userID, address.ID); err != nil {
+		return Address{}, err
+	}
+	if err := address.Validate(); err != nil {
+		return Address{}, err
+	}
+	address.UserID = userID
+	if err := s.repo.SaveAddress(ctx, &address); err != nil {
+		return Address{}, err
+	}
+	return address, nil
+}
+
+// RemoveAddress removes an address from user's address book.
+func (s service) RemoveAddress(ctx context.Context, userID, addressID string) error {
+	if _, err := s.ownAddress(ctx, userID, addressID); err != nil {
+		return err
+	}
+	return s.repo.DeleteAddress(ctx, addressID)
+}
+
+// ownAddress returns address only if it belongs to user.
+func (s service) ownAddress(ctx context.Context, userID, addressID string) (Address, error) {
+	address, err := s.repo.GetAddress(ctx, addressID)
+	if err != nil || address.UserID != userID {
+		return Address{}, ErrAddressNotFound
+	}
+	return address, nil
 }
 
 // changePassword is an unexpoted helper function to change the password of the user.
-func (s service) changePassword(_ context.Context, user User, newPass string) error {
+// It fails with db.ErrPreconditionFailed if ctx expects another version of
+// the user, and with db.ErrConflict if the user changed meanwhile. The new
+// password ends any lockout.
+func (s service) changePassword(ctx context.Context, user User, newPass string) error {
+	if err := db.CheckIfMatch(ctx, user.Version); err != nil {
+		return err
+	}
 	user.Password = calculatePassHash(newPass, user.Salt)
-	if err := s.repo.Save(&user); err != nil {
+	user.unlock()
+	if err := s.repo.Save(ctx, &user); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Delete soft deletes a user, failing with db.ErrPreconditionFailed if the
+// admin expects another version, see db.WithIfMatch, and with
+// db.ErrConflict if the user saved meanwhile, e.g: by a failed login.
+func (s service) Delete(ctx context.Context, id string) error {
+	user, err := s.repo.GetByID(ctx, id)
+	if err == db.ErrNotFound {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := db.CheckIfMatch(ctx, user.Version); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id, user.Version)
+}
+
+// ListDeleted returns the deleted users of the repo.
+func (s service) ListDeleted(ctx context.Context) ([]User, error) {
+	return s.repo.ListDeleted(ctx)
+}
+
+// Restore restores a deleted user and returns it, ErrUserNotFound if it
+// isn't deleted or was purged.
+func (s service) Restore(ctx context.Context, id string) (User, error) {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		if err == db.ErrNotFound {
+			return User{}, ErrUserNotFound
+		}
+		return User{}, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// SetRoles replaces the roles of a user and returns it. It fails with
+// db.ErrPreconditionFailed if ctx expects another version of the user.
+func (s service) SetRoles(ctx context.Context, id string, roles auth.Roles, publisherID string) (User, error) {
+	if err := validateRoles(roles, publisherID); err != nil {
+		return User{}, err
+	}
+	user, err := s.repo.GetByID(ctx, id)
+	if err == db.ErrNotFound {
+		return User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if err := db.CheckIfMatch(ctx, user.Version); err != nil {
+		return User{}, err
+	}
+	user.Roles = roles
+	user.PublisherID = ""
+	if roles.Has(auth.Publisher) {
+		user.PublisherID = publisherID
+	}
+	if err := s.repo.Save(ctx, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
 // Middleware is a Service middleware for user Service
 type Middleware func(Service) Service
@@ -0,0 +1,110 @@
+package user
+
+import (
+	"context"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingService struct {
+	tracer trace.Tracer
+	next   Service
+}
+
+// TracingMiddleware traces every call of the service in a span, child of the
+// span of its ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next Service) Service {
+		return tracingService{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+func (s tracingService) Register(ctx context.Context, nuser NewUser) (user User, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.Register")
+	defer func() { tracing.End(span, err) }()
+	return s.next.Register(ctx, nuser)
+}
+
+func (s tracingService) Login(ctx context.Context, email, password string) (user User, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.Login")
+	defer func() { tracing.End(span, err) }()
+	return s.next.Login(ctx, email, password)
+}
+
+func (s tracingService) AuthToken(ctx context.Context, token string) (user User, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.AuthToken")
+	defer func() { tracing.End(span, err) }()
+	return s.next.AuthToken(ctx, token)
+}
+
+func (s tracingService) ResetPassword(ctx context.Context, key, newpass string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "user.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+	return s.next.ResetPassword(ctx, key, newpass)
+}
+
+func (s tracingService) ChangePassword(ctx context.Context, userID string, oldpass, newpass string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "user.ChangePassword", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.ChangePassword(ctx, userID, oldpass, newpass)
+}
+
+func (s tracingService) List(ctx context.Context, order string, limit, offset int) (users []User, total int, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.List")
+	defer func() { tracing.End(span, err) }()
+	return s.next.List(ctx, order, limit, offset)
+}
+
+func (s tracingService) Addresses(ctx context.Context, userID string) (addresses []Address, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.Addresses", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Addresses(ctx, userID)
+}
+
+func (s tracingService) AddAddress(ctx context.Context, userID string, address Address) (a Address, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.AddAddress", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.AddAddress(ctx, userID, address)
+}
+
+func (s tracingService) UpdateAddress(ctx context.Context, userID string, address Address) (a Address, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.UpdateAddress", trace.WithAttributes(attribute.String("user.id", userID), attribute.String("address.id", address.ID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.UpdateAddress(ctx, userID, address)
+}
+
+func (s tracingService) RemoveAddress(ctx context.Context, userID, addressID string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "user.RemoveAddress", trace.WithAttributes(attribute.String("user.id", userID), attribute.String("address.id", addressID)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.RemoveAddress(ctx, userID, addressID)
+}
+
+func (s tracingService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "user.Delete", trace.WithAttributes(attribute.String("user.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Delete(ctx, id)
+}
+
+func (s tracingService) ListDeleted(ctx context.Context) (users []User, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.ListDeleted")
+	defer func() { tracing.End(span, err) }()
+	return s.next.ListDeleted(ctx)
+}
+
+func (s tracingService) Restore(ctx context.Context, id string) (user User, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.Restore", trace.WithAttributes(attribute.String("user.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return s.next.Restore(ctx, id)
+}
+
+func (s tracingService) SetRoles(ctx context.Context, id string, roles auth.Roles, publisherID string) (user User, err error) {
+	ctx, span := s.tracer.Start(ctx, "user.SetRoles", trace.WithAttributes(attribute.String("user.id", id), attribute.String("user.roles", roles.String())))
+	defer func() { tracing.End(span, err) }()
+	return s.next.SetRoles(ctx, id, roles, publisherID)
+}
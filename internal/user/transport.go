@@ -1,9 +1,9 @@
 package user
 
 import (
-	"encoding/json"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 
 	"context"
@@ -11,88 +11,204 @@ import (
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/transport"
 	"github.com/pkg/errors"
 )
 
 var (
 	ErrNoNextPage = errors.New("no next page")
 	ErrNoPrevPage = errors.New("no prev page")
+	ErrBadRouting = transport.ErrBadRouting
 )
 
+// errs are the errors of the users sent over HTTP.
+var errs = transport.NewRegistry().
+	Register(ErrUserNotFound, http.StatusNotFound, "user_not_found").
+	Register(ErrAddressNotFound, http.StatusNotFound, "address_not_found").
+	Register(ErrAccountLocked, http.StatusTooManyRequests, "account_locked").
+	Register(ErrInvalidPassword, http.StatusBadRequest, "invalid_password").
+	Register(ErrInvalidResetKey, http.StatusBadRequest, "invalid_reset_key")
+
+var encodeResponse = transport.ResponseEncoder(errs)
+
 const (
 	defaultPageLimit = 20
+	maxPageLimit     = 100
 )
 
-func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+// listOrder matches the orders users can be listed in. It goes into the
+// query as is, so only known columns are allowed.
+var listOrder = regexp.MustCompile(`^(id|first_name|last_name|email|username)( (?i:asc|desc))?$`)
+
+// MakeRoutes returns the routes of the users, mounted under /users/v1.
+func MakeRoutes(ctx context.Context, s Service, logger log.Logger) transport.Routes {
 	e := MakeEndpoints(s)
-	options := []httptransport.ServerOption{
-		httptransport.ServerErrorEncoder(encodeError),
-	}
+	options := transport.ServerOptions(errs)
 	registerHandler := httptransport.NewServer(
 		e.RegisterEndpoint,
-		decodeRegisterRequest,
+		transport.Validated(decodeRegisterRequest),
 		encodeResponse,
 		options...,
 	)
 	loginHandler := httptransport.NewServer(
 		e.LoginEndpoint,
-		decodeLoginRequest,
+		transport.Validated(decodeLoginRequest),
 		encodeResponse,
 		options...,
 	)
 	resetPasswordHandler := httptransport.NewServer(
 		e.ResetPasswordEndpoint,
-		decodeResetPasswordRequest,
+		transport.Validated(decodeResetPasswordRequest),
 		encodeResponse,
 		options...,
 	)
 	changePasswordHandler := httptransport.NewServer(
 		e.ChangePasswordEndpoint,
-		decodeChangePasswordRequest,
+		transport.Validated(decodeChangePasswordRequest),
 		encodeResponse,
 		options...,
 	)
 	listHandler := httptransport.NewServer(
 		e.ListEndpoint,
-		decodeListRequest,
+		transport.Validated(decodeListRequest),
 		encodeResponse,
 		options...,
 	)
 
-	r := mux.NewRouter()
+	addressesHandler := httptransport.NewServer(
+		e.AddressesEndpoint,
+		transport.Validated(decodeAddressesRequest),
+		encodeResponse,
+		options...,
+	)
+	addAddressHandler := httptransport.NewServer(
+		e.AddAddressEndpoint,
+		transport.Validated(decodeAddressRequest),
+		encodeResponse,
+		options...,
+	)
+	updateAddressHandler := httptransport.NewServer(
+		e.UpdateAddressEndpoint,
+		transport.Validated(decodeAddressRequest),
+		encodeResponse,
+		options...,
+	)
+	removeAddressHandler := httptransport.NewServer(
+		e.RemoveAddressEndpoint,
+		transport.Validated(decodeRemoveAddressRequest),
+		encodeResponse,
+		options...,
+	)
+
+	deleteHandler := httptransport.NewServer(
+		e.DeleteEndpoint,
+		transport.Validated(decodeDeleteRequest),
+		encodeResponse,
+		options...,
+	)
+	listDeletedHandler := httptransport.NewServer(
+		e.ListDeletedEndpoint,
+		transport.Validated(decodeListDeletedRequest),
+		encodeResponse,
+		options...,
+	)
+	restoreHandler := httptransport.NewServer(
+		e.RestoreEndpoint,
+		transport.Validated(decodeDeleteRequest),
+		encodeResponse,
+		options...,
+	)
+
+	rolesHandler := httptransport.NewServer(
+		e.RolesEndpoint,
+		transport.Validated(decodeRolesRequest),
+		encodeResponse,
+		options...,
+	)
+	setRolesHandler := httptransport.NewServer(
+		e.SetRolesEndpoint,
+		transport.Validated(decodeSetRolesRequest),
+		encodeResponse,
+		options...,
+	)
 
-	r.Handle("/users/v1/register", registerHandler).Methods("POST")
-	r.Handle("/users/v1/login", loginHandler).Methods("POST")
-	r.Handle("/users/v1/reset-password", resetPasswordHandler).Methods("POST")
-	r.Handle("/users/v1/change-password", changePasswordHandler).Methods("POST")
-	r.Handle("/users/v1/list", listHandler).Methods("GET")
+	return transport.NewRoutes("/users/v1", func(r *mux.Router) {
+		r.Handle("/register", registerHandler).Methods("POST")
+		r.Handle("/login", loginHandler).Methods("POST")
+		r.Handle("/reset-password", resetPasswordHandler).Methods("POST")
+		r.Handle("/change-password", changePasswordHandler).Methods("POST")
+		r.Handle("/list", listHandler).Methods("GET")
+		r.Handle("/addresses", addressesHandler).Methods("GET")
+		r.Handle("/addresses", addAddressHandler).Methods("POST")
+		r.Handle("/addresses/{id}", updateAddressHandler).Methods("PUT")
+		r.Handle("/addresses/{id}", removeAddressHandler).Methods("DELETE")
+
+		// Admin routes of soft deleted users.
+		r.Handle("/deleted", listDeletedHandler).Methods("GET")
+		r.Handle("/{id}", deleteHandler).Methods("DELETE")
+		r.Handle("/{id}/restore", restoreHandler).Methods("POST")
+
+		// Admin routes of the roles of users.
+		r.Handle("/roles", rolesHandler).Methods("GET")
+		r.Handle("/{id}/roles", setRolesHandler).Methods("PUT")
+	})
+}
 
+// MakeHTTPHandler returns handler of the routes of MakeRoutes, e.g: for
+// tests.
+func MakeHTTPHandler(ctx context.Context, s Service, logger log.Logger) http.Handler {
+	r := mux.NewRouter()
+	transport.Mount(r, MakeRoutes(ctx, s, logger))
 	return r
 }
 func decodeRegisterRequest(ctx context.Context, req *http.Request) (interface{}, error) {
 	var r registerRequest
-	err := json.NewDecoder(req.Body).Decode(&r)
+	err := transport.DecodeJSON(req, &r)
 	return r, err
 }
 
 func decodeLoginRequest(ctx context.Context, req *http.Request) (interface{}, error) {
 	var r loginRequest
-	err := json.NewDecoder(req.Body).Decode(&r)
+	err := transport.DecodeJSON(req, &r)
 	return r, err
 }
 
 func decodeResetPasswordRequest(ctx context.Context, req *http.Request) (interface{}, error) {
 	var r resetPasswordRequest
-	err := json.NewDecoder(req.Body).Decode(&r)
+	err := transport.DecodeJSON(req, &r)
 	return r, err
 }
 
 func decodeChangePasswordRequest(ctx context.Context, req *http.Request) (interface{}, error) {
-	var r resetPasswordRequest
-	err := json.NewDecoder(req.Body).Decode(&r)
+	var r changePasswordRequest
+	err := transport.DecodeJSON(req, &r)
+	r.Token = transport.Token(req)
 	return r, err
 }
 
+func decodeAddressesRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return addressesRequest{Token: transport.Token(req)}, nil
+}
+
+func decodeAddressRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	var r addressRequest
+	if err := transport.DecodeJSON(req, &r); err != nil {
+		return nil, err
+	}
+	r.Token = transport.Token(req)
+	// Address ID always comes from the url when given.
+	if id, ok := mux.Vars(req)["id"]; ok {
+		r.Address.ID = id
+	}
+	return r, nil
+}
+
+func decodeRemoveAddressRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	r := removeAddressRequest{Token: transport.Token(req)}
+	r.ID = mux.Vars(req)["id"]
+	return r, nil
+}
+
 func decodeListRequest(ctx context.Context, req *http.Request) (interface{}, error) {
 	lreq := listRequest{}
 	lreq.Order = req.FormValue("order")
@@ -115,87 +231,33 @@ func decodeListRequest(ctx context.Context, req *http.Request) (interface{}, err
 	return lreq, nil
 }
 
-type errorer interface {
-	error() error
-}
-
-type statuser interface {
-	status() int
-}
-
-type pager interface {
-	page() (total int, previous, next string)
-}
-
-// formatResponse is the uniform response format used throughout the users service,
-// for every endpoint response.
-type formatResponse struct {
-	Data interface{}  `json:"data,omitempty"`
-	Meta metaResponse `json:"meta"`
+func decodeDeleteRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	return deleteRequest{ID: id}, nil
 }
 
-// metaResponse is part of response json that tells about basic meta information.
-type metaResponse struct {
-	Status   int    `json:"status"`
-	Error    string `json:"error,omitempty"`
-	Previous string `json:"previous,omitempty"`
-	Next     string `json:"next,omitempty"`
-	Total    int    `json:"total,omitempty"`
+func decodeListDeletedRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return nil, nil
 }
 
-func encodeResponse(ctx context.Context, w http.ResponseWriter, d interface{}) error {
-	if e, ok := d.(errorer); ok && e.error() != nil {
-		// Now its a business logic error.
-		// Extract base domain error.
-		encodeError(ctx, e.error(), w)
-		return nil
-	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-	status := http.StatusOK
-	if s, ok := d.(statuser); ok && s.status() != 0 {
-		status = s.status()
-	}
-
-	f := formatResponse{
-		Data: d,
-		Meta: metaResponse{Status: status},
-	}
-
-	if page, ok := d.(pager); ok {
-		t, p, n := page.page()
-		f.Meta.Total = t
-		f.Meta.Previous = p
-		f.Meta.Next = n
-	}
-
-	return json.NewEncoder(w).Encode(f)
+func decodeRolesRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	return nil, nil
 }
 
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	if err == nil {
-		panic("encodeError with nil error")
+func decodeSetRolesRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	var r setRolesRequest
+	if err := transport.DecodeJSON(req, &r); err != nil {
+		return nil, err
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	// Its important to pass errors.Cause() as we decide status code based on
-	// root error which is domain specific
-	code := codeFrom(errors.Cause(err))
-	w.WriteHeader(code)
-	f := formatResponse{Meta: metaResponse{Status: code, Error: err.Error()}}
-	json.NewEncoder(w).Encode(f)
-}
-
-func codeFrom(err error) int {
-	switch err {
-	case ErrUserNotFound:
-		return http.StatusNotFound
-	case ErrUnauthorized:
-		return http.StatusUnauthorized
-	case ErrInvalidPassword, ErrInvalidResetKey, ErrMissingField, ErrPasswordMismatch:
-		return http.StatusBadRequest
-	default:
-		return http.StatusInternalServerError
+	id, err := transport.Var(req, "id")
+	if err != nil {
+		return nil, err
 	}
+	r.ID = id
+	return r, nil
 }
 
 func nextLimitOffset(total, currentLimit, currentOffset int) (limit, offset int, err error) {
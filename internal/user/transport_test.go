@@ -0,0 +1,41 @@
+package user_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/user"
+)
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	repo := inmem.NewUserRepo()
+	s := user.NewService(repo, user.DefaultLockout)
+	newUser := user.NewUser{FirstName: "Monica", LastName: "Geller", Email: "monica@golang.org", Password: "cleanfreak", ConfirmPassword: "cleanfreak"}
+	if _, err := s.Register(ctx, newUser); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	u, _ := repo.GetByEmail(ctx, newUser.Email)
+	u.AuthToken = "monica-token"
+	if err := repo.Save(ctx, &u); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	h := user.MakeHTTPHandler(ctx, s, log.NewNopLogger())
+
+	body := `{"old_password": "cleanfreak", "new_password": "chefmonica", "confirm_new_password": "chefmonica"}`
+	req := httptest.NewRequest("POST", "/users/v1/change-password", strings.NewReader(body))
+	req.Header.Set("Authorization", "Token "+u.AuthToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %s", w.Code, w.Body)
+	}
+	if _, err := s.Login(ctx, newUser.Email, "chefmonica"); err != nil {
+		t.Errorf("expected login with the new password, got %v", err)
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pkg/errors"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/validate"
 )
 
-var (
-	ErrMissingField     = errors.New("missing field")
-	ErrPasswordMismatch = errors.New("passwords didn't match")
+// Bounds of the password length, in characters.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
 )
 
 // User represents domain model of user service.
@@ -27,6 +29,22 @@ type User struct {
 	Salt      string `json:"-"`
 	ResetKey  string `json:"-"`
 	AuthToken string `json:"-"`
+
+	// FailedLogins counts the failed logins since the last successful one,
+	// LockedUntil is set once they lock the account out, see Lockout.
+	FailedLogins int        `json:"-"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+
+	// Roles grant the user permissions, none for customers. PublisherID is
+	// the publisher whose books the user manages with auth.Publisher.
+	Roles       auth.Roles `json:"roles,omitempty"`
+	PublisherID string     `json:"publisher_id,omitempty"`
+
+	// Version is bumped on every save, see db.ErrConflict.
+	Version int64 `json:"version"`
+
+	// DeletedAt is set once the user is soft deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // New create empty user with random salt.
@@ -55,25 +73,21 @@ type NewUser struct {
 
 // Validate does basic validation before saving into db.
 func (n *NewUser) Validate() error {
-	if n.FirstName == "" {
-		return errors.Wrap(ErrMissingField, ": first_name")
-	}
-	if n.LastName == "" {
-		return errors.Wrap(ErrMissingField, ": last_name")
-	}
-	if n.Email == "" {
-		return errors.Wrap(ErrMissingField, ": email")
+	var v validate.Validator
+	v.Required("first_name", n.FirstName)
+	v.Length("first_name", n.FirstName, 1, 100)
+	v.Required("last_name", n.LastName)
+	v.Length("last_name", n.LastName, 1, 100)
+	if v.Required("email", n.Email) {
+		v.Email("email", n.Email)
 	}
-	if n.Password == "" {
-		return errors.Wrap(ErrMissingField, ": password")
+	if v.Required("password", n.Password) {
+		v.Length("password", n.Password, MinPasswordLength, MaxPasswordLength)
 	}
-	if n.ConfirmPassword == "" {
-		return errors.Wrap(ErrMissingField, ": confirm_password")
+	if v.Required("confirm_password", n.ConfirmPassword) {
+		v.Equal("confirm_password", n.ConfirmPassword, n.Password, "password")
 	}
-	if n.Password != n.ConfirmPassword {
-		return ErrPasswordMismatch
-	}
-	return nil
+	return v.Err()
 }
 
 // User map NewUser with domain User.
@@ -88,6 +102,24 @@ func (n *NewUser) User() User {
 	return u
 }
 
+// Principal returns u as the principal of its requests.
+func (u *User) Principal() auth.Principal {
+	return auth.Principal{UserID: u.ID, Roles: u.Roles, PublisherID: u.PublisherID}
+}
+
+// validateRoles checks roles are known, and that publishers have their
+// publisher.
+func validateRoles(roles auth.Roles, publisherID string) error {
+	var v validate.Validator
+	for _, r := range roles {
+		if !v.Check(r.Known(), "roles", validate.Format, "must be admin or publisher") {
+			break
+		}
+	}
+	v.Check(!roles.Has(auth.Publisher) || publisherID != "", "publisher_id", validate.Missing, "is required for publishers")
+	return v.Err()
+}
+
 func calculatePassHash(pass, salt string) string {
 	h := sha1.New()
 	io.WriteString(h, salt)
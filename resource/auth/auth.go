@@ -0,0 +1,212 @@
+// auth authorizes the requests of users: their roles grant them
+// permissions, endpoints require some of them, see Require.
+package auth
+
+import (
+	"context"
+	"database/sql/driver"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// Permission allows some calls, e.g: deleting books.
+type Permission string
+
+const (
+	ManageUsers      Permission = "users:manage"
+	ManageCatalog    Permission = "catalog:manage"
+	ManageOwnBooks   Permission = "catalog:manage_own" // books of the publisher of the user
+	ManageOrders     Permission = "orders:manage"
+	ManagePromotions Permission = "promotions:manage"
+	ManageRates      Permission = "rates:manage"
+)
+
+// Role grants its permissions to the users having it. Users without roles
+// are customers, they only have access to their own data.
+type Role string
+
+const (
+	Admin     Role = "admin"
+	Publisher Role = "publisher"
+)
+
+// Permissions are the permissions granted by every role.
+var Permissions = map[Role][]Permission{
+	Admin:     {ManageUsers, ManageCatalog, ManageOrders, ManagePromotions, ManageRates},
+	Publisher: {ManageOwnBooks},
+}
+
+// Known reports whether r is one of Permissions.
+func (r Role) Known() bool {
+	_, ok := Permissions[r]
+	return ok
+}
+
+// Roles of a user. They are stored as a comma separated column.
+type Roles []Role
+
+// ParseRoles parses comma separated roles, e.g: admin,publisher.
+func ParseRoles(s string) Roles {
+	var roles Roles
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, Role(r))
+		}
+	}
+	return roles
+}
+
+func (rs Roles) String() string {
+	names := make([]string, len(rs))
+	for i, r := range rs {
+		names[i] = string(r)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Has reports whether role is one of rs.
+func (rs Roles) Has(role Role) bool {
+	for _, r := range rs {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether one of rs grants perm.
+func (rs Roles) Can(perm Permission) bool {
+	for _, r := range rs {
+		for _, p := range Permissions[r] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (rs Roles) Value() (driver.Value, error) {
+	return rs.String(), nil
+}
+
+func (rs *Roles) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*rs = nil
+	case string:
+		*rs = ParseRoles(s)
+	case []byte:
+		*rs = ParseRoles(string(s))
+	default:
+		return errors.Errorf("auth: can't scan %T into roles", src)
+	}
+	return nil
+}
+
+// Principal is the user a request is made by.
+type Principal struct {
+	UserID string
+	Roles  Roles
+	// PublisherID is the publisher whose books the user manages, with
+	// ManageOwnBooks.
+	PublisherID string
+}
+
+// Can reports whether p has perm.
+func (p Principal) Can(perm Permission) bool {
+	return p.Roles.Can(perm)
+}
+
+// Authenticator returns the principal of an auth token. It fails with
+// ErrUnauthorized if the token is unknown.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Principal, error)
+}
+
+// AuthenticatorFunc is a func implementing Authenticator.
+type AuthenticatorFunc func(ctx context.Context, token string) (Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, token string) (Principal, error) {
+	return f(ctx, token)
+}
+
+// credentials of a request, authenticated once they are needed.
+type credentials struct {
+	token string
+	authn Authenticator
+
+	once      sync.Once
+	principal Principal
+	err       error
+}
+
+type credentialsKey struct{}
+
+type principalKey struct{}
+
+// WithToken returns ctx of a request made with the auth token, authenticated
+// by authn the first time PrincipalFrom needs it. Requests to public routes
+// don't look the token up.
+func WithToken(ctx context.Context, token string, authn Authenticator) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, &credentials{token: token, authn: authn})
+}
+
+// PrincipalFrom authenticates the request of ctx, see WithToken. It fails
+// with ErrUnauthorized if the request has no auth token.
+func PrincipalFrom(ctx context.Context) (Principal, error) {
+	if p, ok := FromContext(ctx); ok {
+		return p, nil
+	}
+	c, _ := ctx.Value(credentialsKey{}).(*credentials)
+	if c == nil || c.token == "" || c.authn == nil {
+		return Principal{}, ErrUnauthorized
+	}
+	c.once.Do(func() {
+		c.principal, c.err = c.authn.Authenticate(ctx, c.token)
+	})
+	return c.principal, c.err
+}
+
+// WithPrincipal returns ctx of a request made by p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal authorized by Require, false if the
+// request wasn't authorized, e.g: a call from bookctl.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Require is an endpoint middleware letting through the requests made by a
+// principal having any of perms, see PrincipalFrom. Others fail with
+// ErrUnauthorized or ErrForbidden. The principal is in the ctx of next, see
+// FromContext.
+func Require(perms ...Permission) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			p, err := PrincipalFrom(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, perm := range perms {
+				if p.Can(perm) {
+					return next(WithPrincipal(ctx, p), request)
+				}
+			}
+			return nil, ErrForbidden
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kavirajk/bookshop/auth"
+)
+
+func TestRequire(t *testing.T) {
+	principals := map[string]auth.Principal{
+		"admin":     {UserID: "1", Roles: auth.Roles{auth.Admin}},
+		"publisher": {UserID: "2", Roles: auth.Roles{auth.Publisher}, PublisherID: "penguin"},
+		"customer":  {UserID: "3"},
+	}
+	authn := auth.AuthenticatorFunc(func(ctx context.Context, token string) (auth.Principal, error) {
+		p, ok := principals[token]
+		if !ok {
+			return auth.Principal{}, auth.ErrUnauthorized
+		}
+		return p, nil
+	})
+	cases := []struct {
+		name  string
+		token string
+		perms []auth.Permission
+		err   error
+	}{
+		{"no token", "", []auth.Permission{auth.ManageUsers}, auth.ErrUnauthorized},
+		{"unknown token", "guess", []auth.Permission{auth.ManageUsers}, auth.ErrUnauthorized},
+		{"customer", "customer", []auth.Permission{auth.ManageUsers}, auth.ErrForbidden},
+		{"admin", "admin", []auth.Permission{auth.ManageUsers}, nil},
+		{"publisher of any", "publisher", []auth.Permission{auth.ManageCatalog, auth.ManageOwnBooks}, nil},
+		{"publisher not admin", "publisher", []auth.Permission{auth.ManageCatalog}, auth.ErrForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got auth.Principal
+			e := auth.Require(c.perms...)(func(ctx context.Context, request interface{}) (interface{}, error) {
+				got, _ = auth.FromContext(ctx)
+				return nil, nil
+			})
+			ctx := auth.WithToken(context.Background(), c.token, authn)
+			if _, err := e(ctx, nil); err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if c.err == nil && got.UserID != principals[c.token].UserID {
+				t.Errorf("expected principal %v, got %v", principals[c.token].UserID, got.UserID)
+			}
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	roles := auth.ParseRoles(" publisher, admin ,")
+	if len(roles) != 2 || !roles.Has(auth.Admin) || !roles.Has(auth.Publisher) {
+		t.Fatalf("expected admin and publisher, got %v", roles)
+	}
+	v, err := roles.Value()
+	if err != nil || v != "admin,publisher" {
+		t.Errorf("expected admin,publisher, got %v (%v)", v, err)
+	}
+	var scanned auth.Roles
+	if err := scanned.Scan([]byte("admin,publisher")); err != nil || len(scanned) != 2 {
+		t.Errorf("expected 2 roles, got %v (%v)", scanned, err)
+	}
+	if err := scanned.Scan(""); err != nil || len(scanned) != 0 {
+		t.Errorf("expected no roles, got %v (%v)", scanned, err)
+	}
+	if !roles.Can(auth.ManageOwnBooks) || (auth.Roles{auth.Publisher}).Can(auth.ManageUsers) {
+		t.Errorf("expected publishers to only manage their books")
+	}
+}
@@ -0,0 +1,28 @@
+// cache keeps results of expensive calls for a while, either in process or
+// in a server speaking the Redis protocol, shared by all the nodes.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrMiss       = errors.New("cache miss")
+	ErrNotInteger = errors.New("cached value is not an integer")
+)
+
+// Cache stores values by key until their TTL expires.
+type Cache interface {
+	// Get returns value of key, ErrMiss if it is missing or expired.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores value of key for ttl, for ever if ttl is 0.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Incr increments the integer value of key and returns it. Missing
+	// key counts as 0. The TTL of key is kept.
+	Incr(ctx context.Context, key string) (int64, error)
+}
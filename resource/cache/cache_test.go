@@ -0,0 +1,80 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/kavirajk/bookshop/cache/cachetest"
+	"github.com/pkg/errors"
+)
+
+func TestLRU(t *testing.T) {
+	cachetest.Cache(t, func(t *testing.T) cache.Cache { return cache.NewLRU(10) })
+
+	t.Run("eviction", func(t *testing.T) {
+		ctx := context.Background()
+		c := cache.NewLRU(2)
+		c.Set(ctx, "a", []byte("1"), 0)
+		c.Set(ctx, "b", []byte("2"), 0)
+		c.Get(ctx, "a") // b is the least recently used now
+		c.Set(ctx, "c", []byte("3"), 0)
+
+		if c.Len() != 2 {
+			t.Errorf("expected 2 keys, got %v", c.Len())
+		}
+		if _, err := c.Get(ctx, "b"); errors.Cause(err) != cache.ErrMiss {
+			t.Errorf("expected b evicted, got %v", err)
+		}
+		for _, key := range []string{"a", "c"} {
+			if _, err := c.Get(ctx, key); err != nil {
+				t.Errorf("expected %v kept, got %v", key, err)
+			}
+		}
+	})
+}
+
+func TestRedis(t *testing.T) {
+	cachetest.Cache(t, func(t *testing.T) cache.Cache {
+		c := cache.NewRedis(cachetest.NewServer(t).Addr, cache.RedisOptions{Password: "secret", DB: 1})
+		t.Cleanup(func() { c.Close() })
+		return c
+	})
+
+	t.Run("reuses connections", func(t *testing.T) {
+		ctx := context.Background()
+		srv := cachetest.NewServer(t)
+		c := cache.NewRedis(srv.Addr, cache.RedisOptions{Password: "secret"})
+		defer c.Close()
+		for i := 0; i < 3; i++ {
+			if err := c.Set(ctx, fmt.Sprint(i), []byte("v"), 0); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+		if n := srv.Calls("AUTH"); n != 1 {
+			t.Errorf("expected 1 AUTH, got %v", n)
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		c := cache.NewRedis(cachetest.NewServer(t).Addr, cache.RedisOptions{})
+		defer c.Close()
+		ctx := context.Background()
+		c.Set(ctx, "k", []byte("v"), 0)
+		_, err := c.Incr(ctx, "k")
+		if _, ok := err.(cache.RedisError); !ok {
+			t.Errorf("expected RedisError, got %v", err)
+		}
+		if err := c.Ping(ctx); err != nil {
+			t.Errorf("expected connection usable, got %v", err)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		c := cache.NewRedis("127.0.0.1:1", cache.RedisOptions{})
+		if _, err := c.Get(context.Background(), "k"); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+// cachetest is the conformance test suite of the caches, with a stand-in
+// Redis server to run the Redis cache against.
+package cachetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/pkg/errors"
+)
+
+var ctx = context.Background()
+
+// Cache runs the cache.Cache conformance tests on a fresh cache of
+// newCache for every test.
+func Cache(t *testing.T, newCache func(t *testing.T) cache.Cache) {
+	t.Run("get and set", func(t *testing.T) {
+		c := newCache(t)
+		if _, err := c.Get(ctx, "missing"); errors.Cause(err) != cache.ErrMiss {
+			t.Errorf("expected %v, got %v", cache.ErrMiss, err)
+		}
+		if err := c.Set(ctx, "k", []byte("v\r\n1"), 0); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if got, err := c.Get(ctx, "k"); err != nil || string(got) != "v\r\n1" {
+			t.Errorf("expected v\\r\\n1, got %q (%v)", got, err)
+		}
+		c.Set(ctx, "k", []byte("w"), 0)
+		if got, _ := c.Get(ctx, "k"); string(got) != "w" {
+			t.Errorf("expected w, got %q", got)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c := newCache(t)
+		c.Set(ctx, "k", []byte("v"), 20*time.Millisecond)
+		if _, err := c.Get(ctx, "k"); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		time.Sleep(40 * time.Millisecond)
+		if _, err := c.Get(ctx, "k"); errors.Cause(err) != cache.ErrMiss {
+			t.Errorf("expected %v, got %v", cache.ErrMiss, err)
+		}
+	})
+
+	t.Run("incr", func(t *testing.T) {
+		c := newCache(t)
+		for want := int64(1); want <= 2; want++ {
+			if n, err := c.Incr(ctx, "n"); err != nil || n != want {
+				t.Errorf("expected %v, got %v (%v)", want, n, err)
+			}
+		}
+		if got, _ := c.Get(ctx, "n"); string(got) != "2" {
+			t.Errorf("expected 2, got %q", got)
+		}
+		c.Set(ctx, "k", []byte("v"), 0)
+		if _, err := c.Incr(ctx, "k"); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package cachetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a stand-in Redis server knowing just the commands the Redis
+// cache sends: PING, AUTH, SELECT, GET, SET with PX and INCR. It keeps
+// everything in memory and ignores the selected database.
+type Server struct {
+	Addr string
+
+	ln     net.Listener
+	mu     sync.Mutex
+	values map[string]value
+	calls  map[string]int
+}
+
+type value struct {
+	data    string
+	expires time.Time
+}
+
+// NewServer starts Server on a random local port, stopped once t is done.
+func NewServer(t *testing.T) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	s := &Server{
+		Addr:   ln.Addr().String(),
+		ln:     ln,
+		values: make(map[string]value),
+		calls:  make(map[string]int),
+	}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+// Calls returns how many times command was received, e.g: GET.
+func (s *Server) Calls(command string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[command]
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd := strings.ToUpper(args[0])
+	s.calls[cmd]++
+	switch {
+	case cmd == "PING":
+		return "+PONG\r\n"
+	case (cmd == "AUTH" || cmd == "SELECT") && len(args) == 2:
+		return "+OK\r\n"
+	case cmd == "GET" && len(args) == 2:
+		v, ok := s.get(args[1])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v.data), v.data)
+	case cmd == "SET" && len(args) == 3:
+		s.values[args[1]] = value{data: args[2]}
+		return "+OK\r\n"
+	case cmd == "SET" && len(args) == 5 && strings.ToUpper(args[3]) == "PX":
+		ms, err := strconv.Atoi(args[4])
+		if err != nil || ms <= 0 {
+			return "-ERR invalid expire time in 'set' command\r\n"
+		}
+		s.values[args[1]] = value{data: args[2], expires: time.Now().Add(time.Duration(ms) * time.Millisecond)}
+		return "+OK\r\n"
+	case cmd == "INCR" && len(args) == 2:
+		v, _ := s.get(args[1])
+		n := int64(0)
+		if v.data != "" {
+			var err error
+			if n, err = strconv.ParseInt(v.data, 10, 64); err != nil {
+				return "-ERR value is not an integer or out of range\r\n"
+			}
+		}
+		n++
+		v.data = strconv.FormatInt(n, 10)
+		s.values[args[1]] = v
+		return fmt.Sprintf(":%d\r\n", n)
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+func (s *Server) get(key string) (value, bool) {
+	v, ok := s.values[key]
+	if ok && !v.expires.IsZero() && !time.Now().Before(v.expires) {
+		delete(s.values, key)
+		return value{}, false
+	}
+	return v, ok
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("empty command")
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) < 2 || line[0] != prefix {
+		return 0, fmt.Errorf("expected %c, got %q", prefix, line)
+	}
+	return strconv.Atoi(line[1:])
+}
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrProtocol is returned when the server reply doesn't follow the Redis
+// protocol.
+var ErrProtocol = errors.New("malformed redis reply")
+
+// RedisError is an error replied by the server, e.g: "ERR unknown command".
+type RedisError string
+
+func (e RedisError) Error() string { return "redis: " + string(e) }
+
+// RedisOptions tunes the connections to the server.
+type RedisOptions struct {
+	Password string
+	DB       int
+
+	// Timeout bounds dialing and every command unless ctx has a sooner
+	// deadline, 1s by default.
+	Timeout time.Duration
+
+	// MaxIdle is how many idle connections are kept for reuse, 4 by default.
+	MaxIdle int
+}
+
+// Redis is a Cache kept by a server speaking the Redis protocol, so that
+// all the nodes share it.
+type Redis struct {
+	addr string
+	opts RedisOptions
+	idle chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedis returns Redis cache of the server at addr, e.g: localhost:6379.
+// Connections are dialed on first use.
+func NewRedis(addr string, opts RedisOptions) *Redis {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 4
+	}
+	return &Redis{addr: addr, opts: opts, idle: make(chan *redisConn, opts.MaxIdle)}
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return nil, ErrMiss
+	case []byte:
+		return v, nil
+	}
+	return nil, ErrProtocol
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms == 0 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+func (c *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := c.do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, ErrProtocol
+	}
+	return n, nil
+}
+
+// Eval runs the Lua script on the server with keys and args, and returns
+// its reply. Integers are int64, strings []byte and nil is nil, tables
+// aren't supported.
+func (c *Redis) Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	cmd := append([]string{"EVAL", script, strconv.Itoa(len(keys))}, keys...)
+	return c.do(ctx, append(cmd, args...)...)
+}
+
+// Ping checks the server is reachable.
+func (c *Redis) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections.
+func (c *Redis) Close() error {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends the command args and returns its reply. Errors replied by the
+// server are returned as RedisError, the connection stays usable then.
+func (c *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(c.deadline(ctx), args...)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "redis "+args[0])
+	}
+	c.put(conn)
+	if rerr, ok := reply.(RedisError); ok {
+		return nil, rerr
+	}
+	return reply, nil
+}
+
+func (c *Redis) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// conn returns an idle connection or dials a new one.
+func (c *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+	d := net.Dialer{Deadline: c.deadline(ctx)}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "redis dial")
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	setup := make([][]string, 0, 2)
+	if c.opts.Password != "" {
+		setup = append(setup, []string{"AUTH", c.opts.Password})
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	for _, args := range setup {
+		reply, err := conn.do(c.deadline(ctx), args...)
+		if rerr, ok := reply.(RedisError); ok {
+			err = rerr
+		}
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "redis "+args[0])
+		}
+	}
+	return conn, nil
+}
+
+// put keeps conn for reuse unless there are enough idle ones already.
+func (c *Redis) put(conn *redisConn) {
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (conn *redisConn) do(deadline time.Time, args ...string) (interface{}, error) {
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(conn.r)
+}
+
+// readReply reads a reply of the Redis protocol. Arrays are never replied
+// by the commands used, so they are not supported.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, ErrProtocol
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return RedisError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+	return nil, ErrProtocol
+}
@@ -0,0 +1,97 @@
+// config loads the configuration of a command in layers: defaults, then a
+// YAML or TOML file, then env vars, then flags. Later layers override the
+// earlier ones.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+var ErrUnknownFormat = errors.New("unknown config file format, want .yaml, .yml or .toml")
+
+// Load decodes the YAML or TOML file at path, by its extension, into cfg.
+// Settings missing from the file keep their value in cfg. Unknown ones fail
+// the load, they are likely typos.
+func Load(path string, cfg interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, cfg)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(b), cfg)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = errors.Errorf("unknown settings %v", md.Undecoded())
+		}
+	default:
+		err = ErrUnknownFormat
+	}
+	return errors.Wrap(err, path)
+}
+
+// Marshal returns cfg as YAML, e.g: to print it. Redact its secrets
+// first.
+func Marshal(cfg interface{}) ([]byte, error) {
+	return yaml.Marshal(cfg)
+}
+
+// Duration is a time.Duration written as in flags, e.g: 1m30s, both in
+// YAML and TOML.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Problems collects what is wrong with a config, to report all of them at
+// once.
+type Problems []string
+
+// Check adds the problem formatted with args unless ok.
+func (p *Problems) Check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		*p = append(*p, fmt.Sprintf(format, args...))
+	}
+}
+
+// Err returns the error of the problems, nil if there is none.
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return errors.New("invalid config:\n  " + strings.Join(p, "\n  "))
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"flag"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Redacted replaces secrets when the config is printed.
+const Redacted = "REDACTED"
+
+// Setting binds a field of the config to its flag and env var.
+type Setting struct {
+	Flag  string // e.g: db-source
+	Env   string // e.g: DB_SOURCE
+	Usage string
+	// Value sets the field, see String, Int, Float, Bool, DurationOf and List.
+	Value flag.Value
+	// Redact returns the value to print instead of the secret value, nil
+	// if the setting is not a secret. See Secret and SecretSource.
+	Redact func(value string) string
+}
+
+// Register registers the flags of settings on fs. They are parsed into
+// their own values, Apply applies the ones given over the file and env
+// vars.
+func Register(fs *flag.FlagSet, settings []Setting) {
+	for _, s := range settings {
+		usage := s.Usage
+		if s.Env != "" {
+			usage += " (env " + s.Env + ")"
+		}
+		fs.Var(s.Value, s.Flag, usage)
+	}
+}
+
+// Apply sets settings from the env vars lookup finds, then from the flags
+// given on fs, parsed already. Every value that doesn't parse is reported.
+func Apply(fs *flag.FlagSet, settings []Setting, lookup func(key string) (string, bool)) error {
+	var p Problems
+	byFlag := make(map[string]Setting)
+	for _, s := range settings {
+		byFlag[s.Flag] = s
+		if s.Env == "" {
+			continue
+		}
+		if v, ok := lookup(s.Env); ok {
+			if err := s.Value.Set(v); err != nil {
+				p.Check(false, "env %s: %v", s.Env, err)
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byFlag[f.Name]
+		if !ok {
+			return
+		}
+		if err := s.Value.Set(f.Value.String()); err != nil {
+			p.Check(false, "flag -%s: %v", f.Name, err)
+		}
+	})
+	return p.Err()
+}
+
+// Redact replaces the secrets of settings, e.g: before printing them.
+func Redact(settings []Setting) {
+	for _, s := range settings {
+		if s.Redact != nil {
+			s.Value.Set(s.Redact(s.Value.String()))
+		}
+	}
+}
+
+// Secret redacts the whole value, unless it is empty.
+func Secret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return Redacted
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// SecretSource redacts the password of database sources, both the
+// key=value and the URL forms. e.g: user=bob password=secret.
+func SecretSource(value string) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+			return u.String()
+		}
+		return value
+	}
+	return dsnPassword.ReplaceAllString(value, "${1}"+Redacted)
+}
+
+type stringValue struct{ p *string }
+
+// String returns Value setting *p.
+func String(p *string) flag.Value { return stringValue{p} }
+
+func (v stringValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+type intValue struct{ p *int }
+
+// Int returns Value setting *p.
+func Int(p *int) flag.Value { return intValue{p} }
+
+func (v intValue) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.p)
+}
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.Errorf("%q is not an integer", s)
+	}
+	*v.p = n
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+// Bool returns Value setting *p. The flag is given without value.
+func Bool(p *bool) flag.Value { return boolValue{p} }
+
+func (v boolValue) IsBoolFlag() bool { return true }
+
+func (v boolValue) String() string {
+	if v.p == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*v.p)
+}
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.Errorf("%q is not a boolean", s)
+	}
+	*v.p = b
+	return nil
+}
+
+type durationValue struct{ p *Duration }
+
+// DurationOf returns Value setting *p, e.g: from 1m30s.
+func DurationOf(p *Duration) flag.Value { return durationValue{p} }
+
+func (v durationValue) String() string {
+	if v.p == nil {
+		return "0s"
+	}
+	return v.p.String()
+}
+
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Errorf("%q is not a duration, e.g: 1m30s", s)
+	}
+	v.p.Duration = d
+	return nil
+}
+
+type listValue struct{ p *[]string }
+
+// List returns Value setting *p from a comma separated list, ignoring
+// empty items.
+func List(p *[]string) flag.Value { return listValue{p} }
+
+func (v listValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strings.Join(*v.p, ",")
+}
+
+func (v listValue) Set(s string) error {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v.p = items
+	return nil
+}
+
+type floatValue struct{ p *float64 }
+
+// Float returns Value setting *p.
+func Float(p *float64) flag.Value { return floatValue{p} }
+
+func (v floatValue) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*v.p, 'g', -1, 64)
+}
+
+func (v floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.Errorf("%q is not a number", s)
+	}
+	*v.p = f
+	return nil
+}
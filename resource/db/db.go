@@ -0,0 +1,393 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Supported SQL dialects, named after their database/sql drivers.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite3"
+)
+
+// PoolOptions tunes the connection pool shared by all the repos, and the
+// pools of the replicas. Zero values keep the database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ReplicaCheckInterval is how often replicas are pinged to find out
+	// whether they are healthy, 10s by default.
+	ReplicaCheckInterval time.Duration
+}
+
+// DB is the database handle shared by all the repos, so that the whole
+// process uses a single connection pool per database.
+type DB struct {
+	gorm     *gorm.DB
+	replicas []*replica
+	next     uint32 // round-robin of replicas
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+type replica struct {
+	gorm    *gorm.DB
+	healthy int32 // atomic, 1 when healthy
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *replica) setHealthy(ok bool) {
+	v := int32(0)
+	if ok {
+		v = 1
+	}
+	atomic.StoreInt32(&r.healthy, v)
+}
+
+// Open connects to the database and tunes its pool with opts.
+// Driver is postgres or sqlite3, for sqlite3 source is the database file.
+// Reads done with Read go to the replicas, if any. A replica down at
+// start doesn't fail Open, it is used once its health check passes.
+func Open(driver, source string, opts PoolOptions, replicas ...string) (*DB, error) {
+	g, err := open(driver, source, opts)
+	if err != nil {
+		return nil, err
+	}
+	d := &DB{gorm: g, done: make(chan struct{})}
+	for _, rs := range replicas {
+		r, err := openReplica(driver, rs, opts)
+		if err != nil {
+			d.Close()
+			return nil, err
+		}
+		d.replicas = append(d.replicas, r)
+	}
+	if len(d.replicas) > 0 {
+		interval := opts.ReplicaCheckInterval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		d.wg.Add(1)
+		go d.checkReplicas(interval)
+	}
+	return d, nil
+}
+
+func open(driver, source string, opts PoolOptions) (*gorm.DB, error) {
+	if driver == SQLite {
+		source = sqliteSource(source)
+	}
+	g, err := gorm.Open(driver, source)
+	if err != nil {
+		return nil, err
+	}
+	tune(g.DB(), opts)
+	return g, nil
+}
+
+// openReplica opens the pool of a replica without requiring it to be up.
+func openReplica(driver, source string, opts PoolOptions) (*replica, error) {
+	if driver == SQLite {
+		source = sqliteSource(source)
+	}
+	pool, err := sql.Open(driver, source)
+	if err != nil {
+		return nil, err
+	}
+	tune(pool, opts)
+	// Open pings the replica, a failing ping only marks it unhealthy.
+	g, err := gorm.Open(driver, pool)
+	if g == nil {
+		pool.Close()
+		return nil, err
+	}
+	r := &replica{gorm: g}
+	r.setHealthy(err == nil)
+	return r, nil
+}
+
+func tune(pool *sql.DB, opts PoolOptions) {
+	if opts.MaxOpenConns > 0 {
+		pool.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		pool.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		pool.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		pool.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+}
+
+// checkReplicas pings the replicas every interval until Close.
+func (d *DB) checkReplicas(interval time.Duration) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+		for _, r := range d.replicas {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			r.setHealthy(r.gorm.DB().PingContext(ctx) == nil)
+			cancel()
+		}
+	}
+}
+
+// Dialect returns the SQL dialect of the database, Postgres or SQLite.
+func (d *DB) Dialect() string {
+	return d.gorm.Dialect().GetName()
+}
+
+// SQL returns the underlying pool e.g: to run migrations.
+func (d *DB) SQL() *sql.DB {
+	return d.gorm.DB()
+}
+
+// Ping checks the primary is reachable.
+func (d *DB) Ping(ctx context.Context) error {
+	return d.gorm.DB().PingContext(ctx)
+}
+
+// CheckReplicas fails if there are replicas but none of them is healthy,
+// the primary serves all the reads then.
+func (d *DB) CheckReplicas(ctx context.Context) error {
+	if len(d.replicas) == 0 {
+		return nil
+	}
+	for _, r := range d.replicas {
+		if r.isHealthy() {
+			return nil
+		}
+	}
+	return ErrNoHealthyReplica
+}
+
+// Close closes the pools.
+func (d *DB) Close() error {
+	close(d.done)
+	d.wg.Wait()
+	for _, r := range d.replicas {
+		r.gorm.Close()
+	}
+	return d.gorm.Close()
+}
+
+// Conn returns a fresh query on the primary to run in ctx. It belongs to
+// the transaction of the unit of work ctx is part of, if any.
+// Conn is meant for writes, so the later reads of the session of ctx go to
+// the primary too, see WithSession.
+func (d *DB) Conn(ctx context.Context) *gorm.DB {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		atomic.StoreInt32(&s.wrote, 1)
+	}
+	return d.primary(ctx)
+}
+
+func (d *DB) primary(ctx context.Context) *gorm.DB {
+	if t, ok := ctx.Value(txKey{}).(*transaction); ok {
+		return withContext(ctx, t.gorm.New())
+	}
+	return withContext(ctx, d.gorm.New())
+}
+
+// pingTimeout bounds the ping of a replica a read failed on.
+const pingTimeout = 2 * time.Second
+
+// Read runs the read only query fn on a healthy replica, picked
+// round-robin. Fn runs on the primary instead if there is no healthy
+// replica, ctx is in a unit of work, its session has written or it is from
+// ReadPrimary, and again on the primary if it fails on the replica, unless
+// ctx is done. Replica failing a ping then is left out until its health
+// check passes.
+func (d *DB) Read(ctx context.Context, fn func(q *gorm.DB) error) error {
+	r := d.replica(ctx)
+	if r == nil {
+		return fn(d.primary(ctx))
+	}
+	err := fn(withContext(ctx, r.gorm.New()))
+	if err == nil || err == gorm.ErrRecordNotFound || ctx.Err() != nil {
+		return err
+	}
+	// The ping doesn't use ctx: a request timing out tells nothing about
+	// the replica.
+	pctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if perr := r.gorm.DB().PingContext(pctx); perr != nil {
+		r.setHealthy(false)
+	}
+	return fn(d.primary(ctx))
+}
+
+func (d *DB) replica(ctx context.Context) *replica {
+	if len(d.replicas) == 0 {
+		return nil
+	}
+	if _, ok := ctx.Value(txKey{}).(*transaction); ok {
+		return nil
+	}
+	if ctx.Value(primaryKey{}) != nil {
+		return nil
+	}
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok && atomic.LoadInt32(&s.wrote) == 1 {
+		return nil
+	}
+	n := atomic.AddUint32(&d.next, 1)
+	for i := range d.replicas {
+		r := d.replicas[(int(n)+i)%len(d.replicas)]
+		if r.isHealthy() {
+			return r
+		}
+	}
+	return nil
+}
+
+type session struct {
+	wrote int32 // atomic, 1 once written
+}
+
+type sessionKey struct{}
+
+// WithSession returns ctx of a new session, e.g: of a request. Once the
+// session writes, its reads go to the primary so that they see the writes
+// despite replication lag.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+type primaryKey struct{}
+
+// ReadPrimary returns ctx whose reads go to the primary, e.g: to fill a
+// cache that mustn't keep results older than the last write.
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UnitOfWork runs several repo operations atomically.
+type UnitOfWork interface {
+	// Do runs fn in a transaction carried by the ctx given to fn. Repo
+	// calls made with that ctx are committed together if fn returns nil,
+	// and rolled back otherwise. Nested Do joins the outer transaction.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// transaction of a unit of work, with the funcs to call once it commits.
+type transaction struct {
+	gorm *gorm.DB
+
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+// AfterCommit calls fn once the unit of work ctx is part of commits, e.g:
+// to invalidate a cache only when the write is visible to the others. Fn
+// isn't called if the unit of work rolls back. Outside of units of work fn
+// is called right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	t, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok {
+		fn()
+		return
+	}
+	t.mu.Lock()
+	t.afterCommit = append(t.afterCommit, fn)
+	t.mu.Unlock()
+}
+
+// Do implements UnitOfWork. It also rolls back if fn panics.
+func (d *DB) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*transaction); ok {
+		return fn(ctx)
+	}
+
+	// gorm has no BeginTx, the transaction begun in ctx is wrapped instead,
+	// so that it is rolled back if ctx is done before it commits.
+	sqlTx, err := d.gorm.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx, err := gorm.Open(d.Dialect(), sqlTx)
+	if err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	t := &transaction{gorm: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, t)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	for _, f := range t.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// NoTx is a UnitOfWork for repos without transactions e.g: in memory
+// ones. It just runs fn.
+var NoTx UnitOfWork = noTx{}
+
+type noTx struct{}
+
+func (noTx) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// sqliteSource adds the connection options every sqlite connection of the
+// pool needs, unless source sets them already: foreign keys are off by
+// default, WAL lets readers go on during writes, and taking the write lock
+// on begin makes concurrent transactions wait for each other instead of
+// failing with "database is locked".
+func sqliteSource(source string) string {
+	path, query := source, ""
+	if i := strings.IndexByte(source, '?'); i >= 0 {
+		path, query = source[:i], source[i+1:]
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return source
+	}
+	defaults := []struct{ key, value string }{
+		{"_foreign_keys", "1"},
+		{"_journal_mode", "WAL"},
+		{"_busy_timeout", "5000"},
+		{"_txlock", "immediate"},
+	}
+	for _, d := range defaults {
+		if params.Get(d.key) == "" {
+			params.Set(d.key, d.value)
+		}
+	}
+	return path + "?" + params.Encode()
+}
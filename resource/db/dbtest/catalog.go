@@ -0,0 +1,192 @@
+package dbtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+// CatalogRepo runs the catalog.Repo conformance tests. newRepo is called
+// for every test and the repo is dropped once the test is done.
+func CatalogRepo(t *testing.T, newRepo func(t *testing.T) catalog.Repo) {
+	setup := func(t *testing.T) catalog.Repo {
+		repo := newRepo(t)
+		if err := repo.Drop(ctx); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		books := []catalog.Book{
+			{Title: "The Go Programming Language", ISBN: "978-0134190440", Price: money.New(3499, money.USD),
+				Authors: []catalog.Author{{ID: "donovan", FirstName: "Alan"}, {ID: "kernighan", FirstName: "Brian"}},
+				Prices:  []catalog.BookPrice{{Price: money.New(3299, money.EUR)}}},
+			{Title: "The C Programming Language", ISBN: "978-0131103627", Price: money.New(4999, money.USD)},
+			{Title: "Dune", ISBN: "978-0441172719", Price: money.New(999, money.USD), Format: catalog.Ebook},
+		}
+		for i := range books {
+			if err := repo.Create(ctx, &books[i]); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+		return repo
+	}
+
+	t.Run("create", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		b := catalog.Book{Title: "Go in Action", Price: money.New(2999, money.USD)}
+		if err := repo.Create(ctx, &b); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if b.ID == "" {
+			t.Errorf("expected non-empty, got empty ID")
+		}
+		if err := repo.Create(ctx, &b); errors.Cause(err) != db.ErrAlreadyExists {
+			t.Errorf("expected %v, got %v", db.ErrAlreadyExists, err)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		if _, err := repo.GetByID(ctx, "1"); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+		b, err := repo.GetByISBN(ctx, "978-0134190440")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if b.Price.String() != "34.99 USD" {
+			t.Errorf("expected 34.99 USD, got %v", b.Price)
+		}
+		if len(b.Prices) != 1 || b.Prices[0].ID == "" || b.Prices[0].Price.String() != "32.99 EUR" {
+			t.Errorf("expected single stored 32.99 EUR price, got %v", b.Prices)
+		}
+		got, err := repo.GetByID(ctx, b.ID)
+		if err != nil || got.Title != b.Title {
+			t.Errorf("expected %v, got %v (%v)", b.Title, got.Title, err)
+		}
+	})
+
+	t.Run("save", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		if err := repo.Save(ctx, &catalog.Book{}); errors.Cause(err) != db.ErrMissingID {
+			t.Errorf("expected %v, got %v", db.ErrMissingID, err)
+		}
+		b, _ := repo.GetByISBN(ctx, "978-0441172719")
+		created := b.UpdatedAt
+		if created.IsZero() {
+			t.Errorf("expected update time set on create, got zero")
+		}
+		b.Title = "Dune Messiah"
+		if err := repo.Save(ctx, &b); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		got, _ := repo.GetByID(ctx, b.ID)
+		if got.Title != "Dune Messiah" || got.Format != catalog.Ebook {
+			t.Errorf("expected ebook Dune Messiah, got %v %v", got.Format, got.Title)
+		}
+		if got.UpdatedAt.Before(created) {
+			t.Errorf("expected update time after %v, got %v", created, got.UpdatedAt)
+		}
+
+		stale := got
+		stale.Version--
+		stale.Title = "Children of Dune"
+		if err := repo.Save(ctx, &stale); errors.Cause(err) != db.ErrConflict {
+			t.Errorf("expected %v, got %v", db.ErrConflict, err)
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		books, err := repo.Search(ctx, "programming")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(books) != 2 {
+			t.Errorf("expected 2 books, got %v", len(books))
+		}
+	})
+
+	t.Run("list by author", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		books, err := repo.ListByAuthor(ctx, "kernighan")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(books) != 1 || books[0].ISBN != "978-0134190440" {
+			t.Errorf("expected The Go Programming Language, got %v", books)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		books, total, err := repo.List(ctx, "title", 2, 1)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if total != 3 {
+			t.Errorf("expected total 3, got %v", total)
+		}
+		if len(books) != 2 || books[0].Title != "The C Programming Language" {
+			t.Errorf("expected The C Programming Language first, got %v", books)
+		}
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		b, _ := repo.GetByISBN(ctx, "978-0441172719")
+		if err := repo.Delete(ctx, b.ID, b.Version-1); errors.Cause(err) != db.ErrConflict {
+			t.Errorf("expected %v, got %v", db.ErrConflict, err)
+		}
+		if err := repo.Delete(ctx, b.ID, b.Version); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := repo.Delete(ctx, b.ID, b.Version+1); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+		if _, err := repo.GetByID(ctx, b.ID); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+		if books, _ := repo.Search(ctx, "dune"); len(books) != 0 {
+			t.Errorf("expected no books, got %v", books)
+		}
+		if _, total, _ := repo.List(ctx, "", 10, 0); total != 2 {
+			t.Errorf("expected 2 books, got %v", total)
+		}
+		deleted, err := repo.ListDeleted(ctx)
+		if err != nil || len(deleted) != 1 || deleted[0].DeletedAt == nil {
+			t.Fatalf("expected deleted Dune, got %v (%v)", deleted, err)
+		}
+
+		if err := repo.Restore(ctx, b.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		got, err := repo.GetByID(ctx, b.ID)
+		if err != nil || got.DeletedAt != nil || got.Version != b.Version+2 {
+			t.Errorf("expected restored book of version %v, got %v (%v)", b.Version+2, got, err)
+		}
+		if err := repo.Restore(ctx, b.ID); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+
+		repo.Delete(ctx, b.ID, got.Version)
+		if n, err := repo.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+			t.Errorf("expected nothing purged, got %v (%v)", n, err)
+		}
+		if n, err := repo.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+			t.Errorf("expected 1 purged, got %v (%v)", n, err)
+		}
+		if deleted, _ := repo.ListDeleted(ctx); len(deleted) != 0 {
+			t.Errorf("expected no deleted books, got %v", deleted)
+		}
+	})
+}
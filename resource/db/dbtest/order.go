@@ -0,0 +1,150 @@
+package dbtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/order"
+	"github.com/pkg/errors"
+)
+
+// OrderRepo runs the order.Repo conformance tests. newRepo is called for
+// every test and the repo is dropped once the test is done.
+func OrderRepo(t *testing.T, newRepo func(t *testing.T) order.Repo) {
+	setup := func(t *testing.T) order.Repo {
+		repo := newRepo(t)
+		if err := repo.Drop(ctx); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		return repo
+	}
+	newOrder := func(userID string) order.Order {
+		o := order.Order{
+			CreatedByID: userID,
+			Lines: []order.Line{
+				{BookID: "go", Title: "The Go Programming Language", Quantity: 2, UnitPrice: money.New(3499, money.USD)},
+			},
+			Adjustments: []order.Adjustment{
+				{Description: "10% off", Amount: money.New(-700, money.USD)},
+			},
+			TotalPrice: money.Zero(money.USD),
+		}
+		o.UpdateTotal()
+		return o
+	}
+
+	t.Run("create", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		o := newOrder("ross")
+		if err := repo.Create(ctx, &o); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if o.ID == "" || o.Lines[0].ID == "" || o.Adjustments[0].ID == "" {
+			t.Errorf("expected IDs of order and its parts, got %v", o)
+		}
+		if err := repo.Create(ctx, &o); errors.Cause(err) != db.ErrAlreadyExists {
+			t.Errorf("expected %v, got %v", db.ErrAlreadyExists, err)
+		}
+
+		got, err := repo.GetByID(ctx, o.ID)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(got.Lines) != 1 || len(got.Adjustments) != 1 {
+			t.Errorf("expected 1 line and 1 adjustment, got %v", got)
+		}
+		if got.TotalPrice.String() != "62.98 USD" {
+			t.Errorf("expected 62.98 USD, got %v", got.TotalPrice)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		if _, err := repo.GetByID(ctx, "1"); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+	})
+
+	t.Run("save", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		o := newOrder("ross")
+		if err := repo.Save(ctx, &o); errors.Cause(err) != db.ErrMissingID {
+			t.Errorf("expected %v, got %v", db.ErrMissingID, err)
+		}
+		repo.Create(ctx, &o)
+		o.Coupon = "SUMMER"
+		if err := repo.Save(ctx, &o); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		got, _ := repo.GetByID(ctx, o.ID)
+		if got.Coupon != "SUMMER" {
+			t.Errorf("expected SUMMER, got %v", got.Coupon)
+		}
+
+		stale := got
+		stale.Version--
+		stale.Coupon = "WINTER"
+		if err := repo.Save(ctx, &stale); errors.Cause(err) != db.ErrConflict {
+			t.Errorf("expected %v, got %v", db.ErrConflict, err)
+		}
+	})
+
+	t.Run("list by user", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		for _, u := range []string{"ross", "rachel", "ross"} {
+			o := newOrder(u)
+			if err := repo.Create(ctx, &o); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+		orders, err := repo.ListByUser(ctx, "ross")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(orders) != 2 {
+			t.Errorf("expected 2 orders, got %v", len(orders))
+		}
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		o := newOrder("ross")
+		repo.Create(ctx, &o)
+		if err := repo.Delete(ctx, o.ID, o.Version+1); errors.Cause(err) != db.ErrConflict {
+			t.Errorf("expected %v, got %v", db.ErrConflict, err)
+		}
+		if err := repo.Delete(ctx, o.ID, o.Version); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if orders, _ := repo.ListByUser(ctx, "ross"); len(orders) != 0 {
+			t.Errorf("expected no orders, got %v", orders)
+		}
+		deleted, _ := repo.ListDeleted(ctx)
+		if len(deleted) != 1 || len(deleted[0].Lines) != 1 {
+			t.Fatalf("expected deleted order with its line, got %v", deleted)
+		}
+
+		if err := repo.Restore(ctx, o.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		restored, err := repo.GetByID(ctx, o.ID)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+
+		repo.Delete(ctx, o.ID, restored.Version)
+		if n, err := repo.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+			t.Errorf("expected 1 purged, got %v (%v)", n, err)
+		}
+		if _, err := repo.GetByID(ctx, o.ID); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package dbtest
+
+import (
+	"testing"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/pkg/errors"
+)
+
+// PromotionRepo runs the promotion.Repo conformance tests. newRepo is
+// called for every test and the repo is dropped once the test is done.
+func PromotionRepo(t *testing.T, newRepo func(t *testing.T) promotion.Repo) {
+	setup := func(t *testing.T) promotion.Repo {
+		repo := newRepo(t)
+		if err := repo.Drop(ctx); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		promos := []promotion.Promotion{
+			{ID: "b", Name: "10%", Kind: promotion.Percentage, Percent: 10, Priority: 2, Active: true},
+			{ID: "a", Name: "20%", Kind: promotion.Percentage, Percent: 20, Priority: 2, Active: true},
+			{ID: "c", Name: "summer", Kind: promotion.Percentage, Percent: 30, Priority: 1, Active: true, Coupon: "SUMMER", UsageLimit: 1},
+			{ID: "d", Name: "old", Kind: promotion.Percentage, Percent: 50},
+		}
+		for i := range promos {
+			if err := repo.Create(ctx, &promos[i]); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+		return repo
+	}
+	ids := func(promos []promotion.Promotion) string {
+		s := ""
+		for _, p := range promos {
+			s += p.ID
+		}
+		return s
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		if err := repo.Create(ctx, &promotion.Promotion{ID: "a"}); errors.Cause(err) != db.ErrAlreadyExists {
+			t.Errorf("expected %v, got %v", db.ErrAlreadyExists, err)
+		}
+		if _, err := repo.GetByID(ctx, "x"); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+		p, err := repo.GetByID(ctx, "c")
+		if err != nil || p.Coupon != "SUMMER" {
+			t.Errorf("expected SUMMER, got %v (%v)", p.Coupon, err)
+		}
+	})
+
+	t.Run("list by priority", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		promos, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if got := ids(promos); got != "dcab" {
+			t.Errorf("expected dcab, got %v", got)
+		}
+	})
+
+	t.Run("list applicable", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		promos, _ := repo.ListApplicable(ctx, "")
+		if got := ids(promos); got != "ab" {
+			t.Errorf("expected ab, got %v", got)
+		}
+		promos, _ = repo.ListApplicable(ctx, "SUMMER")
+		if got := ids(promos); got != "cab" {
+			t.Errorf("expected cab, got %v", got)
+		}
+	})
+
+	t.Run("redeem", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		if err := repo.Redeem(ctx, "c"); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := repo.Redeem(ctx, "c"); errors.Cause(err) != promotion.ErrCouponExhausted {
+			t.Errorf("expected %v, got %v", promotion.ErrCouponExhausted, err)
+		}
+		p, _ := repo.GetByID(ctx, "c")
+		if p.Used != 1 {
+			t.Errorf("expected used 1, got %v", p.Used)
+		}
+	})
+}
@@ -0,0 +1,383 @@
+// dbtest is the conformance test suite of the repos. Every repo
+// implementation runs it, so that they all behave the same.
+package dbtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/user"
+	"github.com/pkg/errors"
+)
+
+var ctx = context.Background()
+
+// UserRepo runs the user.Repo conformance tests. newRepo is called for
+// every test and the repo is dropped once the test is done.
+func UserRepo(t *testing.T, newRepo func(t *testing.T) user.Repo) {
+	setup := func(t *testing.T) user.Repo {
+		repo := newRepo(t)
+		if err := repo.Drop(ctx); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		return repo
+	}
+
+	t.Run("create", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		u := user.User{}
+		err := repo.Create(ctx, &u)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if u.ID == "" {
+			t.Errorf("expected non-empty, got empty ID")
+		}
+
+		// trying to create with the already existing
+		err = repo.Create(ctx, &u)
+		if errors.Cause(err) != db.ErrAlreadyExists {
+			t.Errorf("expected %v, got %v", db.ErrAlreadyExists, err)
+		}
+	})
+
+	t.Run("save", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+
+		t.Run("create via save", func(t *testing.T) {
+			u := user.User{}
+			err := repo.Save(ctx, &u)
+			if errors.Cause(err) != db.ErrMissingID {
+				t.Errorf("expected %v, got %v", db.ErrMissingID, err)
+			}
+		})
+
+		t.Run("update", func(t *testing.T) {
+			u := user.User{Email: "joey@golang.org"}
+			repo.Create(ctx, &u)
+			u.Email = "joey@bookshop.com"
+			if err := repo.Save(ctx, &u); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			got, _ := repo.GetByID(ctx, u.ID)
+			if got.Email != u.Email {
+				t.Errorf("expected %v, got %v", u.Email, got.Email)
+			}
+			if got.Version != 2 || u.Version != 2 {
+				t.Errorf("expected version 2, got %v and %v", got.Version, u.Version)
+			}
+		})
+
+		t.Run("stale version", func(t *testing.T) {
+			u := user.User{Email: "chandler@golang.org"}
+			repo.Create(ctx, &u)
+			stale := u
+			u.Email = "chandler@bookshop.com"
+			if err := repo.Save(ctx, &u); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			stale.Email = "bing@bookshop.com"
+			if err := repo.Save(ctx, &stale); errors.Cause(err) != db.ErrConflict {
+				t.Errorf("expected %v, got %v", db.ErrConflict, err)
+			}
+			got, _ := repo.GetByID(ctx, u.ID)
+			if got.Email != u.Email {
+				t.Errorf("expected %v, got %v", u.Email, got.Email)
+			}
+		})
+
+		t.Run("lockout", func(t *testing.T) {
+			u := user.User{Email: "ross@golang.org"}
+			repo.Create(ctx, &u)
+			until := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+			u.FailedLogins, u.LockedUntil = 5, &until
+			if err := repo.Save(ctx, &u); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			got, _ := repo.GetByID(ctx, u.ID)
+			if got.FailedLogins != 5 || got.LockedUntil == nil || !got.LockedUntil.Equal(until) {
+				t.Errorf("expected 5 failed logins locked until %v, got %v until %v", until, got.FailedLogins, got.LockedUntil)
+			}
+		})
+
+		t.Run("roles", func(t *testing.T) {
+			u := user.User{Email: "rachel@golang.org"}
+			repo.Create(ctx, &u)
+			u.Roles, u.PublisherID = auth.Roles{auth.Admin, auth.Publisher}, "penguin"
+			if err := repo.Save(ctx, &u); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			got, _ := repo.GetByID(ctx, u.ID)
+			if !got.Roles.Has(auth.Admin) || !got.Roles.Has(auth.Publisher) || got.PublisherID != "penguin" {
+				t.Errorf("expected admin,publisher of penguin, got %v of %v", got.Roles, got.PublisherID)
+			}
+		})
+
+		t.Run("missing", func(t *testing.T) {
+			u := user.User{ID: "missing", Version: 1}
+			if err := repo.Save(ctx, &u); errors.Cause(err) != db.ErrNotFound {
+				t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+			}
+		})
+	})
+
+	t.Run("failed logins", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		u := user.User{Email: "phoebe@golang.org"}
+		repo.Create(ctx, &u)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.FailLogin(ctx, u.ID); err != nil {
+					t.Errorf("expected nil error, got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if n, err := repo.FailLogin(ctx, u.ID); err != nil || n != 6 {
+			t.Errorf("expected 6 failed logins, got %v (%v)", n, err)
+		}
+		if _, err := repo.FailLogin(ctx, "missing"); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+
+		later := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		sooner := later.Add(-time.Minute)
+		for _, until := range []time.Time{later, sooner} {
+			if err := repo.LockOut(ctx, u.ID, until); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+		got, _ := repo.GetByID(ctx, u.ID)
+		if got.FailedLogins != 6 || got.LockedUntil == nil || !got.LockedUntil.Equal(later) {
+			t.Errorf("expected 6 failed logins locked until %v, got %v until %v", later, got.FailedLogins, got.LockedUntil)
+		}
+		if got.Version == u.Version {
+			t.Errorf("expected version bumped, got %v", got.Version)
+		}
+
+		if err := repo.ResetFailedLogins(ctx, u.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		reset, _ := repo.GetByID(ctx, u.ID)
+		if reset.FailedLogins != 0 || reset.LockedUntil != nil {
+			t.Errorf("expected no failed logins, got %v until %v", reset.FailedLogins, reset.LockedUntil)
+		}
+		if reset.Version == got.Version {
+			t.Errorf("expected version bumped, got %v", reset.Version)
+		}
+		if err := repo.ResetFailedLogins(ctx, "missing"); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		t.Run("invalid", func(t *testing.T) {
+			_, err := repo.GetByID(ctx, "1")
+			if errors.Cause(err) != db.ErrNotFound {
+				t.Errorf("expected NotFound, got %v", err)
+			}
+		})
+
+		var u user.User
+		repo.Create(ctx, &u) // no-need error check. Verified in create test
+		t.Run("valid", func(t *testing.T) {
+			_, err := repo.GetByID(ctx, u.ID)
+			if err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+		})
+	})
+
+	t.Run("get by email", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		t.Run("invalid", func(t *testing.T) {
+			_, err := repo.GetByEmail(ctx, "not-exists@golang.org")
+			if errors.Cause(err) != db.ErrNotFound {
+				t.Errorf("expected NotFound, got %v", err)
+			}
+		})
+
+		u := user.User{Email: "chandler@golang.org"}
+		repo.Create(ctx, &u) // no-need error check. Verified in create test
+		t.Run("valid", func(t *testing.T) {
+			_, err := repo.GetByEmail(ctx, "chandler@golang.org")
+			if err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+		})
+	})
+
+	t.Run("get by token", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		t.Run("invalid", func(t *testing.T) {
+			_, err := repo.GetByToken(ctx, "xxxxx")
+			if errors.Cause(err) != db.ErrNotFound {
+				t.Errorf("expected NotFound, got %v", err)
+			}
+		})
+		token := "xghfghfghfgh"
+		u := user.User{Email: "chandler@golang.org", AuthToken: token}
+		repo.Create(ctx, &u) // no-need error check. Verified in create test
+		t.Run("valid", func(t *testing.T) {
+			_, err := repo.GetByToken(ctx, token)
+			if err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+		})
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		users := []user.User{
+			{Email: "test1@bookshop.com", Username: "test1"},
+			{Email: "test2@bookshop.com", Username: "test2"},
+			{Email: "test3@bookshop.com", Username: "test3"},
+			{Email: "test4@bookshop.com", Username: "test4"},
+		}
+		for i := range users {
+			if err := repo.Create(ctx, &users[i]); err != nil {
+				t.Errorf("expected nil error, got %v\n", err)
+			}
+		}
+
+		t.Run("test limit", func(t *testing.T) {
+			us, total, err := repo.List(ctx, "", 2, 0)
+			if err != nil {
+				t.Errorf("expected nil error, got %v\n", err)
+			}
+			if total != 4 {
+				t.Errorf("expected total 4, got %v\n", total)
+			}
+			if len(us) != 2 {
+				t.Errorf("expected return length 2, got %v\n", len(us))
+			}
+		})
+		t.Run("test offset", func(t *testing.T) {
+			us, total, err := repo.List(ctx, "", 5, 1) // starting from offset 1
+
+			if err != nil {
+				t.Errorf("expected nil error, got %v\n", err)
+			}
+			if total != 4 {
+				t.Errorf("expected total 4, got %v\n", total)
+			}
+			if len(us) != 3 {
+				t.Errorf("expected return length 3, got %v\n", len(us))
+			}
+		})
+		t.Run("test ordering", func(t *testing.T) {
+			us, _, err := repo.List(ctx, "username", 3, 0)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v\n", err)
+			}
+			if us[0].Username != "test1" {
+				t.Errorf("ordering failed. expected test1, got %v\n", us[0].Username)
+			}
+			us, _, err = repo.List(ctx, "username desc", 3, 0)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v\n", err)
+			}
+			if us[0].Username != "test4" {
+				t.Errorf("ordering failed. expected test4, got %v\n", us[0].Username)
+			}
+		})
+	})
+
+	t.Run("address book", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		u := user.User{Email: "monica@golang.org"}
+		repo.Create(ctx, &u)
+
+		home := user.Address{UserID: u.ID, PostalAddress: user.PostalAddress{Name: "Home", Country: "US"}}
+		work := user.Address{UserID: u.ID, PostalAddress: user.PostalAddress{Name: "Work", Country: "US"}}
+		for _, a := range []*user.Address{&work, &home} {
+			if err := repo.CreateAddress(ctx, a); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+
+		addresses, err := repo.ListAddresses(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(addresses) != 2 || addresses[0].Name != "Home" {
+			t.Errorf("expected [Home Work], got %v", addresses)
+		}
+
+		home.City = "New York"
+		if err := repo.SaveAddress(ctx, &home); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		got, err := repo.GetAddress(ctx, home.ID)
+		if err != nil || got.City != "New York" {
+			t.Errorf("expected New York, got %v (%v)", got.City, err)
+		}
+
+		if err := repo.DeleteAddress(ctx, home.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if _, err := repo.GetAddress(ctx, home.ID); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		repo := setup(t)
+		defer repo.Drop(ctx)
+		u := user.User{Email: "monica@golang.org"}
+		repo.Create(ctx, &u)
+		a := user.Address{UserID: u.ID}
+		repo.CreateAddress(ctx, &a)
+		if err := repo.Delete(ctx, u.ID, u.Version+1); errors.Cause(err) != db.ErrConflict {
+			t.Errorf("expected %v, got %v", db.ErrConflict, err)
+		}
+		if err := repo.Delete(ctx, u.ID, u.Version); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if _, err := repo.GetByEmail(ctx, u.Email); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+		if _, total, _ := repo.List(ctx, "", 10, 0); total != 0 {
+			t.Errorf("expected no users, got %v", total)
+		}
+		if deleted, _ := repo.ListDeleted(ctx); len(deleted) != 1 || deleted[0].ID != u.ID {
+			t.Fatalf("expected deleted %v, got %v", u.ID, deleted)
+		}
+
+		if err := repo.Restore(ctx, u.ID); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		got, err := repo.GetByID(ctx, u.ID)
+		if err != nil || got.DeletedAt != nil {
+			t.Errorf("expected restored user, got %v (%v)", got, err)
+		}
+
+		repo.Delete(ctx, u.ID, got.Version)
+		if n, err := repo.Purge(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+			t.Errorf("expected 1 purged, got %v (%v)", n, err)
+		}
+		if _, err := repo.GetAddress(ctx, a.ID); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected address purged with the user, got %v", err)
+		}
+		if err := repo.Restore(ctx, u.ID); errors.Cause(err) != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+	})
+}
@@ -5,4 +5,17 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("entity already exists")
+	ErrMissingID     = errors.New("missing id")
+
+	// ErrConflict is returned by Save and Delete when the entity was changed
+	// by someone else since it was read, i.e: its version is stale.
+	ErrConflict = errors.New("entity changed concurrently")
+
+	// ErrPreconditionFailed is returned when the entity doesn't have the
+	// version the client expects, see WithIfMatch.
+	ErrPreconditionFailed = errors.New("entity version doesn't match")
+
+	// ErrNoHealthyReplica is returned by CheckReplicas when all the replicas
+	// are down.
+	ErrNoHealthyReplica = errors.New("no healthy replica")
 )
@@ -0,0 +1,253 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/pkg/errors"
+)
+
+type catalogRepo struct {
+	mu      sync.RWMutex
+	books   map[string]catalog.Book
+	deleted map[string]catalog.Book // soft deleted, by id
+	ids     []string                // insertion order
+}
+
+// NewCatalogRepo returns catalog.Repo safe for concurrent use.
+func NewCatalogRepo() catalog.Repo {
+	return &catalogRepo{
+		books:   make(map[string]catalog.Book),
+		deleted: make(map[string]catalog.Book),
+	}
+}
+
+// copyBook copies b so that callers can't change the stored book
+// through its slices.
+func copyBook(b catalog.Book) catalog.Book {
+	b.Authors = append([]catalog.Author(nil), b.Authors...)
+	b.Genres = append([]catalog.Genre(nil), b.Genres...)
+	b.Prices = append([]catalog.BookPrice(nil), b.Prices...)
+	if b.Publisher != nil {
+		p := *b.Publisher
+		b.Publisher = &p
+	}
+	return b
+}
+
+func (r *catalogRepo) filter(match func(b *catalog.Book) bool) []catalog.Book {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	books := make([]catalog.Book, 0)
+	for _, id := range r.ids {
+		b := r.books[id]
+		if match(&b) {
+			books = append(books, copyBook(b))
+		}
+	}
+	return books
+}
+
+func (r *catalogRepo) GetByID(_ context.Context, ID string) (catalog.Book, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.books[ID]
+	if !ok {
+		return catalog.Book{}, db.ErrNotFound
+	}
+	return copyBook(b), nil
+}
+
+func (r *catalogRepo) GetByISBN(_ context.Context, ISBN string) (catalog.Book, error) {
+	books := r.filter(func(b *catalog.Book) bool { return b.ISBN == ISBN })
+	if len(books) == 0 {
+		return catalog.Book{}, db.ErrNotFound
+	}
+	return books[0], nil
+}
+
+func (r *catalogRepo) ListByAuthor(_ context.Context, authorID string) ([]catalog.Book, error) {
+	return r.filter(func(b *catalog.Book) bool {
+		for _, a := range b.Authors {
+			if a.ID == authorID {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// Search matches title case insensitively, like ILIKE of postgres.
+func (r *catalogRepo) Search(_ context.Context, title string) ([]catalog.Book, error) {
+	title = strings.ToLower(title)
+	return r.filter(func(b *catalog.Book) bool {
+		return strings.Contains(strings.ToLower(b.Title), title)
+	}), nil
+}
+
+var bookLess = map[string]func(a, b *catalog.Book) bool{
+	"id":               func(a, b *catalog.Book) bool { return a.ID < b.ID },
+	"isbn":             func(a, b *catalog.Book) bool { return a.ISBN < b.ISBN },
+	"title":            func(a, b *catalog.Book) bool { return a.Title < b.Title },
+	"publication_year": func(a, b *catalog.Book) bool { return a.PublicationYear < b.PublicationYear },
+	"publication_date": func(a, b *catalog.Book) bool { return a.PublicationDate.Before(b.PublicationDate) },
+}
+
+func (r *catalogRepo) List(_ context.Context, order string, limit, offset int) ([]catalog.Book, int, error) {
+	field, desc, err := orderBy(order)
+	if err != nil {
+		return nil, 0, err
+	}
+	less, ok := bookLess[field]
+	if field != "" && !ok {
+		return nil, 0, errors.Wrap(ErrInvalidOrder, order)
+	}
+
+	books := r.filter(func(*catalog.Book) bool { return true })
+	if less != nil {
+		sort.SliceStable(books, func(i, j int) bool {
+			if desc {
+				return less(&books[j], &books[i])
+			}
+			return less(&books[i], &books[j])
+		})
+	}
+	start, end := page(len(books), limit, offset)
+	return books[start:end], len(books), nil
+}
+
+func (r *catalogRepo) Create(_ context.Context, b *catalog.Book) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b.ID == "" {
+		b.ID = newID()
+	}
+	if _, ok := r.books[b.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	if _, ok := r.deleted[b.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	b.Version = 1
+	b.UpdatedAt = time.Now()
+	newPriceIDs(b)
+	r.books[b.ID] = copyBook(*b)
+	r.ids = append(r.ids, b.ID)
+	return nil
+}
+
+func (r *catalogRepo) Save(_ context.Context, b *catalog.Book) error {
+	if b.ID == "" {
+		return db.ErrMissingID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.books[b.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if stored.Version != b.Version {
+		return db.ErrConflict
+	}
+	b.Version++
+	b.UpdatedAt = time.Now()
+	newPriceIDs(b)
+	r.books[b.ID] = copyBook(*b)
+	return nil
+}
+
+// newPriceIDs assigns IDs to the book prices that are not yet stored.
+func newPriceIDs(b *catalog.Book) {
+	for i := range b.Prices {
+		if b.Prices[i].ID == "" {
+			b.Prices[i].ID = newID()
+		}
+		b.Prices[i].BookID = b.ID
+	}
+}
+
+func (r *catalogRepo) Drop(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.books = make(map[string]catalog.Book)
+	r.deleted = make(map[string]catalog.Book)
+	r.ids = nil
+	return nil
+}
+
+// Delete moves the book to the deleted ones.
+func (r *catalogRepo) Delete(_ context.Context, id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.books[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if b.Version != version {
+		return db.ErrConflict
+	}
+	now := time.Now().UTC()
+	b.DeletedAt = &now
+	b.Version++
+	r.deleted[id] = b
+	delete(r.books, id)
+	r.ids = without(r.ids, id)
+	return nil
+}
+
+func (r *catalogRepo) ListDeleted(_ context.Context) ([]catalog.Book, error) {
+	r.mu.RLock()
+	books := make([]catalog.Book, 0, len(r.deleted))
+	for _, b := range r.deleted {
+		books = append(books, copyBook(b))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].DeletedAt.After(*books[j].DeletedAt)
+	})
+	return books, nil
+}
+
+// Restore moves the book back from the deleted ones, last in insertion order.
+func (r *catalogRepo) Restore(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.deleted[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	b.DeletedAt = nil
+	b.Version++
+	r.books[id] = b
+	delete(r.deleted, id)
+	r.ids = append(r.ids, id)
+	return nil
+}
+
+func (r *catalogRepo) Purge(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, b := range r.deleted {
+		if b.DeletedAt.Before(before) {
+			delete(r.deleted, id)
+			n++
+		}
+	}
+	return n, nil
+}
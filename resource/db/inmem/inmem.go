@@ -0,0 +1,59 @@
+// inmem implements the repos in memory. Useful for demos and tests,
+// nothing is persisted.
+package inmem
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/twinj/uuid"
+)
+
+var (
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+func newID() string {
+	return uuid.NewV4().String()
+}
+
+// orderBy parses SQL like order "field [asc|desc]" of List calls.
+// Empty order keeps the insertion order.
+func orderBy(order string) (field string, desc bool, err error) {
+	parts := strings.Fields(strings.ToLower(order))
+	switch {
+	case len(parts) == 0:
+		return "", false, nil
+	case len(parts) == 1:
+		return parts[0], false, nil
+	case len(parts) == 2 && (parts[1] == "asc" || parts[1] == "desc"):
+		return parts[0], parts[1] == "desc", nil
+	}
+	return "", false, errors.Wrap(ErrInvalidOrder, order)
+}
+
+// page returns the bounds of the page of n items. Limit <= 0 means
+// no limit.
+func page(n, limit, offset int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end = n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// without returns ids without id, keeping the order.
+func without(ids []string, id string) []string {
+	for i := range ids {
+		if ids[i] == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
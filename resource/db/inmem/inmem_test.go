@@ -0,0 +1,28 @@
+package inmem_test
+
+import (
+	"testing"
+
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db/dbtest"
+	"github.com/kavirajk/bookshop/db/inmem"
+	"github.com/kavirajk/bookshop/order"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/user"
+)
+
+func TestUserRepo(t *testing.T) {
+	dbtest.UserRepo(t, func(*testing.T) user.Repo { return inmem.NewUserRepo() })
+}
+
+func TestCatalogRepo(t *testing.T) {
+	dbtest.CatalogRepo(t, func(*testing.T) catalog.Repo { return inmem.NewCatalogRepo() })
+}
+
+func TestOrderRepo(t *testing.T) {
+	dbtest.OrderRepo(t, func(*testing.T) order.Repo { return inmem.NewOrderRepo() })
+}
+
+func TestPromotionRepo(t *testing.T) {
+	dbtest.PromotionRepo(t, func(*testing.T) promotion.Repo { return inmem.NewPromotionRepo() })
+}
@@ -0,0 +1,199 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/order"
+)
+
+type orderRepo struct {
+	mu      sync.RWMutex
+	orders  map[string]order.Order
+	deleted map[string]order.Order // soft deleted, by id
+	ids     []string               // insertion order
+}
+
+// NewOrderRepo returns order.Repo safe for concurrent use.
+func NewOrderRepo() order.Repo {
+	return &orderRepo{
+		orders:  make(map[string]order.Order),
+		deleted: make(map[string]order.Order),
+	}
+}
+
+// copyOrder copies o so that callers can't change the stored order
+// through its slices. Exchange rates are never changed once set, so
+// they are shared.
+func copyOrder(o order.Order) order.Order {
+	o.Lines = append([]order.Line(nil), o.Lines...)
+	o.Adjustments = append([]order.Adjustment(nil), o.Adjustments...)
+	if o.CreatedBy != nil {
+		u := *o.CreatedBy
+		o.CreatedBy = &u
+	}
+	return o
+}
+
+func (r *orderRepo) GetByID(_ context.Context, ID string) (order.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orders[ID]
+	if !ok {
+		return order.Order{}, db.ErrNotFound
+	}
+	return copyOrder(o), nil
+}
+
+func (r *orderRepo) ListByUser(_ context.Context, userID string) ([]order.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := make([]order.Order, 0)
+	for _, id := range r.ids {
+		if o := r.orders[id]; o.CreatedByID == userID {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	return orders, nil
+}
+
+func (r *orderRepo) Create(_ context.Context, o *order.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o.ID == "" {
+		o.ID = newID()
+	}
+	if _, ok := r.orders[o.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	if _, ok := r.deleted[o.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	o.Version = 1
+	newOrderPartIDs(o)
+	r.orders[o.ID] = copyOrder(*o)
+	r.ids = append(r.ids, o.ID)
+	return nil
+}
+
+func (r *orderRepo) Save(_ context.Context, o *order.Order) error {
+	if o.ID == "" {
+		return db.ErrMissingID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[o.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if stored.Version != o.Version {
+		return db.ErrConflict
+	}
+	o.Version++
+	newOrderPartIDs(o)
+	r.orders[o.ID] = copyOrder(*o)
+	return nil
+}
+
+// newOrderPartIDs assigns IDs to the lines and adjustments not yet stored.
+func newOrderPartIDs(o *order.Order) {
+	if o.CreatedBy != nil && o.CreatedByID == "" {
+		o.CreatedByID = o.CreatedBy.ID
+	}
+	for i := range o.Lines {
+		if o.Lines[i].ID == "" {
+			o.Lines[i].ID = newID()
+		}
+		o.Lines[i].OrderID = o.ID
+	}
+	for i := range o.Adjustments {
+		if o.Adjustments[i].ID == "" {
+			o.Adjustments[i].ID = newID()
+		}
+		o.Adjustments[i].OrderID = o.ID
+	}
+}
+
+func (r *orderRepo) Drop(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders = make(map[string]order.Order)
+	r.deleted = make(map[string]order.Order)
+	r.ids = nil
+	return nil
+}
+
+// Delete moves the order to the deleted ones.
+func (r *orderRepo) Delete(_ context.Context, id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if o.Version != version {
+		return db.ErrConflict
+	}
+	now := time.Now().UTC()
+	o.DeletedAt = &now
+	o.Version++
+	r.deleted[id] = o
+	delete(r.orders, id)
+	r.ids = without(r.ids, id)
+	return nil
+}
+
+func (r *orderRepo) ListDeleted(_ context.Context) ([]order.Order, error) {
+	r.mu.RLock()
+	orders := make([]order.Order, 0, len(r.deleted))
+	for _, o := range r.deleted {
+		orders = append(orders, copyOrder(o))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].DeletedAt.After(*orders[j].DeletedAt)
+	})
+	return orders, nil
+}
+
+// Restore moves the order back from the deleted ones, last in insertion order.
+func (r *orderRepo) Restore(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.deleted[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	o.DeletedAt = nil
+	o.Version++
+	r.orders[id] = o
+	delete(r.deleted, id)
+	r.ids = append(r.ids, id)
+	return nil
+}
+
+func (r *orderRepo) Purge(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, o := range r.deleted {
+		if o.DeletedAt.Before(before) {
+			delete(r.deleted, id)
+			n++
+		}
+	}
+	return n, nil
+}
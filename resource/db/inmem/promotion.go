@@ -0,0 +1,111 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/promotion"
+)
+
+type promotionRepo struct {
+	mu     sync.RWMutex
+	promos map[string]promotion.Promotion
+}
+
+// NewPromotionRepo returns promotion.Repo safe for concurrent use.
+func NewPromotionRepo() promotion.Repo {
+	return &promotionRepo{promos: make(map[string]promotion.Promotion)}
+}
+
+// filter returns matching promotions ordered by priority and ID.
+func (r *promotionRepo) filter(match func(p *promotion.Promotion) bool) []promotion.Promotion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	promos := make([]promotion.Promotion, 0)
+	for _, p := range r.promos {
+		if match(&p) {
+			promos = append(promos, p)
+		}
+	}
+	sort.Slice(promos, func(i, j int) bool {
+		if promos[i].Priority != promos[j].Priority {
+			return promos[i].Priority < promos[j].Priority
+		}
+		return promos[i].ID < promos[j].ID
+	})
+	return promos
+}
+
+func (r *promotionRepo) GetByID(_ context.Context, ID string) (promotion.Promotion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.promos[ID]
+	if !ok {
+		return promotion.Promotion{}, db.ErrNotFound
+	}
+	return p, nil
+}
+
+func (r *promotionRepo) List(_ context.Context) ([]promotion.Promotion, error) {
+	return r.filter(func(*promotion.Promotion) bool { return true }), nil
+}
+
+func (r *promotionRepo) ListApplicable(_ context.Context, coupon string) ([]promotion.Promotion, error) {
+	return r.filter(func(p *promotion.Promotion) bool {
+		return p.Active && (p.Coupon == "" || p.Coupon == coupon)
+	}), nil
+}
+
+func (r *promotionRepo) Redeem(_ context.Context, ID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.promos[ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if p.UsageLimit > 0 && p.Used >= p.UsageLimit {
+		return promotion.ErrCouponExhausted
+	}
+	p.Used++
+	r.promos[ID] = p
+	return nil
+}
+
+func (r *promotionRepo) Create(_ context.Context, p *promotion.Promotion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p.ID == "" {
+		p.ID = newID()
+	}
+	if _, ok := r.promos[p.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	r.promos[p.ID] = *p
+	return nil
+}
+
+func (r *promotionRepo) Save(_ context.Context, p *promotion.Promotion) error {
+	if p.ID == "" {
+		return db.ErrMissingID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.promos[p.ID] = *p
+	return nil
+}
+
+func (r *promotionRepo) Drop(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.promos = make(map[string]promotion.Promotion)
+	return nil
+}
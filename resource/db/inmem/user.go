@@ -1,100 +1,343 @@
 package inmem
 
 import (
-	"fmt"
+	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/kavirajk/bookshop/db"
 	"github.com/kavirajk/bookshop/user"
-	"github.com/twinj/uuid"
+	"github.com/pkg/errors"
 )
 
-type userRepo map[string]user.User
+type userRepo struct {
+	mu        sync.RWMutex
+	users     map[string]user.User
+	deleted   map[string]user.User // soft deleted, by id
+	ids       []string             // insertion order
+	addresses map[string]user.Address
+}
+
+// NewUserRepo returns user.Repo safe for concurrent use.
+func NewUserRepo() user.Repo {
+	return &userRepo{
+		users:     make(map[string]user.User),
+		deleted:   make(map[string]user.User),
+		addresses: make(map[string]user.Address),
+	}
+}
+
+func (r *userRepo) find(match func(u *user.User) bool) (user.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-type userID struct {
-	ID string
-	sync.Mutex
+	for _, id := range r.ids {
+		u := r.users[id]
+		if match(&u) {
+			return u, nil
+		}
+	}
+	return user.User{}, db.ErrNotFound
 }
 
-var global userID
+func (r *userRepo) GetByID(_ context.Context, id string) (user.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-func (r userRepo) GetByID(ID string) (user.User, error) {
-	u, ok := r[ID]
+	u, ok := r.users[id]
 	if !ok {
-		return user.User{}, fmt.Errorf("user %v", db.ErrNotFound)
+		return user.User{}, db.ErrNotFound
 	}
 	return u, nil
 }
 
-func (r userRepo) GetByUserName(username string) (user.User, error) {
-	for _, v := range r {
-		if v.Username == username {
-			return v, nil
-		}
+func (r *userRepo) GetByUserName(_ context.Context, username string) (user.User, error) {
+	return r.find(func(u *user.User) bool { return u.Username == username })
+}
+
+func (r *userRepo) GetByEmail(_ context.Context, email string) (user.User, error) {
+	return r.find(func(u *user.User) bool { return u.Email == email })
+}
+
+func (r *userRepo) GetByToken(_ context.Context, token string) (user.User, error) {
+	return r.find(func(u *user.User) bool { return u.AuthToken == token })
+}
+
+func (r *userRepo) GetByResetKey(_ context.Context, key string) (user.User, error) {
+	return r.find(func(u *user.User) bool { return u.ResetKey == key })
+}
+
+var userLess = map[string]func(a, b *user.User) bool{
+	"id":         func(a, b *user.User) bool { return a.ID < b.ID },
+	"email":      func(a, b *user.User) bool { return a.Email < b.Email },
+	"username":   func(a, b *user.User) bool { return a.Username < b.Username },
+	"first_name": func(a, b *user.User) bool { return a.FirstName < b.FirstName },
+	"last_name":  func(a, b *user.User) bool { return a.LastName < b.LastName },
+}
+
+func (r *userRepo) List(_ context.Context, order string, limit, offset int) ([]user.User, int, error) {
+	field, desc, err := orderBy(order)
+	if err != nil {
+		return nil, 0, err
+	}
+	less, ok := userLess[field]
+	if field != "" && !ok {
+		return nil, 0, errors.Wrap(ErrInvalidOrder, order)
+	}
+
+	r.mu.RLock()
+	users := make([]user.User, 0, len(r.ids))
+	for _, id := range r.ids {
+		users = append(users, r.users[id])
+	}
+	r.mu.RUnlock()
+
+	if less != nil {
+		sort.SliceStable(users, func(i, j int) bool {
+			if desc {
+				return less(&users[j], &users[i])
+			}
+			return less(&users[i], &users[j])
+		})
 	}
-	return user.User{}, fmt.Errorf("user %v", db.ErrNotFound)
+	start, end := page(len(users), limit, offset)
+	return users[start:end], len(users), nil
 }
 
-func (r userRepo) GetByEmail(email string) (user.User, error) {
-	for _, v := range r {
-		if v.Email == email {
-			return v, nil
-		}
+func (r *userRepo) Create(_ context.Context, u *user.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u.ID == "" {
+		u.ID = newID()
+	}
+	if _, ok := r.users[u.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	if _, ok := r.deleted[u.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	u.Version = 1
+	r.users[u.ID] = *u
+	r.ids = append(r.ids, u.ID)
+	return nil
+}
+
+func (r *userRepo) Save(_ context.Context, u *user.User) error {
+	if u.ID == "" {
+		return db.ErrMissingID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if stored.Version != u.Version {
+		return db.ErrConflict
 	}
-	return user.User{}, fmt.Errorf("user %v", db.ErrNotFound)
+	u.Version++
+	r.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) Drop(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users = make(map[string]user.User)
+	r.deleted = make(map[string]user.User)
+	r.addresses = make(map[string]user.Address)
+	r.ids = nil
+	return nil
 }
 
-func (r userRepo) GetByToken(token string) (user.User, error) {
-	for _, v := range r {
-		if v.AuthToken == token {
-			return v, nil
+func (r *userRepo) CreateAddress(_ context.Context, a *user.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a.ID == "" {
+		a.ID = newID()
+	}
+	if _, ok := r.addresses[a.ID]; ok {
+		return db.ErrAlreadyExists
+	}
+	r.addresses[a.ID] = *a
+	return nil
+}
+
+func (r *userRepo) SaveAddress(_ context.Context, a *user.Address) error {
+	if a.ID == "" {
+		return db.ErrMissingID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addresses[a.ID] = *a
+	return nil
+}
+
+func (r *userRepo) GetAddress(_ context.Context, id string) (user.Address, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.addresses[id]
+	if !ok {
+		return user.Address{}, db.ErrNotFound
+	}
+	return a, nil
+}
+
+func (r *userRepo) ListAddresses(_ context.Context, userID string) ([]user.Address, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	addresses := make([]user.Address, 0)
+	for _, a := range r.addresses {
+		if a.UserID == userID {
+			addresses = append(addresses, a)
 		}
 	}
-	return user.User{}, fmt.Errorf("user %v", db.ErrNotFound)
+	sort.Slice(addresses, func(i, j int) bool {
+		if addresses[i].Name != addresses[j].Name {
+			return addresses[i].Name < addresses[j].Name
+		}
+		return addresses[i].ID < addresses[j].ID
+	})
+	return addresses, nil
+}
+
+func (r *userRepo) DeleteAddress(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.addresses, id)
+	return nil
 }
 
-func (r userRepo) GetByResetKey(key string) (user.User, error) {
-	for _, v := range r {
-		if v.ResetKey == key {
-			return v, nil
+// deleteAddresses deletes address book of a purged user. Caller must hold
+// the lock.
+func (r *userRepo) deleteAddresses(userID string) {
+	for id, a := range r.addresses {
+		if a.UserID == userID {
+			delete(r.addresses, id)
 		}
 	}
-	return user.User{}, fmt.Errorf("user %v", db.ErrNotFound)
 }
 
-func (r userRepo) List() ([]user.User, error) {
-	users := make([]user.User, 0)
-	for _, v := range r {
-		users = append(users, v)
+func (r *userRepo) FailLogin(_ context.Context, id string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return 0, db.ErrNotFound
 	}
-	return users, nil
+	u.FailedLogins++
+	u.Version++
+	r.users[id] = u
+	return u.FailedLogins, nil
 }
 
-func (r userRepo) Create(user *user.User) error {
-	global.Lock()
-	global.ID = uuid.NewV4().String()
-	user.ID = global.ID
-	global.Unlock()
+func (r *userRepo) LockOut(_ context.Context, id string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if _, ok := r[user.ID]; ok {
-		return db.ErrAlreadyExists
+	u, ok := r.users[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if u.LockedUntil != nil && !u.LockedUntil.Before(until) {
+		return nil
 	}
-	r[user.ID] = *user
+	u.LockedUntil = &until
+	u.Version++
+	r.users[id] = u
 	return nil
 }
 
-func (r userRepo) Save(user *user.User) error {
-	r[user.ID] = *user
+func (r *userRepo) ResetFailedLogins(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+	u.Version++
+	r.users[id] = u
 	return nil
 }
 
-func (r userRepo) Drop() error {
-	for k := range r {
-		delete(r, k)
+// Delete moves the user to the deleted ones.
+func (r *userRepo) Delete(_ context.Context, id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if u.Version != version {
+		return db.ErrConflict
 	}
+	now := time.Now().UTC()
+	u.DeletedAt = &now
+	u.Version++
+	r.deleted[id] = u
+	delete(r.users, id)
+	r.ids = without(r.ids, id)
 	return nil
 }
 
-func NewUserRepo() user.Repo {
-	return userRepo{}
+func (r *userRepo) ListDeleted(_ context.Context) ([]user.User, error) {
+	r.mu.RLock()
+	users := make([]user.User, 0, len(r.deleted))
+	for _, u := range r.deleted {
+		users = append(users, u)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].DeletedAt.After(*users[j].DeletedAt)
+	})
+	return users, nil
+}
+
+// Restore moves the user back from the deleted ones, last in insertion order.
+func (r *userRepo) Restore(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.deleted[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	u.DeletedAt = nil
+	u.Version++
+	r.users[id] = u
+	delete(r.deleted, id)
+	r.ids = append(r.ids, id)
+	return nil
+}
+
+func (r *userRepo) Purge(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, u := range r.deleted {
+		if u.DeletedAt.Before(before) {
+			delete(r.deleted, id)
+			r.deleteAddresses(id)
+			n++
+		}
+	}
+	return n, nil
 }
@@ -0,0 +1,49 @@
+package migrate_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kavirajk/bookshop/db/migrate"
+	"github.com/pkg/errors"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/0010_books.up.sql":   {Data: []byte("CREATE TABLE books ()")},
+			"m/0010_books.down.sql": {Data: []byte("DROP TABLE books")},
+			"m/0002_users.up.sql":   {Data: []byte("CREATE TABLE users ()")},
+		}
+		ms, err := migrate.Load(fsys, "m")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(ms) != 2 || ms[0].Version != 2 || ms[1].Version != 10 {
+			t.Fatalf("expected versions [2 10], got %v", ms)
+		}
+		if ms[1].Name != "books" || ms[1].Down != "DROP TABLE books" {
+			t.Errorf("expected books with down, got %v", ms[1])
+		}
+	})
+
+	cases := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"bad name", fstest.MapFS{"m/books.up.sql": {}}},
+		{"missing up", fstest.MapFS{"m/0001_books.down.sql": {Data: []byte("DROP TABLE books")}}},
+		{"duplicate version", fstest.MapFS{
+			"m/0001_books.up.sql": {Data: []byte("SELECT 1")},
+			"m/0001_users.up.sql": {Data: []byte("SELECT 1")},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := migrate.Load(c.fsys, "m")
+			if errors.Cause(err) != migrate.ErrInvalidMigration {
+				t.Errorf("expected %v, got %v", migrate.ErrInvalidMigration, err)
+			}
+		})
+	}
+}
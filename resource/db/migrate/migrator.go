@@ -0,0 +1,280 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockKey is the postgres advisory lock held while migrating.
+// Any constant works as long as every bookshop process uses the same.
+const lockKey = 7346101
+
+var createTable = map[string]string{
+	"postgres": `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamp with time zone NOT NULL DEFAULT now()
+)`,
+	"sqlite3": `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    integer PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+}
+
+// Status tells whether a migration is applied.
+// Unknown is set for versions applied by a newer build.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Unknown   bool
+}
+
+// Migrator applies migrations on a database.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New returns Migrator of migrations sorted by version e.g: as returned by Load.
+// Dialect is the database/sql driver name of db, postgres or sqlite3.
+// Closing db is left to the caller.
+func New(db *sql.DB, dialect string, migrations []Migration) *Migrator {
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}
+}
+
+// Up applies all the pending migrations in version order and returns them.
+// Every migration runs in its own transaction, so a failing migration leaves
+// the ones before it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+			if err := run(ctx, conn, mg, mg.Up,
+				m.bind("INSERT INTO schema_migrations (version, name) VALUES (?, ?)"), mg.Version, mg.Name,
+			); err != nil {
+				return err
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, latest first, and
+// returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	reverted := make([]Migration, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if len(versions) == 0 {
+			return ErrNoApplied
+		}
+		for i := 0; i < steps && i < len(versions); i++ {
+			mg, ok := m.find(versions[i])
+			if !ok {
+				return errors.Wrapf(ErrUnknownVersion, "%d", versions[i])
+			}
+			if mg.Down == "" {
+				return errors.Wrapf(ErrInvalidMigration, "%d_%s can't be reverted", mg.Version, mg.Name)
+			}
+			if err := run(ctx, conn, mg, mg.Down,
+				m.bind("DELETE FROM schema_migrations WHERE version = ?"), mg.Version,
+			); err != nil {
+				return err
+			}
+			reverted = append(reverted, mg)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns status of every known migration in version order,
+// followed by the applied ones unknown to this build.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := Status{Migration: mg}
+		if a, ok := done[mg.Version]; ok {
+			s.Applied, s.AppliedAt = true, a.at
+			delete(done, mg.Version)
+		}
+		status = append(status, s)
+	}
+	unknown := make([]int64, 0, len(done))
+	for v := range done {
+		unknown = append(unknown, v)
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+	for _, v := range unknown {
+		a := done[v]
+		status = append(status, Status{
+			Migration: Migration{Version: v, Name: a.name},
+			Applied:   true,
+			AppliedAt: a.at,
+			Unknown:   true,
+		})
+	}
+	return status, nil
+}
+
+// Pending returns the migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]Migration, 0)
+	for _, s := range status {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg, true
+		}
+	}
+	return Migration{}, false
+}
+
+// locked runs fn holding the advisory lock. Lock belongs to the session,
+// so everything is done on the same connection.
+// SQLite has no advisory locks, it is meant for a single node anyway and
+// every migration takes the write lock of the whole database.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect != "postgres" {
+		return fn(conn)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return errors.Wrap(err, "acquiring migration lock")
+	}
+	defer func() {
+		// Use fresh context, unlocking must happen even if ctx is done.
+		if _, uerr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); uerr != nil && err == nil {
+			err = errors.Wrap(uerr, "releasing migration lock")
+		}
+	}()
+	return fn(conn)
+}
+
+type applied struct {
+	name string
+	at   time.Time
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	if err := m.supported(); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, createTable[m.dialect]); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int64]applied)
+	for rows.Next() {
+		var (
+			v int64
+			a applied
+		)
+		if err := rows.Scan(&v, &a.name, &a.at); err != nil {
+			return nil, err
+		}
+		done[v] = a
+	}
+	return done, rows.Err()
+}
+
+func (m *Migrator) supported() error {
+	if _, ok := createTable[m.dialect]; !ok {
+		return errors.Wrap(ErrUnsupportedDialect, m.dialect)
+	}
+	return nil
+}
+
+// bind rewrites ? placeholders of query into the ones of the dialect.
+func (m *Migrator) bind(query string) string {
+	if m.dialect != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// run executes sql of the migration and records it with the bookkeeping
+// statement in a single transaction.
+func run(ctx context.Context, conn *sql.Conn, mg Migration, query string, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "migration %d_%s", mg.Version, mg.Name)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Purger hard deletes the entities soft deleted before a time and returns
+// their count. Repos of soft deleted entities implement it.
+type Purger interface {
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// PurgeJob hard deletes the soft deleted entities once they are older than
+// Retention, so that they can be restored till then.
+type PurgeJob struct {
+	Retention time.Duration
+	Purgers   map[string]Purger // by entity name e.g: "users"
+	Logger    log.Logger
+}
+
+// Run purges every interval until ctx is done.
+func (j PurgeJob) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		j.Purge(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge purges the entities deleted more than Retention before now.
+// Failure of a purger is logged and doesn't stop the others.
+func (j PurgeJob) Purge(ctx context.Context, now time.Time) {
+	before := now.Add(-j.Retention)
+	for name, p := range j.Purgers {
+		n, err := p.Purge(ctx, before)
+		j.Logger.Log("entity", name, "purged", n, "before", before.Format(time.RFC3339), "err", err)
+	}
+}
@@ -0,0 +1,165 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db"
+	_ "github.com/lib/pq"
+)
+
+type catalogRepo struct {
+	db *db.DB
+}
+
+func NewCatalogRepo(d *db.DB) catalog.Repo {
+	return &catalogRepo{db: d}
+}
+
+// query returns new query on the primary preloading prices and the
+// associations promotions are scoped by.
+func (r *catalogRepo) query(ctx context.Context) *gorm.DB {
+	return preloadBook(r.db.Conn(ctx))
+}
+
+func preloadBook(q *gorm.DB) *gorm.DB {
+	return q.Preload("Prices").Preload("Authors").Preload("Genres")
+}
+
+// read runs fn with a preloading query on a replica, see db.Read.
+// Catalog reads dominate the traffic and tolerate replication lag.
+func (r *catalogRepo) read(ctx context.Context, fn func(q *gorm.DB) error) error {
+	return r.db.Read(ctx, func(q *gorm.DB) error {
+		return fn(preloadBook(q))
+	})
+}
+
+func (r *catalogRepo) get(ctx context.Context, where ...interface{}) (catalog.Book, error) {
+	var b catalog.Book
+	err := r.read(ctx, func(q *gorm.DB) error {
+		return q.First(&b, where...).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return catalog.Book{}, db.ErrNotFound
+		}
+		return catalog.Book{}, err
+	}
+	return b, nil
+}
+
+func (r *catalogRepo) GetByID(ctx context.Context, ID string) (catalog.Book, error) {
+	return r.get(ctx, "id=?", ID)
+}
+
+func (r *catalogRepo) GetByISBN(ctx context.Context, ISBN string) (catalog.Book, error) {
+	return r.get(ctx, "isbn=?", ISBN)
+}
+
+func (r *catalogRepo) ListByAuthor(ctx context.Context, authorID string) ([]catalog.Book, error) {
+	books := make([]catalog.Book, 0)
+	err := r.read(ctx, func(q *gorm.DB) error {
+		return q.Joins("JOIN book_authors ON book_authors.book_id = books.id").
+			Where("book_authors.author_id = ?", authorID).
+			Find(&books).Error
+	})
+	return books, err
+}
+
+func (r *catalogRepo) GetByToken(ctx context.Context, token string) (catalog.Book, error) {
+	return r.get(ctx, "auth_token=?", token)
+}
+
+func (r *catalogRepo) GetByResetKey(ctx context.Context, key string) (catalog.Book, error) {
+	return r.get(ctx, "reset_key=?", key)
+}
+
+func (r *catalogRepo) List(ctx context.Context, order string, limit, offset int) ([]catalog.Book, int, error) {
+	catalogs := make([]catalog.Book, 0)
+	var total int
+	err := r.db.Read(ctx, func(q *gorm.DB) error {
+		if err := q.Model(&catalog.Book{}).Order(order).Count(&total).Error; err != nil {
+			return err
+		}
+		return preloadBook(q).Order(order).Limit(limit).Offset(offset).Find(&catalogs).Error
+	})
+	return catalogs, total, err
+}
+
+// Search matches title case insensitively. SQLite LIKE already ignores
+// case, postgres needs ILIKE.
+func (r *catalogRepo) Search(ctx context.Context, title string) ([]catalog.Book, error) {
+	books := make([]catalog.Book, 0)
+	q := fmt.Sprintf("%%%s%%", title)
+	like := "LIKE"
+	if r.db.Dialect() == db.Postgres {
+		like = "ILIKE"
+	}
+	err := r.read(ctx, func(d *gorm.DB) error {
+		return d.Where("title "+like+" ?", q).Find(&books).Error
+	})
+	return books, err
+}
+
+func (r *catalogRepo) Create(ctx context.Context, u *catalog.Book) error {
+	d := r.db.Conn(ctx)
+
+	if u.ID == "" {
+		u.ID = NewID()
+	}
+	newPriceIDs(u)
+
+	u.Version = 1
+	if err := d.Create(u).Error; err != nil {
+		return duplicate(err)
+	}
+	return nil
+}
+
+func (r *catalogRepo) Save(ctx context.Context, u *catalog.Book) error {
+	if u.ID == "" {
+		return db.ErrMissingID
+	}
+	newPriceIDs(u)
+	return saveVersioned(ctx, r.db, "books", u.ID, &u.Version, u)
+}
+
+func (r *catalogRepo) Delete(ctx context.Context, id string, version int64) error {
+	return softDelete(ctx, r.db, &catalog.Book{}, id, version)
+}
+
+func (r *catalogRepo) ListDeleted(ctx context.Context) ([]catalog.Book, error) {
+	books := make([]catalog.Book, 0)
+	err := r.query(ctx).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&books).Error
+	return books, err
+}
+
+func (r *catalogRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, r.db, &catalog.Book{}, id)
+}
+
+func (r *catalogRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, r.db, &catalog.Book{}, before)
+}
+
+// newPriceIDs assigns IDs to the book prices that are not yet stored.
+func newPriceIDs(b *catalog.Book) {
+	for i := range b.Prices {
+		if b.Prices[i].ID == "" {
+			b.Prices[i].ID = NewID()
+		}
+	}
+}
+
+func (r *catalogRepo) Drop(ctx context.Context) error {
+	// Prices and the book associations go with the books.
+	for _, table := range []string{"books", "authors", "genres", "publishers"} {
+		if err := r.db.Conn(ctx).Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package sqldb
+
+import (
+	"embed"
+	"path"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/db/migrate"
+	"github.com/pkg/errors"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the schema migrations of all the repos in version order
+// for dialect, db.Postgres or db.SQLite. Both dialects have the same versions.
+// Apply them with bookctl before starting bookserver.
+func Migrations(dialect string) ([]migrate.Migration, error) {
+	switch dialect {
+	case db.Postgres, db.SQLite:
+		return migrate.Load(migrationFiles, path.Join("migrations", dialect))
+	}
+	return nil, errors.Wrap(migrate.ErrUnsupportedDialect, dialect)
+}
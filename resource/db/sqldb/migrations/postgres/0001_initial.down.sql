@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS adjustments;
+DROP TABLE IF EXISTS lines;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS promotions;
+DROP TABLE IF EXISTS book_genres;
+DROP TABLE IF EXISTS book_authors;
+DROP TABLE IF EXISTS book_prices;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS genres;
+DROP TABLE IF EXISTS publishers;
+DROP TABLE IF EXISTS authors;
+DROP TABLE IF EXISTS addresses;
+DROP TABLE IF EXISTS users;
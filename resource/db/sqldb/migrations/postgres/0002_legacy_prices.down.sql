@@ -0,0 +1,2 @@
+-- Legacy float prices are gone for good, nothing to revert.
+SELECT 1;
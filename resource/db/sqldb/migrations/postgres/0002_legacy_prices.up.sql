@@ -0,0 +1,49 @@
+-- Databases created by AutoMigrate before prices had a currency keep them
+-- as float columns books.price and orders.total_price, orders.currency.
+-- Copy them into minor units and drop the legacy columns. Rows without
+-- currency are assumed to be USD.
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS price_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS price_currency text NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_price_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_price_currency text NOT NULL DEFAULT '';
+
+-- ROUND rounds ties away from zero, prices are rounded ties to even as
+-- money.RoundHalfEven does.
+CREATE FUNCTION pg_temp.round_half_even(x numeric) RETURNS numeric AS $$
+	SELECT ROUND(x) - CASE WHEN ABS(x - TRUNC(x)) = 0.5 AND MOD(ROUND(x), 2) <> 0
+		THEN SIGN(x) ELSE 0 END
+$$ LANGUAGE sql IMMUTABLE;
+
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'books' AND column_name = 'price') THEN
+		UPDATE books SET
+			price_amount = pg_temp.round_half_even(COALESCE(price, 0)::numeric * 100),
+			price_currency = 'USD';
+		ALTER TABLE books DROP COLUMN price;
+	END IF;
+
+	IF EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'total_price') THEN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'currency') THEN
+			ALTER TABLE orders ADD COLUMN currency text;
+		END IF;
+		UPDATE orders SET
+			total_price_currency = COALESCE(NULLIF(UPPER(TRIM(currency)), ''), 'USD'),
+			-- minor units by currency exponent, see money.Currency.Exponent.
+			total_price_amount = pg_temp.round_half_even(COALESCE(total_price, 0)::numeric *
+				CASE COALESCE(NULLIF(UPPER(TRIM(currency)), ''), 'USD')
+					WHEN 'JPY' THEN 1
+					WHEN 'KWD' THEN 1000
+					ELSE 100
+				END);
+		ALTER TABLE orders DROP COLUMN total_price;
+		ALTER TABLE orders DROP COLUMN currency;
+	END IF;
+END
+$$;
+
+DROP FUNCTION pg_temp.round_half_even(numeric);
@@ -0,0 +1,9 @@
+-- Users, books and orders are soft deleted, rows with deleted_at set are
+-- hidden by the repos and hard deleted by the purge job after retention.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
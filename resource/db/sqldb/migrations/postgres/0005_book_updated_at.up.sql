@@ -0,0 +1,3 @@
+-- Books tell when they last changed, for Last-Modified of the catalog.
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS updated_at timestamp with time zone NOT NULL DEFAULT now();
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS publisher_id;
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
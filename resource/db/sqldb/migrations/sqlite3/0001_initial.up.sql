@@ -0,0 +1,160 @@
+-- Same schema as the postgres one in SQLite types. Timestamps are text
+-- the driver parses, booleans are 0 or 1.
+
+CREATE TABLE IF NOT EXISTS users (
+	id         text PRIMARY KEY,
+	first_name text NOT NULL DEFAULT '',
+	last_name  text NOT NULL DEFAULT '',
+	email      text NOT NULL DEFAULT '',
+	username   text NOT NULL DEFAULT '',
+	password   text NOT NULL DEFAULT '',
+	salt       text NOT NULL DEFAULT '',
+	reset_key  text NOT NULL DEFAULT '',
+	auth_token text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_auth_token ON users (auth_token);
+CREATE INDEX IF NOT EXISTS idx_users_reset_key ON users (reset_key);
+
+CREATE TABLE IF NOT EXISTS addresses (
+	id          text PRIMARY KEY,
+	user_id     text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name        text NOT NULL DEFAULT '',
+	line1       text NOT NULL DEFAULT '',
+	line2       text NOT NULL DEFAULT '',
+	city        text NOT NULL DEFAULT '',
+	region      text NOT NULL DEFAULT '',
+	postal_code text NOT NULL DEFAULT '',
+	country     text NOT NULL DEFAULT '',
+	phone       text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses (user_id);
+
+CREATE TABLE IF NOT EXISTS authors (
+	id         text PRIMARY KEY,
+	first_name text NOT NULL DEFAULT '',
+	last_name  text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS publishers (
+	id   text PRIMARY KEY,
+	name text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS genres (
+	id   text PRIMARY KEY,
+	name text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS books (
+	id               text PRIMARY KEY,
+	isbn             text NOT NULL DEFAULT '',
+	title            text NOT NULL DEFAULT '',
+	tag_string       text NOT NULL DEFAULT '',
+	publisher_id     text NOT NULL DEFAULT '',
+	publication_year text NOT NULL DEFAULT '',
+	publication_date timestamp NOT NULL DEFAULT '0001-01-01 00:00:00+00:00',
+	sample_url       text NOT NULL DEFAULT '',
+	full_url         text NOT NULL DEFAULT '',
+	format           text NOT NULL DEFAULT '',
+	weight_grams     integer NOT NULL DEFAULT 0,
+	price_amount     bigint NOT NULL DEFAULT 0,
+	price_currency   text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS book_prices (
+	id             text PRIMARY KEY,
+	book_id        text NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	price_amount   bigint NOT NULL DEFAULT 0,
+	price_currency text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_book_prices_book_id ON book_prices (book_id);
+
+CREATE TABLE IF NOT EXISTS book_authors (
+	book_id   text NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	author_id text NOT NULL REFERENCES authors (id) ON DELETE CASCADE,
+	PRIMARY KEY (book_id, author_id)
+);
+
+CREATE TABLE IF NOT EXISTS book_genres (
+	book_id  text NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	genre_id text NOT NULL REFERENCES genres (id) ON DELETE CASCADE,
+	PRIMARY KEY (book_id, genre_id)
+);
+
+CREATE TABLE IF NOT EXISTS promotions (
+	id              text PRIMARY KEY,
+	name            text NOT NULL DEFAULT '',
+	kind            text NOT NULL DEFAULT '',
+	percent         integer NOT NULL DEFAULT 0,
+	amount_amount   bigint NOT NULL DEFAULT 0,
+	amount_currency text NOT NULL DEFAULT '',
+	buy_quantity    integer NOT NULL DEFAULT 0,
+	get_quantity    integer NOT NULL DEFAULT 0,
+	genre_id        text NOT NULL DEFAULT '',
+	author_id       text NOT NULL DEFAULT '',
+	publisher_id    text NOT NULL DEFAULT '',
+	coupon          text NOT NULL DEFAULT '',
+	usage_limit     integer NOT NULL DEFAULT 0,
+	used            integer NOT NULL DEFAULT 0,
+	priority        integer NOT NULL DEFAULT 0,
+	exclusive       boolean NOT NULL DEFAULT 0,
+	active          boolean NOT NULL DEFAULT 0,
+	starts_at       timestamp NOT NULL DEFAULT '0001-01-01 00:00:00+00:00',
+	expires_at      timestamp NOT NULL DEFAULT '0001-01-01 00:00:00+00:00'
+);
+CREATE INDEX IF NOT EXISTS idx_promotions_coupon ON promotions (coupon);
+
+CREATE TABLE IF NOT EXISTS orders (
+	id                     text PRIMARY KEY,
+	created_by_id          text NOT NULL DEFAULT '',
+	coupon                 text NOT NULL DEFAULT '',
+	billing_country        text NOT NULL DEFAULT '',
+	billing_region         text NOT NULL DEFAULT '',
+	tax_total_amount       bigint NOT NULL DEFAULT 0,
+	tax_total_currency     text NOT NULL DEFAULT '',
+	shipping_name          text NOT NULL DEFAULT '',
+	shipping_line1         text NOT NULL DEFAULT '',
+	shipping_line2         text NOT NULL DEFAULT '',
+	shipping_city          text NOT NULL DEFAULT '',
+	shipping_region        text NOT NULL DEFAULT '',
+	shipping_postal_code   text NOT NULL DEFAULT '',
+	shipping_country       text NOT NULL DEFAULT '',
+	shipping_phone         text NOT NULL DEFAULT '',
+	shipping_method        text NOT NULL DEFAULT '',
+	shipping_cost_amount   bigint NOT NULL DEFAULT 0,
+	shipping_cost_currency text NOT NULL DEFAULT '',
+	total_price_amount     bigint NOT NULL DEFAULT 0,
+	total_price_currency   text NOT NULL DEFAULT '',
+	exchange_rates         text
+);
+CREATE INDEX IF NOT EXISTS idx_orders_created_by_id ON orders (created_by_id);
+
+CREATE TABLE IF NOT EXISTS lines (
+	id                  text PRIMARY KEY,
+	order_id            text NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+	book_id             text NOT NULL DEFAULT '',
+	title               text NOT NULL DEFAULT '',
+	format              text NOT NULL DEFAULT '',
+	quantity            integer NOT NULL DEFAULT 0,
+	unit_price_amount   bigint NOT NULL DEFAULT 0,
+	unit_price_currency text NOT NULL DEFAULT '',
+	discount_amount     bigint NOT NULL DEFAULT 0,
+	discount_currency   text NOT NULL DEFAULT '',
+	tax_name            text NOT NULL DEFAULT '',
+	tax_rate            text NOT NULL DEFAULT '',
+	tax_inclusive       boolean NOT NULL DEFAULT 0,
+	tax_amount_amount   bigint NOT NULL DEFAULT 0,
+	tax_amount_currency text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_lines_order_id ON lines (order_id);
+
+CREATE TABLE IF NOT EXISTS adjustments (
+	id              text PRIMARY KEY,
+	order_id        text NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+	promotion_id    text NOT NULL DEFAULT '',
+	description     text NOT NULL DEFAULT '',
+	amount_amount   bigint NOT NULL DEFAULT 0,
+	amount_currency text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_adjustments_order_id ON adjustments (order_id);
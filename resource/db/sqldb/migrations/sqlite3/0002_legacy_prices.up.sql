@@ -0,0 +1,3 @@
+-- SQLite databases never had the legacy float prices, kept to have the
+-- same versions as postgres.
+SELECT 1;
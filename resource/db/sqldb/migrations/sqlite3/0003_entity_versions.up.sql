@@ -0,0 +1,6 @@
+-- Version of the entities saved with optimistic concurrency control.
+-- Existing rows start at 1, same as the new ones.
+
+ALTER TABLE users ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE books ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_deleted_at;
+DROP INDEX IF EXISTS idx_books_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE orders DROP COLUMN deleted_at;
+ALTER TABLE books DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
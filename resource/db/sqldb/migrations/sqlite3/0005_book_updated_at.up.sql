@@ -0,0 +1,4 @@
+-- Books tell when they last changed, for Last-Modified of the catalog.
+
+ALTER TABLE books ADD COLUMN updated_at timestamp NOT NULL DEFAULT '0001-01-01 00:00:00+00:00';
+UPDATE books SET updated_at = CURRENT_TIMESTAMP;
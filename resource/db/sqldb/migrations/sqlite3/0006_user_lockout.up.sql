@@ -0,0 +1,4 @@
+-- Users are locked out after repeated failed logins, see user.Lockout.
+
+ALTER TABLE users ADD COLUMN failed_logins integer NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until timestamp;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN publisher_id;
+ALTER TABLE users DROP COLUMN roles;
@@ -0,0 +1,5 @@
+-- Roles grant users permissions, see auth.Permissions. Publishers manage
+-- the books of their publisher.
+
+ALTER TABLE users ADD COLUMN roles text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN publisher_id text NOT NULL DEFAULT '';
@@ -0,0 +1,112 @@
+package sqldb
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/order"
+	_ "github.com/lib/pq"
+)
+
+type orderRepo struct {
+	db *db.DB
+}
+
+func NewOrderRepo(d *db.DB) order.Repo {
+	return &orderRepo{db: d}
+}
+
+// query returns new query preloading all the parts of an order.
+func (r *orderRepo) query(ctx context.Context) *gorm.DB {
+	return r.db.Conn(ctx).Preload("Lines").Preload("Adjustments")
+}
+
+func (r *orderRepo) get(ctx context.Context, where ...interface{}) (order.Order, error) {
+	var b order.Order
+	d := r.query(ctx)
+
+	if err := d.First(&b, where...).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return order.Order{}, db.ErrNotFound
+		}
+		return order.Order{}, err
+	}
+	return b, nil
+}
+
+func (r *orderRepo) filter(ctx context.Context, where ...interface{}) ([]order.Order, error) {
+	orders := make([]order.Order, 0)
+	d := r.query(ctx)
+
+	err := d.Find(&orders, where...).Error
+	return orders, err
+}
+
+func (r *orderRepo) GetByID(ctx context.Context, ID string) (order.Order, error) {
+	return r.get(ctx, "id=?", ID)
+}
+
+func (r *orderRepo) ListByUser(ctx context.Context, userID string) ([]order.Order, error) {
+	return r.filter(ctx, "created_by_id=?", userID)
+}
+
+func (r *orderRepo) Create(ctx context.Context, u *order.Order) error {
+	d := r.db.Conn(ctx)
+
+	if u.ID == "" {
+		u.ID = NewID()
+	}
+	newOrderPartIDs(u)
+
+	u.Version = 1
+	if err := d.Create(u).Error; err != nil {
+		return duplicate(err)
+	}
+	return nil
+}
+
+func (r *orderRepo) Save(ctx context.Context, u *order.Order) error {
+	if u.ID == "" {
+		return db.ErrMissingID
+	}
+	newOrderPartIDs(u)
+	return saveVersioned(ctx, r.db, "orders", u.ID, &u.Version, u)
+}
+
+func (r *orderRepo) Delete(ctx context.Context, id string, version int64) error {
+	return softDelete(ctx, r.db, &order.Order{}, id, version)
+}
+
+func (r *orderRepo) ListDeleted(ctx context.Context) ([]order.Order, error) {
+	orders := make([]order.Order, 0)
+	err := r.query(ctx).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&orders).Error
+	return orders, err
+}
+
+func (r *orderRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, r.db, &order.Order{}, id)
+}
+
+func (r *orderRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, r.db, &order.Order{}, before)
+}
+
+// newOrderPartIDs assigns IDs to the lines and adjustments not yet stored.
+func newOrderPartIDs(o *order.Order) {
+	for i := range o.Lines {
+		if o.Lines[i].ID == "" {
+			o.Lines[i].ID = NewID()
+		}
+	}
+	for i := range o.Adjustments {
+		if o.Adjustments[i].ID == "" {
+			o.Adjustments[i].ID = NewID()
+		}
+	}
+}
+
+func (r *orderRepo) Drop(ctx context.Context) error {
+	return r.db.Conn(ctx).Exec("DELETE FROM ORDERS").Error
+}
@@ -0,0 +1,93 @@
+package sqldb
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/promotion"
+	_ "github.com/lib/pq"
+)
+
+type promotionRepo struct {
+	db *db.DB
+}
+
+func NewPromotionRepo(d *db.DB) promotion.Repo {
+	return &promotionRepo{db: d}
+}
+
+func (r *promotionRepo) get(ctx context.Context, where ...interface{}) (promotion.Promotion, error) {
+	var p promotion.Promotion
+	d := r.db.Conn(ctx)
+
+	if err := d.First(&p, where...).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return promotion.Promotion{}, db.ErrNotFound
+		}
+		return promotion.Promotion{}, err
+	}
+	return p, nil
+}
+
+func (r *promotionRepo) filter(ctx context.Context, where ...interface{}) ([]promotion.Promotion, error) {
+	promos := make([]promotion.Promotion, 0)
+	d := r.db.Conn(ctx)
+
+	err := d.Order("priority, id").Find(&promos, where...).Error
+	return promos, err
+}
+
+func (r *promotionRepo) GetByID(ctx context.Context, ID string) (promotion.Promotion, error) {
+	return r.get(ctx, "id=?", ID)
+}
+
+func (r *promotionRepo) List(ctx context.Context) ([]promotion.Promotion, error) {
+	return r.filter(ctx)
+}
+
+func (r *promotionRepo) ListApplicable(ctx context.Context, coupon string) ([]promotion.Promotion, error) {
+	return r.filter(ctx, "active=? AND (coupon='' OR coupon=?)", true, coupon)
+}
+
+func (r *promotionRepo) Redeem(ctx context.Context, ID string) error {
+	d := r.db.Exec(ctx,
+		"UPDATE promotions SET used = used + 1 WHERE id = ? AND (usage_limit = 0 OR used < usage_limit)", ID,
+	)
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return promotion.ErrCouponExhausted
+	}
+	return nil
+}
+
+func (r *promotionRepo) Create(ctx context.Context, p *promotion.Promotion) error {
+	d := r.db.Conn(ctx)
+
+	if p.ID == "" {
+		p.ID = NewID()
+	}
+
+	if err := d.Create(p).Error; err != nil {
+		return duplicate(err)
+	}
+	return nil
+}
+
+func (r *promotionRepo) Save(ctx context.Context, p *promotion.Promotion) error {
+	if p.ID == "" {
+		return db.ErrMissingID
+	}
+	d := r.db.Conn(ctx)
+
+	if err := d.Save(p).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *promotionRepo) Drop(ctx context.Context) error {
+	return r.db.Conn(ctx).Exec("DELETE FROM PROMOTIONS").Error
+}
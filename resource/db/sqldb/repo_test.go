@@ -0,0 +1,492 @@
+package sqldb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/db/dbtest"
+	"github.com/kavirajk/bookshop/db/migrate"
+	"github.com/kavirajk/bookshop/db/sqldb"
+	"github.com/kavirajk/bookshop/exchange"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/order"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/shipping"
+	"github.com/kavirajk/bookshop/tax"
+	"github.com/kavirajk/bookshop/tracing"
+	"github.com/kavirajk/bookshop/user"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// dialects runs fn against every database available to the tests.
+// SQLite uses a fresh file per test, postgres needs
+// POSTGRES_TEST_DB_DATASOURCE to point to a test database.
+func dialects(t *testing.T, fn func(t *testing.T, setup func(t *testing.T) *db.DB)) {
+	t.Run(db.SQLite, func(t *testing.T) {
+		fn(t, func(t *testing.T) *db.DB {
+			return open(t, db.SQLite, filepath.Join(t.TempDir(), "bookshop.db"))
+		})
+	})
+	t.Run(db.Postgres, func(t *testing.T) {
+		source := os.Getenv("POSTGRES_TEST_DB_DATASOURCE")
+		if source == "" {
+			t.Skip("missing POSTGRES_TEST_DB_DATASOURCE env variable")
+		}
+		fn(t, func(t *testing.T) *db.DB { return open(t, db.Postgres, source) })
+	})
+}
+
+func open(t *testing.T, driver, source string) *db.DB {
+	d, err := db.Open(driver, source, db.PoolOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+
+	migrations, err := sqldb.Migrations(d.Dialect())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := migrate.New(d.SQL(), d.Dialect(), migrations).Up(context.Background()); err != nil {
+		t.Fatalf("%v", err)
+	}
+	return d
+}
+
+func TestUserRepo(t *testing.T) {
+	dialects(t, func(t *testing.T, setup func(t *testing.T) *db.DB) {
+		dbtest.UserRepo(t, func(t *testing.T) user.Repo { return sqldb.NewUserRepo(setup(t)) })
+	})
+}
+
+func TestCatalogRepo(t *testing.T) {
+	dialects(t, func(t *testing.T, setup func(t *testing.T) *db.DB) {
+		dbtest.CatalogRepo(t, func(t *testing.T) catalog.Repo { return sqldb.NewCatalogRepo(setup(t)) })
+	})
+}
+
+func TestOrderRepo(t *testing.T) {
+	dialects(t, func(t *testing.T, setup func(t *testing.T) *db.DB) {
+		dbtest.OrderRepo(t, func(t *testing.T) order.Repo { return sqldb.NewOrderRepo(setup(t)) })
+	})
+}
+
+func TestPromotionRepo(t *testing.T) {
+	dialects(t, func(t *testing.T, setup func(t *testing.T) *db.DB) {
+		dbtest.PromotionRepo(t, func(t *testing.T) promotion.Repo { return sqldb.NewPromotionRepo(setup(t)) })
+	})
+}
+
+func TestMigrationsUpTwice(t *testing.T) {
+	d := open(t, db.SQLite, filepath.Join(t.TempDir(), "bookshop.db"))
+	migrations, _ := sqldb.Migrations(db.SQLite)
+	applied, err := migrate.New(d.SQL(), db.SQLite, migrations).Up(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no migrations applied, got %d", len(applied))
+	}
+}
+
+func TestMigrationsDown(t *testing.T) {
+	ctx := context.Background()
+	dialects(t, func(t *testing.T, setup func(t *testing.T) *db.DB) {
+		d := setup(t)
+		migrations, _ := sqldb.Migrations(d.Dialect())
+		m := migrate.New(d.SQL(), d.Dialect(), migrations)
+		reverted, err := m.Down(ctx, len(migrations))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(reverted) != len(migrations) {
+			t.Errorf("expected %d migrations reverted, got %d", len(migrations), len(reverted))
+		}
+		if _, err := m.Up(ctx); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+}
+
+// baselineSchema is the schema gorm AutoMigrate created before versioned
+// migrations, with legacy float prices.
+const baselineSchema = `
+CREATE TABLE users (id text, first_name text, last_name text, email text, username text,
+	password text, salt text, reset_key text, auth_token text, PRIMARY KEY (id));
+CREATE TABLE books (id text, isbn text, title text, tag_string text, publisher_id text,
+	publication_year text, publication_date timestamp with time zone, sample_url text,
+	full_url text, price numeric, PRIMARY KEY (id));
+CREATE TABLE authors (id text, first_name text, last_name text, PRIMARY KEY (id));
+CREATE TABLE publishers (id text, name text, PRIMARY KEY (id));
+CREATE TABLE genres (id text, name text, PRIMARY KEY (id));
+CREATE TABLE orders (id text, created_by_id text, total_price numeric, currency text, PRIMARY KEY (id));
+INSERT INTO users (id, email) VALUES ('joey', 'joey@golang.org');
+INSERT INTO books (id, title, price) VALUES ('dune', 'Dune', 12.345);
+INSERT INTO orders (id, created_by_id, total_price, currency) VALUES ('legacy', 'joey', 1200.5, ' jpy');
+`
+
+func TestMigrationsFromBaseline(t *testing.T) {
+	ctx := context.Background()
+	source := os.Getenv("POSTGRES_TEST_DB_DATASOURCE")
+	if source == "" {
+		t.Skip("missing POSTGRES_TEST_DB_DATASOURCE env variable")
+	}
+	admin, err := db.Open(db.Postgres, source, db.PoolOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer admin.Close()
+	schema := fmt.Sprintf("baseline_%d", time.Now().UnixNano())
+	if _, err := admin.SQL().ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer admin.SQL().ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+
+	// lib/pq sends unknown settings, here search_path, to the server.
+	if strings.Contains(source, "://") {
+		sep := "?"
+		if strings.Contains(source, "?") {
+			sep = "&"
+		}
+		source += sep + "search_path=" + schema
+	} else {
+		source += " search_path=" + schema
+	}
+	baseline, err := db.Open(db.Postgres, source, db.PoolOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer baseline.Close()
+	if _, err := baseline.SQL().ExecContext(ctx, baselineSchema); err != nil {
+		t.Fatalf("%v", err)
+	}
+	d := open(t, db.Postgres, source)
+
+	books, orders := sqldb.NewCatalogRepo(d), sqldb.NewOrderRepo(d)
+	b, err := books.GetByID(ctx, "dune")
+	if err != nil || b.Price != money.New(1234, money.USD) {
+		t.Fatalf("expected dune at 12.34 USD, got %v %v", b.Price, err)
+	}
+	// Legacy books are print ones, sell dune as ebook to skip shipping.
+	b.Format = catalog.Ebook
+	if err := books.Save(ctx, &b); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if u, err := sqldb.NewUserRepo(d).GetByEmail(ctx, "joey@golang.org"); err != nil || u.ID != "joey" {
+		t.Errorf("expected joey, got %v %v", u.ID, err)
+	}
+	if o, err := orders.GetByID(ctx, "legacy"); err != nil || o.TotalPrice != money.New(1200, money.JPY) {
+		t.Errorf("expected legacy order at 1200 JPY, got %v %v", o.TotalPrice, err)
+	}
+
+	taxes, _ := tax.NewTable(nil)
+	rates, _ := shipping.NewTable(nil)
+	s := order.NewService(orders, d, books, exchange.NewService(money.Rates{Base: "USD"}),
+		promotion.NewService(sqldb.NewPromotionRepo(d)), taxes, rates)
+	placed, err := s.PlaceOrder(ctx, order.Cart{Items: []order.CartItem{{BookID: "dune", Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if _, err := orders.GetByID(ctx, placed.ID); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestReadReplicas(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	primaryFile, replicaFile := filepath.Join(dir, "primary.db"), filepath.Join(dir, "replica.db")
+	open(t, db.SQLite, primaryFile)
+	replicaDB := open(t, db.SQLite, replicaFile)
+	replica := sqldb.NewCatalogRepo(replicaDB)
+
+	d, err := db.Open(db.SQLite, primaryFile, db.PoolOptions{}, replicaFile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer d.Close()
+	repo := sqldb.NewCatalogRepo(d)
+
+	onPrimary := catalog.Book{Title: "Primary"}
+	if err := repo.Create(ctx, &onPrimary); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	onReplica := catalog.Book{Title: "Replica"}
+	if err := replica.Create(ctx, &onReplica); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("reads go to replica", func(t *testing.T) {
+		if _, err := repo.GetByID(ctx, onReplica.ID); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if _, err := repo.GetByID(ctx, onPrimary.ID); err != db.ErrNotFound {
+			t.Errorf("expected %v, got %v", db.ErrNotFound, err)
+		}
+		if books, _ := repo.Search(ctx, "replica"); len(books) != 1 {
+			t.Errorf("expected 1 book, got %v", books)
+		}
+	})
+
+	t.Run("read your writes", func(t *testing.T) {
+		sctx := db.WithSession(ctx)
+		b := catalog.Book{Title: "Written"}
+		if err := repo.Create(sctx, &b); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if _, err := repo.GetByID(sctx, b.ID); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if _, total, _ := repo.List(sctx, "", 10, 0); total != 2 {
+			t.Errorf("expected total 2, got %v", total)
+		}
+	})
+
+	t.Run("read primary", func(t *testing.T) {
+		if _, err := repo.GetByID(db.ReadPrimary(ctx), onPrimary.ID); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("cache filled from primary after a write", func(t *testing.T) {
+		c := cache.NewLRU(100)
+		books := catalog.InvalidatingRepo(repo, c, log.NewNopLogger())
+		s := catalog.CachingMiddleware(c, catalog.CacheTTLs{Get: time.Minute})(catalog.NewService(books, nil))
+		b := catalog.Book{Title: "Cached"}
+		if err := books.Create(ctx, &b); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if _, err := s.Get(ctx, b.ID, ""); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("failing replica falls back to primary", func(t *testing.T) {
+		if err := replicaDB.Conn(ctx).Exec("ALTER TABLE books RENAME TO books_gone").Error; err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if _, err := repo.GetByID(ctx, onPrimary.ID); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("cancelled read neither falls back nor fails replica", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := repo.GetByID(cctx, onPrimary.ID); err == nil {
+			t.Errorf("expected the error of the replica, got nil")
+		}
+		if err := d.CheckReplicas(ctx); err != nil {
+			t.Errorf("expected healthy replica, got %v", err)
+		}
+	})
+}
+
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	dialects(t, func(t *testing.T, setup func(t *testing.T) *db.DB) {
+		d := setup(t)
+		repo := sqldb.NewCatalogRepo(d)
+		defer repo.Drop(ctx)
+		exists := func(id string) bool {
+			_, err := repo.GetByID(ctx, id)
+			return err == nil
+		}
+
+		t.Run("commit", func(t *testing.T) {
+			b := catalog.Book{Title: "Committed"}
+			err := d.Do(ctx, func(ctx context.Context) error {
+				return repo.Create(ctx, &b)
+			})
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if !exists(b.ID) {
+				t.Errorf("expected book %v committed", b.ID)
+			}
+		})
+
+		t.Run("rollback", func(t *testing.T) {
+			b := catalog.Book{Title: "Rolled back"}
+			err := d.Do(ctx, func(ctx context.Context) error {
+				if err := repo.Create(ctx, &b); err != nil {
+					return err
+				}
+				return errFailed
+			})
+			if err != errFailed {
+				t.Fatalf("expected %v, got %v", errFailed, err)
+			}
+			if b.ID == "" || exists(b.ID) {
+				t.Errorf("expected book %q rolled back", b.ID)
+			}
+		})
+
+		t.Run("nested joins outer", func(t *testing.T) {
+			outer, inner := catalog.Book{Title: "Outer"}, catalog.Book{Title: "Inner"}
+			err := d.Do(ctx, func(ctx context.Context) error {
+				if err := repo.Create(ctx, &outer); err != nil {
+					return err
+				}
+				err := d.Do(ctx, func(ctx context.Context) error {
+					return repo.Create(ctx, &inner)
+				})
+				if err != nil {
+					return err
+				}
+				return errFailed
+			})
+			if err != errFailed {
+				t.Fatalf("expected %v, got %v", errFailed, err)
+			}
+			if exists(outer.ID) || exists(inner.ID) {
+				t.Errorf("expected books %q and %q rolled back together", outer.ID, inner.ID)
+			}
+		})
+
+		t.Run("after commit", func(t *testing.T) {
+			var calls []string
+			d.Do(ctx, func(ctx context.Context) error {
+				db.AfterCommit(ctx, func() { calls = append(calls, "committed") })
+				return d.Do(ctx, func(ctx context.Context) error {
+					db.AfterCommit(ctx, func() { calls = append(calls, "nested") })
+					if len(calls) != 0 {
+						t.Errorf("expected no calls before commit, got %v", calls)
+					}
+					return nil
+				})
+			})
+			d.Do(ctx, func(ctx context.Context) error {
+				db.AfterCommit(ctx, func() { calls = append(calls, "rolled back") })
+				return errFailed
+			})
+			db.AfterCommit(ctx, func() { calls = append(calls, "no unit of work") })
+			if got := strings.Join(calls, ","); got != "committed,nested,no unit of work" {
+				t.Errorf("expected committed,nested,no unit of work, got %v", got)
+			}
+		})
+
+		t.Run("cancelled", func(t *testing.T) {
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			called := false
+			err := d.Do(cctx, func(ctx context.Context) error {
+				called = true
+				return nil
+			})
+			if err == nil || called {
+				t.Errorf("expected Do to fail without calling fn, got %v", err)
+			}
+		})
+	})
+}
+
+// failingOrders fails Create once the order is written, like a commit
+// losing the connection.
+type failingOrders struct {
+	order.Repo
+}
+
+func (r failingOrders) Create(ctx context.Context, o *order.Order) error {
+	if err := r.Repo.Create(ctx, o); err != nil {
+		return err
+	}
+	return errors.New("connection lost")
+}
+
+func TestPlaceOrder(t *testing.T) {
+	ctx := context.Background()
+	d := open(t, db.SQLite, filepath.Join(t.TempDir(), "bookshop.db"))
+	books, orders, promos := sqldb.NewCatalogRepo(d), sqldb.NewOrderRepo(d), sqldb.NewPromotionRepo(d)
+	taxes, _ := tax.NewTable(nil)
+	rates, _ := shipping.NewTable(nil)
+	placeOrder := func(ctx context.Context, orders order.Repo) (order.Order, error) {
+		s := order.NewService(orders, d, books, exchange.NewService(money.Rates{Base: "USD"}), promotion.NewService(promos), taxes, rates)
+		return s.PlaceOrder(ctx, order.Cart{
+			Items:  []order.CartItem{{BookID: "go", Quantity: 1}},
+			Coupon: "summer",
+		})
+	}
+
+	book := catalog.Book{ID: "go", Title: "The Go Programming Language", Format: catalog.Ebook, Price: money.New(3000, "USD")}
+	if err := books.Create(ctx, &book); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	promo := promotion.Promotion{ID: "summer", Name: "summer", Kind: promotion.Percentage, Percent: 10, Coupon: "SUMMER", UsageLimit: 1, Active: true}
+	if err := promos.Create(ctx, &promo); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("failing order doesn't use up coupon", func(t *testing.T) {
+		if _, err := placeOrder(ctx, failingOrders{orders}); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+		if p, _ := promos.GetByID(ctx, promo.ID); p.Used != 0 {
+			t.Errorf("expected used 0, got %v", p.Used)
+		}
+	})
+
+	t.Run("order redeems coupon", func(t *testing.T) {
+		o, err := placeOrder(auth.WithPrincipal(ctx, auth.Principal{UserID: "joey"}), orders)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if placed, _ := orders.ListByUser(ctx, "joey"); len(placed) != 1 || placed[0].ID != o.ID {
+			t.Errorf("expected order %v of joey stored, got %v", o.ID, placed)
+		}
+		if p, _ := promos.GetByID(ctx, promo.ID); p.Used != 1 {
+			t.Errorf("expected used 1, got %v", p.Used)
+		}
+	})
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	repo := sqldb.NewCatalogRepo(open(t, db.SQLite, filepath.Join(t.TempDir(), "bookshop.db")))
+	ctx, span := tracing.Tracer().Start(context.Background(), "catalog.Get")
+	b := catalog.Book{Title: "Traced"}
+	if err := repo.Create(ctx, &b); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if _, err := repo.GetByID(ctx, "missing"); err != db.ErrNotFound {
+		t.Fatalf("expected %v, got %v", db.ErrNotFound, err)
+	}
+	span.End()
+
+	queries := make(map[string]bool)
+	for _, s := range recorder.Ended() {
+		if s.Name() == "catalog.Get" {
+			continue
+		}
+		queries[s.Name()] = true
+		if s.Parent().SpanID() != span.SpanContext().SpanID() {
+			t.Errorf("expected %v child of the caller, got parent %v", s.Name(), s.Parent().SpanID())
+		}
+		if s.Status().Code == codes.Error {
+			t.Errorf("expected %v not failed, got %v", s.Name(), s.Status())
+		}
+	}
+	if !queries["db.create"] || !queries["db.query"] {
+		t.Errorf("expected db.create and db.query spans, got %v", queries)
+	}
+}
@@ -0,0 +1,57 @@
+package sqldb
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/kavirajk/bookshop/db"
+)
+
+// Users, books and orders are soft deleted. gorm hides the rows with
+// deleted_at set from every query of their models unless Unscoped.
+
+// softDelete marks row id of model deleted and bumps its version, only if
+// the row still has version. It fails with db.ErrConflict otherwise.
+func softDelete(ctx context.Context, d *db.DB, model interface{}, id string, version int64) error {
+	res := d.Conn(ctx).Model(model).Where("id = ? AND version = ?", id, version).UpdateColumns(map[string]interface{}{
+		"deleted_at": time.Now().UTC(),
+		"version":    gorm.Expr("version + 1"),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		var n int
+		if err := d.Conn(ctx).Model(model).Where("id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return db.ErrNotFound
+		}
+		return db.ErrConflict
+	}
+	return nil
+}
+
+// restore undoes softDelete of row id of model.
+func restore(ctx context.Context, d *db.DB, model interface{}, id string) error {
+	res := d.Conn(ctx).Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).UpdateColumns(map[string]interface{}{
+		"deleted_at": gorm.Expr("NULL"),
+		"version":    gorm.Expr("version + 1"),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+// purge hard deletes rows of model deleted before. Their children go
+// with them by the foreign keys.
+func purge(ctx context.Context, d *db.DB, model interface{}, before time.Time) (int, error) {
+	res := d.Conn(ctx).Unscoped().Where("deleted_at < ?", before.UTC()).Delete(model)
+	return int(res.RowsAffected), res.Error
+}
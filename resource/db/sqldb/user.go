@@ -0,0 +1,243 @@
+package sqldb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/user"
+	"github.com/lib/pq"
+	"github.com/pborman/uuid"
+)
+
+type userRepo struct {
+	db *db.DB
+}
+
+func NewUserRepo(d *db.DB) user.Repo {
+	return &userRepo{db: d}
+}
+
+func (r *userRepo) get(ctx context.Context, where ...interface{}) (user.User, error) {
+	var u user.User
+	d := r.db.Conn(ctx)
+
+	if err := d.First(&u, where...).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return user.New(), db.ErrNotFound
+		}
+		return user.New(), err
+	}
+	return u, nil
+}
+
+func (r *userRepo) GetByID(ctx context.Context, id string) (user.User, error) {
+	return r.get(ctx, "id=?", id)
+}
+
+func (r *userRepo) GetByUserName(ctx context.Context, username string) (user.User, error) {
+	return r.get(ctx, "username=?", username)
+}
+
+func (r *userRepo) GetByEmail(ctx context.Context, email string) (user.User, error) {
+	return r.get(ctx, "email=?", email)
+}
+
+func (r *userRepo) GetByToken(ctx context.Context, token string) (user.User, error) {
+	return r.get(ctx, "auth_token=?", token)
+}
+
+func (r *userRepo) GetByResetKey(ctx context.Context, key string) (user.User, error) {
+	return r.get(ctx, "reset_key=?", key)
+}
+
+func (r *userRepo) List(ctx context.Context, order string, limit, offset int) ([]user.User, int, error) {
+	users := make([]user.User, 0)
+	d := r.db.Conn(ctx)
+
+	var total int
+	if err := d.Model(&user.User{}).Order(order).Count(&total).Error; err != nil {
+		return users, 0, err
+	}
+
+	err := d.Order(order).Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+func (r *userRepo) Create(ctx context.Context, u *user.User) error {
+	d := r.db.Conn(ctx)
+
+	if u.ID == "" {
+		u.ID = NewID()
+	}
+
+	u.Version = 1
+	if err := d.Create(u).Error; err != nil {
+		return duplicate(err)
+	}
+	return nil
+}
+
+func (r *userRepo) Save(ctx context.Context, u *user.User) error {
+	if u.ID == "" {
+		return db.ErrMissingID
+	}
+	return saveVersioned(ctx, r.db, "users", u.ID, &u.Version, u)
+}
+
+func (r *userRepo) Drop(ctx context.Context) error {
+	if err := r.db.Conn(ctx).Exec("DELETE FROM ADDRESSES").Error; err != nil {
+		return err
+	}
+	return r.db.Conn(ctx).Exec("DELETE FROM USERS").Error
+}
+
+// FailLogin increments the counter and reads it in a unit of work: the
+// update locks the row, so concurrent failed logins count one after the
+// other. SQLite before 3.35 has no RETURNING.
+func (r *userRepo) FailLogin(ctx context.Context, id string) (int, error) {
+	var failed int
+	err := r.db.Do(ctx, func(ctx context.Context) error {
+		res := r.db.Exec(ctx, "UPDATE users SET failed_logins = failed_logins + 1, version = version + 1 WHERE id = ? AND deleted_at IS NULL", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return db.ErrNotFound
+		}
+		return r.db.Conn(ctx).Table("users").Where("id = ?", id).Select("failed_logins").Row().Scan(&failed)
+	})
+	return failed, err
+}
+
+func (r *userRepo) LockOut(ctx context.Context, id string, until time.Time) error {
+	res := r.db.Exec(ctx, "UPDATE users SET locked_until = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", until.UTC(), id, until.UTC())
+	return res.Error
+}
+
+func (r *userRepo) ResetFailedLogins(ctx context.Context, id string) error {
+	res := r.db.Exec(ctx, "UPDATE users SET failed_logins = 0, locked_until = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NULL", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) Delete(ctx context.Context, id string, version int64) error {
+	return softDelete(ctx, r.db, &user.User{}, id, version)
+}
+
+func (r *userRepo) ListDeleted(ctx context.Context) ([]user.User, error) {
+	users := make([]user.User, 0)
+	err := r.db.Conn(ctx).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&users).Error
+	return users, err
+}
+
+func (r *userRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, r.db, &user.User{}, id)
+}
+
+func (r *userRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, r.db, &user.User{}, before)
+}
+
+func (r *userRepo) CreateAddress(ctx context.Context, a *user.Address) error {
+	d := r.db.Conn(ctx)
+
+	if a.ID == "" {
+		a.ID = NewID()
+	}
+	return duplicate(d.Create(a).Error)
+}
+
+func (r *userRepo) SaveAddress(ctx context.Context, a *user.Address) error {
+	if a.ID == "" {
+		return db.ErrMissingID
+	}
+	return r.db.Conn(ctx).Save(a).Error
+}
+
+func (r *userRepo) GetAddress(ctx context.Context, id string) (user.Address, error) {
+	var a user.Address
+	if err := r.db.Conn(ctx).First(&a, "id=?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return a, db.ErrNotFound
+		}
+		return a, err
+	}
+	return a, nil
+}
+
+func (r *userRepo) ListAddresses(ctx context.Context, userID string) ([]user.Address, error) {
+	addresses := make([]user.Address, 0)
+	err := r.db.Conn(ctx).Where("user_id=?", userID).Order("name").Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *userRepo) DeleteAddress(ctx context.Context, id string) error {
+	return r.db.Conn(ctx).Delete(&user.Address{}, "id=?", id).Error
+}
+
+// Helpers
+
+// saveVersioned saves value, the row id of table, only if the row still has
+// version, the one value was read with, and bumps the version of both.
+// Version is bumped first and in the same transaction as the save, so that
+// concurrent saves of the same version wait for each other and all but the
+// first fail with db.ErrConflict.
+func saveVersioned(ctx context.Context, d *db.DB, table, id string, version *int64, value interface{}) error {
+	return d.Do(ctx, func(ctx context.Context) error {
+		res := d.Exec(ctx, "UPDATE "+table+" SET version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", id, *version)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var n int
+			if err := d.Conn(ctx).Table(table).Where("id = ? AND deleted_at IS NULL", id).Count(&n).Error; err != nil {
+				return err
+			}
+			if n == 0 {
+				return db.ErrNotFound
+			}
+			return db.ErrConflict
+		}
+		*version++
+		if err := d.Conn(ctx).Save(value).Error; err != nil {
+			*version--
+			return err
+		}
+		return nil
+	})
+}
+
+// duplicate maps unique violations to db.ErrAlreadyExists. SQLite errors
+// are matched by message, so that postgres only builds don't need cgo.
+func duplicate(err error) error {
+	if e, ok := err.(*pq.Error); ok && e.Code == "23505" {
+		return db.ErrAlreadyExists
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "UNIQUE constraint failed") {
+		return db.ErrAlreadyExists
+	}
+	return err
+}
+
+var encoding = base32.NewEncoding("ybndrfg8ejkmcpqxot1uwisza345h769")
+
+// NewID return global uniq indentifier that will be used as
+// primary key.
+func NewID() string {
+	var b bytes.Buffer
+	encoder := base32.NewEncoder(encoding, &b)
+	encoder.Write(uuid.NewRandom())
+	encoder.Close()
+	b.Truncate(26)
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Settings of the gorm queries carrying their span, see init.
+const (
+	contextSetting = "bookshop:context"
+	spanSetting    = "bookshop:span"
+)
+
+// withContext returns q carrying ctx, so that its queries are traced as
+// children of the span of ctx.
+func withContext(ctx context.Context, q *gorm.DB) *gorm.DB {
+	return q.Set(contextSetting, ctx)
+}
+
+// init registers gorm callbacks tracing every query in a span, child of
+// the span of the context given with withContext. They are registered once
+// for all the handles, gorm shares the callbacks of the handles it opens.
+func init() {
+	callbacks := gorm.DefaultCallback
+	for _, c := range []struct {
+		operation string
+		processor func() *gorm.CallbackProcessor
+	}{
+		{"create", callbacks.Create},
+		{"query", callbacks.Query},
+		{"update", callbacks.Update},
+		{"delete", callbacks.Delete},
+		{"row_query", callbacks.RowQuery},
+	} {
+		// Processors register a single callback each.
+		c.processor().Before("gorm:"+c.operation).Register("tracing:start_"+c.operation, startSpan(c.operation))
+		c.processor().After("gorm:"+c.operation).Register("tracing:end_"+c.operation, endSpan)
+	}
+}
+
+func startSpan(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		v, ok := scope.Get(contextSetting)
+		if !ok {
+			return
+		}
+		_, span := tracing.Tracer().Start(v.(context.Context), "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", scope.Dialect().GetName()),
+				attribute.String("db.sql.table", scope.TableName()),
+			),
+		)
+		scope.InstanceSet(spanSetting, span)
+	}
+}
+
+func endSpan(scope *gorm.Scope) {
+	v, ok := scope.InstanceGet(spanSetting)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetAttributes(attribute.String("db.statement", scope.SQL))
+	err := scope.DB().Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// Exec runs the sql statement on the primary in a span, gorm doesn't trace
+// raw statements. See Conn.
+func (d *DB) Exec(ctx context.Context, sql string, values ...interface{}) *gorm.DB {
+	ctx, span := tracing.Tracer().Start(ctx, "db.exec",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", d.Dialect()),
+			attribute.String("db.statement", sql),
+		),
+	)
+	res := d.Conn(ctx).Exec(sql, values...)
+	tracing.End(span, res.Error)
+	return res
+}
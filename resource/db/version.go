@@ -0,0 +1,27 @@
+package db
+
+import "context"
+
+type ifMatchKey struct{}
+
+// WithIfMatch returns ctx carrying the versions the client accepts for the
+// entity it updates, e.g: from If-Match header. Services check them with
+// CheckIfMatch before changing the entity.
+func WithIfMatch(ctx context.Context, versions ...int64) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, versions)
+}
+
+// CheckIfMatch returns ErrPreconditionFailed unless version is one of the
+// versions ctx carries. Ctx without versions matches any version.
+func CheckIfMatch(ctx context.Context, version int64) error {
+	versions, ok := ctx.Value(ifMatchKey{}).([]int64)
+	if !ok {
+		return nil
+	}
+	for _, v := range versions {
+		if v == version {
+			return nil
+		}
+	}
+	return ErrPreconditionFailed
+}
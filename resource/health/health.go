@@ -0,0 +1,159 @@
+// health tells whether the process is alive, and ready to serve by
+// checking the dependencies of every service e.g: the database.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kavirajk/bookshop/transport"
+)
+
+// Statuses of the checks and of the whole process.
+const (
+	OK          = "ok"
+	Failed      = "failed"
+	Degraded    = "degraded"
+	Unavailable = "unavailable"
+	Draining    = "draining"
+)
+
+// Checker checks a dependency is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckFunc is a func as Checker. e.g: CheckFunc(database.Ping).
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Check is a dependency of a service.
+type Check struct {
+	Service string // e.g: catalog
+	Name    string // e.g: db
+	Checker Checker
+
+	// Optional dependencies failing only degrade the service, e.g: reads
+	// bypass the cache while it is down.
+	Optional bool
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// Report is the outcome of all the checks, by service then name.
+type Report struct {
+	Status   string                       `json:"status"`
+	Services map[string]map[string]Result `json:"services,omitempty"`
+}
+
+// Health runs the checks of the services.
+type Health struct {
+	timeout  time.Duration
+	mu       sync.Mutex
+	checks   []Check
+	draining int32 // atomic, 1 once draining
+}
+
+// New returns Health giving every check timeout to pass.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Register adds checks.
+func (h *Health) Register(checks ...Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, checks...)
+}
+
+// Drain makes the process unready from now on, so that load balancers stop
+// sending it requests before it shuts down.
+func (h *Health) Drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+// Ready runs all the checks concurrently and reports their outcome. The
+// process is unavailable if a required check fails, degraded if an
+// optional one does.
+func (h *Health) Ready(ctx context.Context) Report {
+	h.mu.Lock()
+	checks := append([]Check(nil), h.checks...)
+	h.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			results[i] = Result{Status: OK, Optional: c.Optional}
+			if err := c.Checker.Check(ctx); err != nil {
+				results[i].Status = Failed
+				results[i].Error = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	r := Report{Status: OK, Services: make(map[string]map[string]Result)}
+	for i, c := range checks {
+		if r.Services[c.Service] == nil {
+			r.Services[c.Service] = make(map[string]Result)
+		}
+		r.Services[c.Service][c.Name] = results[i]
+		switch {
+		case results[i].Status == OK:
+		case c.Optional && r.Status == OK:
+			r.Status = Degraded
+		case !c.Optional:
+			r.Status = Unavailable
+		}
+	}
+	if atomic.LoadInt32(&h.draining) == 1 {
+		r.Status = Draining
+	}
+	return r
+}
+
+// LiveHandler returns handler of /healthz, it passes as long as the process
+// serves requests. Dependencies are left to ReadyHandler, so that a
+// database outage doesn't get the process restarted.
+func (h *Health) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		encode(w, http.StatusOK, Report{Status: OK})
+	})
+}
+
+// ReadyHandler returns handler of /readyz, it fails with 503 if the process
+// is unavailable or draining.
+func (h *Health) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := h.Ready(req.Context())
+		status := http.StatusOK
+		if r.Status == Unavailable || r.Status == Draining {
+			status = http.StatusServiceUnavailable
+		}
+		encode(w, status, r)
+	})
+}
+
+func encode(w http.ResponseWriter, status int, r Report) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", transport.CacheNoStore)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(transport.FormatResponse{Data: r, Meta: transport.MetaResponse{Status: status}})
+}
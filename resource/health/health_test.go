@@ -0,0 +1,90 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kavirajk/bookshop/health"
+)
+
+var (
+	pass = health.CheckFunc(func(context.Context) error { return nil })
+	fail = health.CheckFunc(func(context.Context) error { return errors.New("connection refused") })
+	hang = health.CheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+)
+
+func TestReady(t *testing.T) {
+	cases := []struct {
+		name   string
+		checks []health.Check
+		status string
+		code   int
+	}{
+		{"no checks", nil, health.OK, http.StatusOK},
+		{
+			"all pass",
+			[]health.Check{{Service: "users", Name: "db", Checker: pass}, {Service: "catalog", Name: "db", Checker: pass}},
+			health.OK, http.StatusOK,
+		},
+		{
+			"optional fails",
+			[]health.Check{{Service: "catalog", Name: "db", Checker: pass}, {Service: "catalog", Name: "cache", Checker: fail, Optional: true}},
+			health.Degraded, http.StatusOK,
+		},
+		{
+			"required fails",
+			[]health.Check{{Service: "catalog", Name: "cache", Checker: fail, Optional: true}, {Service: "catalog", Name: "db", Checker: fail}},
+			health.Unavailable, http.StatusServiceUnavailable,
+		},
+		{
+			"times out",
+			[]health.Check{{Service: "orders", Name: "db", Checker: hang}},
+			health.Unavailable, http.StatusServiceUnavailable,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := health.New(10 * time.Millisecond)
+			h.Register(c.checks...)
+
+			w := httptest.NewRecorder()
+			h.ReadyHandler().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+			var body struct{ Data health.Report }
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if w.Code != c.code || body.Data.Status != c.status {
+				t.Errorf("expected %v %v, got %v %v", c.code, c.status, w.Code, body.Data.Status)
+			}
+			for _, check := range c.checks {
+				if _, ok := body.Data.Services[check.Service][check.Name]; !ok {
+					t.Errorf("expected result of %v %v, got %v", check.Service, check.Name, body.Data.Services)
+				}
+			}
+		})
+	}
+
+	t.Run("draining", func(t *testing.T) {
+		h := health.New(time.Second)
+		h.Register(health.Check{Service: "users", Name: "db", Checker: pass})
+		h.Drain()
+		if r := h.Ready(context.Background()); r.Status != health.Draining {
+			t.Errorf("expected %v, got %v", health.Draining, r.Status)
+		}
+
+		// Still alive while draining.
+		w := httptest.NewRecorder()
+		h.LiveHandler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200, got %v", w.Code)
+		}
+	})
+}
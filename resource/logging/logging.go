@@ -0,0 +1,104 @@
+// logging correlates the log lines of a request by its ID, and keeps secrets
+// out of the logs.
+package logging
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+// Formats of the logs.
+const (
+	Logfmt = "logfmt"
+	JSON   = "json"
+)
+
+// Redacted replaces the values of secret keys.
+const Redacted = "REDACTED"
+
+var ErrUnknownFormat = errors.New("unknown log format, want logfmt or json")
+
+// New returns logger writing to w in format, timestamped and redacting the
+// secrets, see Redacting.
+func New(w io.Writer, format string) (log.Logger, error) {
+	var logger log.Logger
+	switch format {
+	case Logfmt, "":
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(w))
+	case JSON:
+		logger = log.NewJSONLogger(log.NewSyncWriter(w))
+	default:
+		return nil, ErrUnknownFormat
+	}
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+	return Redacting(logger), nil
+}
+
+type ctxKey int
+
+const requestIDKey ctxKey = iota
+
+// WithRequestID returns ctx carrying the ID of the request it serves.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the ID of the request ctx serves, empty if none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// FromContext returns logger logging the ID of the request ctx serves with
+// every line.
+func FromContext(ctx context.Context, logger log.Logger) log.Logger {
+	if id := RequestID(ctx); id != "" {
+		return log.With(logger, "request_id", id)
+	}
+	return logger
+}
+
+type redactingLogger struct {
+	next log.Logger
+}
+
+// Redacting returns logger replacing the values of secret keys with
+// Redacted, e.g: password, new_password, token and key, so that a
+// middleware logging the arguments of a call can't leak them.
+func Redacting(next log.Logger) log.Logger {
+	return redactingLogger{next}
+}
+
+func (l redactingLogger) Log(keyvals ...interface{}) error {
+	var redacted []interface{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok || !Secret(key) {
+			continue
+		}
+		if redacted == nil {
+			// keyvals may be shared by the contexts of the logger.
+			redacted = append([]interface{}(nil), keyvals...)
+		}
+		redacted[i+1] = Redacted
+	}
+	if redacted != nil {
+		keyvals = redacted
+	}
+	return l.next.Log(keyvals...)
+}
+
+// Secret tells whether the value of key is a secret.
+func Secret(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range []string{"password", "token", "secret", "key", "authorization", "cookie"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
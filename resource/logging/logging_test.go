@@ -0,0 +1,50 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/kavirajk/bookshop/logging"
+)
+
+func TestRedacting(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.With(logging.Redacting(log.NewLogfmtLogger(&buf)), "auth_token", "t0ken")
+	logger.Log("method", "login", "password", "hunter2", "new_password", "hunter3", "reset_key", "k3y", "user_id", "42")
+
+	line := buf.String()
+	for _, secret := range []string{"t0ken", "hunter2", "hunter3", "k3y"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("expected %v redacted, got %v", secret, line)
+		}
+	}
+	if !strings.Contains(line, "method=login") || !strings.Contains(line, "user_id=42") {
+		t.Errorf("expected method and user_id kept, got %v", line)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.JSON)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	logging.FromContext(ctx, logger).Log("method", "get", "token", "t0ken")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %v: %v", buf.String(), err)
+	}
+	if line["request_id"] != "req-1" || line["token"] != logging.Redacted || line["ts"] == nil {
+		t.Errorf("expected request_id, redacted token and ts, got %v", line)
+	}
+
+	if _, err := logging.New(&buf, "xml"); err != logging.ErrUnknownFormat {
+		t.Errorf("expected %v, got %v", logging.ErrUnknownFormat, err)
+	}
+}
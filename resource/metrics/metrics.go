@@ -0,0 +1,182 @@
+// metrics exposes the bookshop metrics to Prometheus: requests of the http
+// routes and of the services, database pool and queries. Durations are
+// histograms in seconds, so that they aggregate across instances.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace of the bookshop metrics.
+const Namespace = "api"
+
+// QueryBuckets are the buckets of the query durations in seconds, finer
+// than the request ones as most queries take a few milliseconds.
+var QueryBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// Service returns the request counter and duration histogram of the
+// service name, e.g: catalog, for its InstrumentingMiddleware. They are
+// labelled by method and error.
+func Service(name string) (metrics.Counter, metrics.Histogram) {
+	fieldKeys := []string{"method", "error"}
+	return kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: name + "_service",
+			Name:      "request_count",
+			Help:      "Number of requests received",
+		}, fieldKeys),
+		kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: name + "_service",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests in seconds",
+			Buckets:   stdprometheus.DefBuckets,
+		}, fieldKeys)
+}
+
+// Queries returns the histogram of the durations of the repo methods, for
+// the InstrumentingRepo of every domain. It is labelled by repo, method
+// and error.
+func Queries() metrics.Histogram {
+	return kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of repo queries in seconds",
+		Buckets:   QueryBuckets,
+	}, []string{"repo", "method", "error"})
+}
+
+// HTTPMetrics are the metrics of the http requests, labelled by method,
+// route and status, see HTTP.
+type HTTPMetrics struct {
+	Requests metrics.Counter
+	Duration metrics.Histogram
+}
+
+// NewHTTPMetrics returns the HTTPMetrics exported to Prometheus.
+func NewHTTPMetrics() HTTPMetrics {
+	fieldKeys := []string{"method", "route", "status"}
+	return HTTPMetrics{
+		Requests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of http requests by route and status",
+		}, fieldKeys),
+		Duration: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of http requests in seconds by route and status",
+			Buckets:   stdprometheus.DefBuckets,
+		}, fieldKeys),
+	}
+}
+
+// Unmatched is the route label of the requests matching no route, so that
+// scans of random paths don't make a series each.
+const Unmatched = "unmatched"
+
+// OtherMethod is the method label of the requests with a non-standard
+// method, so that clients can't make a series per method they send.
+const OtherMethod = "other"
+
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// HTTP serves the requests with router, counting and timing them in m by
+// the path template of the route they match, e.g: /catalog/v1/{id}.
+func HTTP(router *mux.Router, m HTTPMetrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		begin := time.Now()
+		route := Unmatched
+		var match mux.RouteMatch
+		if router.Match(req, &match) && match.Route != nil {
+			if tmpl, err := match.Route.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		router.ServeHTTP(sw, req)
+
+		method := req.Method
+		if !methods[method] {
+			method = OtherMethod
+		}
+		lvs := []string{"method", method, "route", route, "status", strconv.Itoa(sw.status)}
+		m.Requests.With(lvs...).Add(1)
+		m.Duration.With(lvs...).Observe(time.Since(begin).Seconds())
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// DBPool returns a collector of the stats of the connection pool, to
+// register with prometheus.MustRegister.
+func DBPool(pool *sql.DB) stdprometheus.Collector {
+	return dbPool{pool: pool}
+}
+
+type dbPool struct {
+	pool *sql.DB
+}
+
+func poolDesc(name, help string) *stdprometheus.Desc {
+	return stdprometheus.NewDesc(stdprometheus.BuildFQName(Namespace, "db_pool", name), help, nil, nil)
+}
+
+var (
+	maxOpenDesc      = poolDesc("max_open_connections", "Maximum number of open connections")
+	openDesc         = poolDesc("open_connections", "Number of open connections, in use or idle")
+	inUseDesc        = poolDesc("in_use_connections", "Number of connections in use")
+	idleDesc         = poolDesc("idle_connections", "Number of idle connections")
+	waitCountDesc    = poolDesc("wait_count_total", "Number of connections waited for")
+	waitDurationDesc = poolDesc("wait_duration_seconds_total", "Time spent waiting for connections in seconds")
+	idleClosedDesc   = poolDesc("max_idle_closed_total", "Number of connections closed as idle beyond the maximum")
+	lifeClosedDesc   = poolDesc("max_lifetime_closed_total", "Number of connections closed beyond their maximum lifetime")
+)
+
+func (c dbPool) Describe(ch chan<- *stdprometheus.Desc) {
+	for _, d := range []*stdprometheus.Desc{maxOpenDesc, openDesc, inUseDesc, idleDesc, waitCountDesc, waitDurationDesc, idleClosedDesc, lifeClosedDesc} {
+		ch <- d
+	}
+}
+
+func (c dbPool) Collect(ch chan<- stdprometheus.Metric) {
+	s := c.pool.Stats()
+	ch <- stdprometheus.MustNewConstMetric(maxOpenDesc, stdprometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- stdprometheus.MustNewConstMetric(openDesc, stdprometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- stdprometheus.MustNewConstMetric(inUseDesc, stdprometheus.GaugeValue, float64(s.InUse))
+	ch <- stdprometheus.MustNewConstMetric(idleDesc, stdprometheus.GaugeValue, float64(s.Idle))
+	ch <- stdprometheus.MustNewConstMetric(waitCountDesc, stdprometheus.CounterValue, float64(s.WaitCount))
+	ch <- stdprometheus.MustNewConstMetric(waitDurationDesc, stdprometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- stdprometheus.MustNewConstMetric(idleClosedDesc, stdprometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- stdprometheus.MustNewConstMetric(lifeClosedDesc, stdprometheus.CounterValue, float64(s.MaxLifetimeClosed))
+}
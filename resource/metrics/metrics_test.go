@@ -0,0 +1,99 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/gorilla/mux"
+	bsmetrics "github.com/kavirajk/bookshop/metrics"
+	_ "github.com/mattn/go-sqlite3"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// observed records the label values of every observation, joined by a
+// space.
+type observed struct {
+	lvs  []string
+	seen *[]string
+}
+
+func (o observed) With(lvs ...string) metrics.Counter {
+	return observed{lvs: append(append([]string{}, o.lvs...), lvs...), seen: o.seen}
+}
+
+func (o observed) Add(float64) { *o.seen = append(*o.seen, strings.Join(o.lvs, " ")) }
+
+type observedHistogram struct{ observed }
+
+func (o observedHistogram) With(lvs ...string) metrics.Histogram {
+	return observedHistogram{o.observed.With(lvs...).(observed)}
+}
+
+func (o observedHistogram) Observe(v float64) { o.Add(v) }
+
+func TestHTTP(t *testing.T) {
+	var requests, durations []string
+	m := bsmetrics.HTTPMetrics{
+		Requests: observed{seen: &requests},
+		Duration: observedHistogram{observed{seen: &durations}},
+	}
+	router := mux.NewRouter()
+	sub := router.PathPrefix("/catalog/v1").Subrouter()
+	sub.Handle("/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})).Methods("GET")
+	h := bsmetrics.HTTP(router, m)
+
+	for _, url := range []string{"/catalog/v1/42", "/catalog/v1/43", "/wp-login.php"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	for _, method := range []string{"POST", "XYZZY"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/wp-login.php", nil))
+	}
+
+	expected := []string{
+		"method GET route /catalog/v1/{id} status 404",
+		"method GET route /catalog/v1/{id} status 404",
+		"method GET route unmatched status 404",
+		"method POST route unmatched status 404",
+		"method other route unmatched status 404",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+	if len(durations) != len(expected) {
+		t.Errorf("expected %v durations, got %v", len(expected), len(durations))
+	}
+}
+
+func TestDBPool(t *testing.T) {
+	pool, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	defer pool.Close()
+	pool.SetMaxOpenConns(3)
+
+	registry := stdprometheus.NewRegistry()
+	registry.MustRegister(bsmetrics.DBPool(pool))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	values := map[string]float64{}
+	for _, f := range families {
+		if m := f.GetMetric()[0]; m.GetGauge() != nil {
+			values[f.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+	if got := values["api_db_pool_max_open_connections"]; got != 3 {
+		t.Errorf("expected 3 max open connections, got %v", got)
+	}
+	if len(families) != 8 {
+		t.Errorf("expected 8 pool metrics, got %v", len(families))
+	}
+}
@@ -0,0 +1,287 @@
+// money provides an exact decimal money type used by all the services.
+// Amounts are kept as integer minor units (e.g: cents) along with an
+// ISO 4217 currency code, so summing and discounting never loses precision.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrPrecision        = errors.New("amount exceeds currency precision")
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+const (
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	INR Currency = "INR"
+	AUD Currency = "AUD"
+	CAD Currency = "CAD"
+	JPY Currency = "JPY"
+	KWD Currency = "KWD"
+)
+
+// exponents holds the number of minor unit digits for every supported currency.
+var exponents = map[Currency]int{
+	USD: 2,
+	EUR: 2,
+	GBP: 2,
+	INR: 2,
+	AUD: 2,
+	CAD: 2,
+	JPY: 0,
+	KWD: 3,
+}
+
+// ParseCurrency returns the Currency for the given code. Code is case insensitive.
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !c.Valid() {
+		return "", errors.Wrap(ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// Valid reports whether c is a supported currency.
+func (c Currency) Valid() bool {
+	_, ok := exponents[c]
+	return ok
+}
+
+// Exponent returns number of minor unit digits of the currency.
+// e.g: 2 for USD (cents), 0 for JPY.
+func (c Currency) Exponent() int {
+	if e, ok := exponents[c]; ok {
+		return e
+	}
+	return 2
+}
+
+// RoundingMode decides how fractional minor units are rounded.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to nearest, ties to even (bankers rounding).
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to nearest, ties away from zero.
+	RoundHalfUp
+	// RoundDown rounds towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// Money represents an amount in minor units of a currency.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// New returns Money of amount minor units in currency c.
+func New(amount int64, c Currency) Money {
+	return Money{Amount: amount, Currency: c}
+}
+
+// Zero returns zero amount in currency c.
+func Zero(c Currency) Money {
+	return Money{Currency: c}
+}
+
+// Parse parses decimal string s e.g: "12.34" into Money of currency c.
+// It fails with ErrPrecision if s has more digits than the currency allows.
+func Parse(s string, c Currency) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, errors.Wrap(ErrInvalidAmount, s)
+	}
+	r.Mul(r, scale(c))
+	if !r.IsInt() {
+		return Money{}, errors.Wrap(ErrPrecision, s)
+	}
+	return fromInt(r.Num(), c)
+}
+
+// FromFloat converts legacy float64 amounts into Money, rounding to the
+// currency precision with mode. Use it only to import old data.
+func FromFloat(f float64, c Currency, mode RoundingMode) (Money, error) {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		return Money{}, errors.Wrap(ErrInvalidAmount, fmt.Sprint(f))
+	}
+	return fromInt(round(r.Mul(r, scale(c)), mode), c)
+}
+
+// Add returns m + o. Both must be of same currency.
+// It fails with ErrInvalidAmount if the sum overflows.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return fromInt(new(big.Int).Add(big.NewInt(m.Amount), big.NewInt(o.Amount)), m.Currency)
+}
+
+// Sub returns m - o. Both must be of same currency.
+// It fails with ErrInvalidAmount if the difference overflows.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return fromInt(new(big.Int).Sub(big.NewInt(m.Amount), big.NewInt(o.Amount)), m.Currency)
+}
+
+// Mul returns m multiplied by n, e.g: price of n copies.
+// It fails with ErrInvalidAmount if the product overflows.
+func (m Money) Mul(n int64) (Money, error) {
+	return fromInt(new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(n)), m.Currency)
+}
+
+// MulRat returns m multiplied by r, rounded to minor units with mode.
+// e.g: MulRat(big.NewRat(15, 100), RoundHalfUp) gives 15% of m.
+func (m Money) MulRat(r *big.Rat, mode RoundingMode) Money {
+	x := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), r)
+	return Money{Amount: round(x, mode).Int64(), Currency: m.Currency}
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// IsZero reports whether amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether amount is less than zero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Cmp compares m and o, returning -1, 0 or +1. Both must be of same currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Rat returns the amount in major units as exact rational number.
+func (m Money) Rat() *big.Rat {
+	r := new(big.Rat).SetInt64(m.Amount)
+	return r.Quo(r, scale(m.Currency))
+}
+
+// Decimal returns amount in major units e.g: "12.34".
+func (m Money) Decimal() string {
+	return m.Rat().FloatString(m.Currency.Exponent())
+}
+
+// String returns human readable form e.g: "12.34 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+type jsonMoney struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as decimal string so that clients never
+// need to deal with floating point. e.g: {"amount": "12.34", "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes json produced by MarshalJSON.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var j jsonMoney
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	c, err := ParseCurrency(string(j.Currency))
+	if err != nil {
+		return err
+	}
+	v, err := Parse(j.Amount, c)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Sum adds all the ms. Returns zero amount of c if ms is empty.
+func Sum(c Currency, ms ...Money) (Money, error) {
+	total := Zero(c)
+	for _, m := range ms {
+		var err error
+		if total, err = total.Add(m); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// scale returns 10^exponent of c.
+func scale(c Currency) *big.Rat {
+	e := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.Exponent())), nil)
+	return new(big.Rat).SetInt(e)
+}
+
+func fromInt(i *big.Int, c Currency) (Money, error) {
+	if !i.IsInt64() {
+		return Money{}, errors.Wrap(ErrInvalidAmount, "overflow")
+	}
+	return Money{Amount: i.Int64(), Currency: c}, nil
+}
+
+// round rounds r to an integer with mode.
+func round(r *big.Rat, mode RoundingMode) *big.Int {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+	sign := big.NewInt(int64(r.Sign()))
+
+	// cmp compares twice the remainder with the denominator to find out
+	// whether we are below, at or above the half way.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	cmp := twice.Cmp(r.Denom())
+
+	switch mode {
+	case RoundDown:
+		return q
+	case RoundUp:
+		return q.Add(q, sign)
+	case RoundHalfUp:
+		if cmp >= 0 {
+			return q.Add(q, sign)
+		}
+		return q
+	default: // RoundHalfEven
+		if cmp > 0 || (cmp == 0 && q.Bit(0) == 1) {
+			return q.Add(q, sign)
+		}
+		return q
+	}
+}
@@ -0,0 +1,136 @@
+package money_test
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/kavirajk/bookshop/money"
+	"github.com/pkg/errors"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in       string
+		currency money.Currency
+		amount   int64
+		fails    bool
+	}{
+		{"12.34", money.USD, 1234, false},
+		{"12.3", money.USD, 1230, false},
+		{"-0.05", money.EUR, -5, false},
+		{"1500", money.JPY, 1500, false},
+		{"1.234", money.KWD, 1234, false},
+		{"12.345", money.USD, 0, true},
+		{"1.5", money.JPY, 0, true},
+		{"abc", money.USD, 0, true},
+	}
+	for _, c := range cases {
+		m, err := money.Parse(c.in, c.currency)
+		if c.fails {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected nil error, got %v", c.in, err)
+		}
+		if m.Amount != c.amount {
+			t.Errorf("%s: expected %d, got %d", c.in, c.amount, m.Amount)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	t.Run("sum has no rounding error", func(t *testing.T) {
+		// 0.1 + 0.2 is the classic float64 trap.
+		a, _ := money.Parse("0.10", money.USD)
+		b, _ := money.Parse("0.20", money.USD)
+		s, err := money.Sum(money.USD, a, b)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if s.Decimal() != "0.30" {
+			t.Errorf("expected 0.30, got %v", s.Decimal())
+		}
+	})
+	t.Run("currency mismatch", func(t *testing.T) {
+		_, err := money.New(100, money.USD).Add(money.New(100, money.EUR))
+		if err != money.ErrCurrencyMismatch {
+			t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+		}
+	})
+	t.Run("overflow", func(t *testing.T) {
+		max := money.New(math.MaxInt64, money.USD)
+		min := money.New(math.MinInt64, money.USD)
+		one := money.New(1, money.USD)
+		if _, err := max.Add(one); errors.Cause(err) != money.ErrInvalidAmount {
+			t.Errorf("expected %v, got %v", money.ErrInvalidAmount, err)
+		}
+		if _, err := min.Sub(one); errors.Cause(err) != money.ErrInvalidAmount {
+			t.Errorf("expected %v, got %v", money.ErrInvalidAmount, err)
+		}
+		if _, err := max.Mul(2); errors.Cause(err) != money.ErrInvalidAmount {
+			t.Errorf("expected %v, got %v", money.ErrInvalidAmount, err)
+		}
+		if m, err := min.Add(one); err != nil || m.Amount != math.MinInt64+1 {
+			t.Errorf("expected %d, got %d (%v)", int64(math.MinInt64+1), m.Amount, err)
+		}
+		if m, err := one.Mul(-3); err != nil || m.Amount != -3 {
+			t.Errorf("expected -3, got %d (%v)", m.Amount, err)
+		}
+	})
+	t.Run("rounding modes", func(t *testing.T) {
+		// 12.5% of 1.00 is 12.5 cents
+		m := money.New(100, money.USD)
+		r := big.NewRat(125, 1000)
+		cases := []struct {
+			mode money.RoundingMode
+			want int64
+		}{
+			{money.RoundHalfEven, 12},
+			{money.RoundHalfUp, 13},
+			{money.RoundDown, 12},
+			{money.RoundUp, 13},
+		}
+		for _, c := range cases {
+			if got := m.MulRat(r, c.mode).Amount; got != c.want {
+				t.Errorf("mode %d: expected %d, got %d", c.mode, c.want, got)
+			}
+			if got := m.Neg().MulRat(r, c.mode).Amount; got != -c.want {
+				t.Errorf("mode %d: expected %d, got %d", c.mode, -c.want, got)
+			}
+		}
+	})
+}
+
+func TestFromFloat(t *testing.T) {
+	m, err := money.FromFloat(19.99, money.USD, money.RoundHalfEven)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if m.Amount != 1999 {
+		t.Errorf("expected 1999, got %d", m.Amount)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	m := money.New(1999, money.USD)
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if string(b) != `{"amount":"19.99","currency":"USD"}` {
+		t.Errorf("unexpected json %s", b)
+	}
+
+	var got money.Money
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got != m {
+		t.Errorf("expected %v, got %v", m, got)
+	}
+}
@@ -0,0 +1,194 @@
+package money
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoRate       = errors.New("no exchange rate")
+	ErrInvalidRates = errors.New("invalid exchange rates")
+)
+
+// Rates is a snapshot of exchange rates relative to a Base currency.
+// Every rate tells how many units of a currency one unit of Base buys.
+type Rates struct {
+	Base  Currency
+	AsOf  time.Time
+	Rates map[Currency]*big.Rat
+}
+
+// RateSource provides the current exchange rates snapshot.
+type RateSource interface {
+	Rates(ctx context.Context) (Rates, error)
+}
+
+// Rate returns the rate of currency c relative to Base.
+func (r Rates) Rate(c Currency) (*big.Rat, error) {
+	if c == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+	rate, ok := r.Rates[c]
+	if !ok || rate.Sign() <= 0 {
+		return nil, errors.Wrap(ErrNoRate, string(c))
+	}
+	return rate, nil
+}
+
+// Convert converts m into currency to, rounding to minor units with mode.
+// Conversion between two non-base currencies goes through Base.
+func (r Rates) Convert(m Money, to Currency, mode RoundingMode) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	from, err := r.Rate(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	target, err := r.Rate(to)
+	if err != nil {
+		return Money{}, err
+	}
+	x := m.Rat()
+	x.Quo(x, from)
+	x.Mul(x, target)
+	x.Mul(x, scale(to))
+	return fromInt(round(x, mode), to)
+}
+
+// Subset returns a copy of r holding only the rates of cs.
+// Useful to record just the rates a calculation depends on.
+func (r Rates) Subset(cs ...Currency) Rates {
+	s := Rates{Base: r.Base, AsOf: r.AsOf, Rates: make(map[Currency]*big.Rat)}
+	for _, c := range cs {
+		if rate, ok := r.Rates[c]; ok {
+			s.Rates[c] = new(big.Rat).Set(rate)
+		}
+	}
+	return s
+}
+
+// Validate checks for valid base currency and positive rates.
+func (r Rates) Validate() error {
+	if !r.Base.Valid() {
+		return errors.Wrap(ErrInvalidRates, "base")
+	}
+	for c, rate := range r.Rates {
+		if !c.Valid() {
+			return errors.Wrap(ErrInvalidRates, string(c))
+		}
+		if rate == nil || rate.Sign() <= 0 {
+			return errors.Wrap(ErrInvalidRates, string(c))
+		}
+	}
+	return nil
+}
+
+// IsZero reports whether r is an empty snapshot.
+func (r Rates) IsZero() bool {
+	return r.Base == "" && len(r.Rates) == 0
+}
+
+type jsonRates struct {
+	Base  Currency            `json:"base"`
+	AsOf  time.Time           `json:"as_of"`
+	Rates map[Currency]string `json:"rates"`
+}
+
+// MarshalJSON encodes rates as decimal strings.
+// e.g: {"base": "USD", "as_of": "...", "rates": {"EUR": "0.92"}}
+func (r Rates) MarshalJSON() ([]byte, error) {
+	j := jsonRates{Base: r.Base, AsOf: r.AsOf, Rates: make(map[Currency]string)}
+	for c, rate := range r.Rates {
+		j.Rates[c] = ratString(rate)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes json produced by MarshalJSON.
+func (r *Rates) UnmarshalJSON(b []byte) error {
+	var j jsonRates
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	rates := Rates{Base: j.Base, AsOf: j.AsOf, Rates: make(map[Currency]*big.Rat)}
+	for c, s := range j.Rates {
+		rate, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return errors.Wrap(ErrInvalidRates, string(c))
+		}
+		rates.Rates[c] = rate
+	}
+	*r = rates
+	return nil
+}
+
+// Value stores the snapshot as json text column.
+func (r Rates) Value() (driver.Value, error) {
+	if r.IsZero() {
+		return nil, nil
+	}
+	b, err := r.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads the snapshot stored by Value.
+func (r *Rates) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = Rates{}
+		return nil
+	case string:
+		return r.UnmarshalJSON([]byte(v))
+	case []byte:
+		return r.UnmarshalJSON(v)
+	}
+	return errors.Wrap(ErrInvalidRates, "unsupported column type")
+}
+
+// ReadRates reads json encoded rates from reader and validates them.
+func ReadRates(rd io.Reader) (Rates, error) {
+	var r Rates
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return Rates{}, errors.Wrap(ErrInvalidRates, err.Error())
+	}
+	if err := r.Validate(); err != nil {
+		return Rates{}, err
+	}
+	return r, nil
+}
+
+// LoadRatesFile reads rates from json file at path.
+func LoadRatesFile(path string) (Rates, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Rates{}, err
+	}
+	defer f.Close()
+	return ReadRates(f)
+}
+
+// ratString formats rate as plain decimal whenever it is exact,
+// falling back to "a/b" form so that no precision is lost.
+func ratString(r *big.Rat) string {
+	const maxDigits = 12
+	x := new(big.Rat).Set(r)
+	ten := big.NewRat(10, 1)
+	for p := 0; p <= maxDigits; p++ {
+		if x.IsInt() {
+			return r.FloatString(p)
+		}
+		x.Mul(x, ten)
+	}
+	return r.RatString()
+}
@@ -0,0 +1,64 @@
+package money_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kavirajk/bookshop/money"
+)
+
+const ratesJSON = `{
+	"base": "USD",
+	"as_of": "2017-06-01T00:00:00Z",
+	"rates": {"EUR": "0.8", "INR": "64.5", "JPY": "110"}
+}`
+
+func TestConvert(t *testing.T) {
+	rates, err := money.ReadRates(strings.NewReader(ratesJSON))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	cases := []struct {
+		from money.Money
+		to   money.Currency
+		want string
+	}{
+		{money.New(1000, money.USD), money.EUR, "8.00 EUR"},
+		{money.New(800, money.EUR), money.USD, "10.00 USD"},
+		{money.New(1000, money.EUR), money.JPY, "1375 JPY"},
+		{money.New(999, money.USD), money.INR, "644.36 INR"},
+		{money.New(999, money.USD), money.USD, "9.99 USD"},
+	}
+	for _, c := range cases {
+		got, err := rates.Convert(c.from, c.to, money.RoundHalfEven)
+		if err != nil {
+			t.Errorf("%v: expected nil error, got %v", c.from, err)
+		}
+		if got.String() != c.want {
+			t.Errorf("%v: expected %v, got %v", c.from, c.want, got)
+		}
+	}
+
+	t.Run("missing rate", func(t *testing.T) {
+		_, err := rates.Convert(money.New(100, money.USD), money.GBP, money.RoundHalfEven)
+		if err == nil {
+			t.Errorf("expected ErrNoRate, got nil")
+		}
+	})
+}
+
+func TestRatesRoundTrip(t *testing.T) {
+	rates, _ := money.ReadRates(strings.NewReader(ratesJSON))
+	v, err := rates.Subset(money.EUR).Value()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	var got money.Rates
+	if err := got.Scan(v); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(got.Rates) != 1 || got.Rates[money.EUR].FloatString(1) != "0.8" {
+		t.Errorf("unexpected rates %v", got.Rates)
+	}
+}
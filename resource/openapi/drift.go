@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/transport"
+)
+
+// Drift returns how the routes registered in router and the ones of s
+// differ, e.g: a route missing from the spec. Both are the same if it is
+// empty.
+func Drift(router *mux.Router, s Service) []string {
+	var drift []string
+	routes := transport.ListRoutes(router)
+	routed := make(map[string]bool)
+	for _, r := range routes {
+		if len(r.Methods) == 0 {
+			drift = append(drift, fmt.Sprintf("%s: unreachable, an earlier route matches its requests", r.Path))
+		}
+		for _, method := range r.Methods {
+			routed[method+" "+r.Path] = true
+		}
+	}
+	declared := make(map[string]bool)
+	for _, r := range s.Routes {
+		declared[r.Method+" "+r.Path] = true
+		if !routed[r.Method+" "+r.Path] {
+			drift = append(drift, fmt.Sprintf("%s %s: in the spec but not routed", r.Method, r.Path))
+		}
+	}
+	for _, r := range routes {
+		for _, method := range r.Methods {
+			if method != "HEAD" && !declared[method+" "+r.Path] {
+				drift = append(drift, fmt.Sprintf("%s %s: routed but not in the spec", method, r.Path))
+			}
+		}
+	}
+	return drift
+}
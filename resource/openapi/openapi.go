@@ -0,0 +1,294 @@
+// openapi describes the HTTP API of the services as an OpenAPI 3 document,
+// generated from the routes every service declares and the Go types of
+// their requests and responses.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/transport"
+)
+
+// Version of the OpenAPI specification of the documents.
+const Version = "3.0.3"
+
+// Service is the API of a service.
+type Service struct {
+	// Name tags the operations of the service, e.g: catalog.
+	Name   string
+	Routes []Route
+	// Errors are how the errors of the service are sent, see
+	// transport.Registry.Infos.
+	Errors []transport.ErrorInfo
+}
+
+// Route is an operation of a service, as registered in its router.
+type Route struct {
+	// Name is the unique id of the operation, e.g: searchBooks.
+	Name   string
+	Method string
+	// Path is the mux path template, e.g: /catalog/v1/{id}.
+	Path    string
+	Summary string
+	Query   []Param
+	// Request is the type of the JSON body, nil if it has none.
+	Request interface{}
+	// Response is the type of the data in the envelope, nil if it has
+	// none.
+	Response interface{}
+	// Status of success, 200 if 0.
+	Status int
+	// Auth tells whether the user's auth token is needed.
+	Auth bool
+	// Permissions the user needs any of, see auth.Require. They imply
+	// Auth.
+	Permissions []auth.Permission
+}
+
+// Param is a query parameter of a route.
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	// Type is the JSON type of the value, string if empty.
+	Type string
+}
+
+// Document is an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info is the metadata of the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem is the operations of a path, by method.
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+// Operation is a single route of the API.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of an operation.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components are the schemas and security schemes the operations refer to.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is how operations are authenticated.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// tokenAuth is the security scheme of the routes needing auth token.
+const tokenAuth = "token"
+
+const contentType = "application/json"
+
+var pathParam = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Build returns the document of the services. It is meant for server
+// initialization and panics if two routes have the same name or method and
+// path.
+func Build(info Info, services ...Service) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				tokenAuth: {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "Auth token of the user, from login. Also accepted as: Authorization: Token <token>",
+				},
+			},
+		},
+	}
+	meta := g.schema(reflect.TypeOf(transport.MetaResponse{}))
+	names := make(map[string]bool)
+	for _, s := range services {
+		errs := errorResponses(s.Errors, meta)
+		for _, r := range s.Routes {
+			if names[r.Name] {
+				panic(fmt.Sprintf("openapi: route %q declared twice", r.Name))
+			}
+			names[r.Name] = true
+
+			item, ok := doc.Paths[r.Path]
+			if !ok {
+				item = &PathItem{}
+				doc.Paths[r.Path] = item
+			}
+			op := item.operation(r.Method)
+			if *op != nil {
+				panic(fmt.Sprintf("openapi: %s %s declared twice", r.Method, r.Path))
+			}
+			*op = g.operation(s.Name, r, meta, errs)
+		}
+	}
+	return doc
+}
+
+func (g *generator) operation(tag string, r Route, meta *Schema, errs map[string]Response) *Operation {
+	op := &Operation{
+		OperationID: r.Name,
+		Summary:     r.Summary,
+		Tags:        []string{tag},
+		Responses:   make(map[string]Response),
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	for _, p := range r.Query {
+		t := p.Type
+		if t == "" {
+			t = "string"
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: &Schema{Type: t},
+		})
+	}
+	if r.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: g.schema(reflect.TypeOf(r.Request))}},
+		}
+	}
+	if r.Auth || len(r.Permissions) > 0 {
+		op.Security = []map[string][]string{{tokenAuth: {}}}
+	}
+	if len(r.Permissions) > 0 {
+		perms := make([]string, len(r.Permissions))
+		for i, p := range r.Permissions {
+			perms[i] = string(p)
+		}
+		op.Description = "Needs the permission " + strings.Join(perms, " or ") + "."
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	envelope := &Schema{Type: "object", Properties: map[string]*Schema{"meta": meta}}
+	if r.Response != nil {
+		envelope.Properties["data"] = g.schema(reflect.TypeOf(r.Response))
+	}
+	op.Responses[strconv.Itoa(status)] = Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{contentType: {Schema: envelope}},
+	}
+	for code, resp := range errs {
+		op.Responses[code] = resp
+	}
+	return op
+}
+
+// errorResponses returns the error responses by status, listing the codes
+// sent with each.
+func errorResponses(infos []transport.ErrorInfo, meta *Schema) map[string]Response {
+	codes := make(map[int][]string)
+	for _, info := range infos {
+		codes[info.Status] = append(codes[info.Status], info.Code)
+	}
+	envelope := &Schema{Type: "object", Properties: map[string]*Schema{"meta": meta}}
+	resps := make(map[string]Response)
+	for status, c := range codes {
+		sort.Strings(c)
+		resps[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status) + ". Codes: " + strings.Join(c, ", "),
+			Content:     map[string]MediaType{contentType: {Schema: envelope}},
+		}
+	}
+	return resps
+}
+
+// operation returns where the operation of method is kept in item.
+func (item *PathItem) operation(method string) **Operation {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return &item.Get
+	case "PUT":
+		return &item.Put
+	case "POST":
+		return &item.Post
+	case "DELETE":
+		return &item.Delete
+	case "PATCH":
+		return &item.Patch
+	}
+	panic(fmt.Sprintf("openapi: unsupported method %q", method))
+}
+
+// Handler returns handler serving doc as JSON.
+func Handler(doc *Document) http.Handler {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("openapi: encoding document: %v", err))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(body)
+	})
+}
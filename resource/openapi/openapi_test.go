@@ -0,0 +1,218 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/catalog"
+	"github.com/kavirajk/bookshop/exchange"
+	"github.com/kavirajk/bookshop/money"
+	"github.com/kavirajk/bookshop/openapi"
+	"github.com/kavirajk/bookshop/order"
+	"github.com/kavirajk/bookshop/promotion"
+	"github.com/kavirajk/bookshop/transport"
+	"github.com/kavirajk/bookshop/user"
+)
+
+// TestServices fails when the routes of a service and its spec drift.
+func TestServices(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	services := []struct {
+		handler http.Handler
+		api     openapi.Service
+	}{
+		{user.MakeHTTPHandler(ctx, nil, logger), user.OpenAPI()},
+		{catalog.MakeHTTPHandler(ctx, nil, logger), catalog.OpenAPI()},
+		{order.MakeHTTPHandler(ctx, nil, logger), order.OpenAPI()},
+		{exchange.MakeHTTPHandler(ctx, nil, logger), exchange.OpenAPI()},
+		{promotion.MakeHTTPHandler(ctx, nil, logger), promotion.OpenAPI()},
+	}
+	apis := make([]openapi.Service, 0, len(services))
+	for _, s := range services {
+		t.Run(s.api.Name, func(t *testing.T) {
+			for _, d := range openapi.Drift(s.handler.(*mux.Router), s.api) {
+				t.Error(d)
+			}
+		})
+		apis = append(apis, s.api)
+	}
+
+	// Every route of every service in one document.
+	doc := openapi.Build(openapi.Info{Title: "Bookshop", Version: "v1"}, apis...)
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if doc.Paths["/catalog/v1/{id}"].Get == nil || doc.Paths["/orders/v1/place"].Post == nil {
+		t.Errorf("expected catalog and order routes, got %v", doc.Paths)
+	}
+}
+
+// TestPermissions fails when a route declaring permissions lets through
+// requests without auth token or from customers.
+func TestPermissions(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	customer := auth.AuthenticatorFunc(func(ctx context.Context, token string) (auth.Principal, error) {
+		if token != "customer" {
+			return auth.Principal{}, auth.ErrUnauthorized
+		}
+		return auth.Principal{UserID: "joey"}, nil
+	})
+	services := []struct {
+		handler http.Handler
+		api     openapi.Service
+	}{
+		{user.MakeHTTPHandler(ctx, nil, logger), user.OpenAPI()},
+		{catalog.MakeHTTPHandler(ctx, nil, logger), catalog.OpenAPI()},
+		{order.MakeHTTPHandler(ctx, nil, logger), order.OpenAPI()},
+		{exchange.MakeHTTPHandler(ctx, nil, logger), exchange.OpenAPI()},
+		{promotion.MakeHTTPHandler(ctx, nil, logger), promotion.OpenAPI()},
+	}
+	for _, s := range services {
+		h := transport.Authenticate(s.handler, customer)
+		for _, r := range s.api.Routes {
+			if len(r.Permissions) == 0 {
+				continue
+			}
+			path := strings.Replace(r.Path, "{id}", "1", -1)
+			for token, status := range map[string]int{"": http.StatusUnauthorized, "customer": http.StatusForbidden} {
+				req := httptest.NewRequest(r.Method, path, strings.NewReader("{}"))
+				req.Header.Set("Authorization", token)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if rec.Code != status {
+					t.Errorf("expected %v %v with token %q to fail with %v, got %v", r.Method, r.Path, token, status, rec.Code)
+				}
+			}
+		}
+	}
+}
+
+type book struct {
+	ID      string      `json:"id"`
+	Price   money.Money `json:"price"`
+	Tags    []string    `json:"tags,omitempty"`
+	Secret  string      `json:"-"`
+	Error   error       `json:"error,omitempty"`
+	Author  *Author     `json:"author"`
+	Related []Author    `json:"related"`
+	At      time.Time   `json:"at"`
+	Counts  map[string]int
+	Embedded
+}
+
+type Author struct {
+	Name  string `json:"name"`
+	Books []book `json:"books"`
+}
+
+type Embedded struct {
+	Version int64 `json:"version"`
+}
+
+func TestBuild(t *testing.T) {
+	router := mux.NewRouter()
+	router.Handle("/books/v1/search", http.NotFoundHandler()).Methods("GET")
+	router.Handle("/books/v1/{id}", http.NotFoundHandler()).Methods("GET")
+	router.Handle("/books/v1/{id}", http.NotFoundHandler()).Methods("PUT")
+	router.Handle("/books/v1/{isbn}", http.NotFoundHandler()).Methods("GET")
+
+	errs := transport.NewRegistry().Register(catalog.ErrBookNotFound, http.StatusNotFound, "book_not_found")
+	api := openapi.Service{
+		Name:   "books",
+		Errors: errs.Infos(),
+		Routes: []openapi.Route{
+			{Name: "getBook", Method: "GET", Path: "/books/v1/{id}", Response: book{}},
+			{Name: "updateBook", Method: "PUT", Path: "/books/v1/{id}", Request: book{}, Response: book{}, Auth: true},
+			{Name: "createBook", Method: "POST", Path: "/books/v1/create", Request: book{}, Status: http.StatusCreated},
+		},
+	}
+
+	t.Run("drift", func(t *testing.T) {
+		want := []string{
+			"/books/v1/{isbn}: unreachable, an earlier route matches its requests",
+			"POST /books/v1/create: in the spec but not routed",
+			"GET /books/v1/search: routed but not in the spec",
+		}
+		if got := openapi.Drift(router, api); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	doc := openapi.Build(openapi.Info{Title: "Books", Version: "v1"}, api)
+
+	t.Run("schemas", func(t *testing.T) {
+		body, _ := json.Marshal(doc.Paths["/books/v1/{id}"].Get.Responses["200"])
+		var resp struct {
+			Content map[string]struct {
+				Schema struct {
+					Properties map[string]struct {
+						Properties map[string]json.RawMessage
+					}
+				}
+			}
+		}
+		json.Unmarshal(body, &resp)
+		data := resp.Content["application/json"].Schema.Properties["data"].Properties
+		for _, name := range []string{"id", "price", "tags", "author", "related", "at", "Counts", "version"} {
+			if _, ok := data[name]; !ok {
+				t.Errorf("expected property %v, got %v", name, data)
+			}
+		}
+		for _, name := range []string{"Secret", "error"} {
+			if _, ok := data[name]; ok {
+				t.Errorf("expected no property %v, got %v", name, data)
+			}
+		}
+		if got := string(data["author"]); got != `{"$ref":"#/components/schemas/openapi_test.Author"}` {
+			t.Errorf("expected reference of Author, got %v", got)
+		}
+		if _, ok := doc.Components.Schemas["openapi_test.Author"]; !ok {
+			t.Errorf("expected Author in components, got %v", doc.Components.Schemas)
+		}
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		get := doc.Paths["/books/v1/{id}"].Get
+		if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" {
+			t.Errorf("expected id path parameter, got %+v", get.Parameters)
+		}
+		if _, ok := get.Responses["404"]; !ok {
+			t.Errorf("expected 404 response, got %v", get.Responses)
+		}
+		if _, ok := get.Responses["500"]; !ok {
+			t.Errorf("expected 500 response, got %v", get.Responses)
+		}
+		if get.Security != nil {
+			t.Errorf("expected no security, got %v", get.Security)
+		}
+		if doc.Paths["/books/v1/{id}"].Put.Security == nil {
+			t.Errorf("expected security of update, got none")
+		}
+		if _, ok := doc.Paths["/books/v1/create"].Post.Responses["201"]; !ok {
+			t.Errorf("expected 201 response, got %v", doc.Paths["/books/v1/create"].Post.Responses)
+		}
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		openapi.Handler(doc).ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+		var got map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if got["openapi"] != openapi.Version {
+			t.Errorf("expected %v, got %v", openapi.Version, got["openapi"])
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kavirajk/bookshop/money"
+)
+
+// Schema is the JSON schema of a value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// known are the schemas of the types encoded by their own MarshalJSON.
+var known = map[reflect.Type]*Schema{
+	reflect.TypeOf(time.Time{}): {Type: "string", Format: "date-time"},
+	reflect.TypeOf(money.Money{}): {
+		Type:        "object",
+		Description: "Amount as decimal string, so that clients never deal with floating point",
+		Properties: map[string]*Schema{
+			"amount":   {Type: "string", Format: "decimal"},
+			"currency": {Type: "string", Description: "ISO 4217 code, e.g: USD"},
+		},
+	},
+	reflect.TypeOf(money.Rates{}): {
+		Type: "object",
+		Properties: map[string]*Schema{
+			"base":  {Type: "string"},
+			"as_of": {Type: "string", Format: "date-time"},
+			"rates": {Type: "object", AdditionalProperties: &Schema{Type: "string", Format: "decimal"}},
+		},
+	},
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// generator generates the schemas of Go types, exported structs are
+// referred to from the components.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: make(map[string]*Schema)}
+}
+
+// schema returns the schema of values of t as encoding/json encodes them.
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := known[t]; ok {
+		return s
+	}
+	if s, ok := promoted(t); ok {
+		return s
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	// Interfaces may hold any value.
+	return &Schema{}
+}
+
+// promoted returns the known schema of the struct embedding a known type,
+// its MarshalJSON is promoted to the struct.
+func promoted(t reflect.Type) (*Schema, bool) {
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if s, ok := known[f.Type]; ok && f.Anonymous && f.Tag.Get("json") == "" {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// structSchema returns the schema of struct t, a reference to the
+// components if t is exported. Component names are qualified by package,
+// e.g: catalog.Book.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	exported := t.Name() != "" && t.Name()[0] >= 'A' && t.Name()[0] <= 'Z'
+	if !exported {
+		return g.object(t)
+	}
+	name := path.Base(t.PkgPath()) + "." + t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := g.schemas[name]; ok {
+		return ref
+	}
+	// Registered before the fields, so that recursive types end.
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+	return ref
+}
+
+// object returns the schema of the fields of struct t, the ones of
+// embedded structs included.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s.Properties)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, props map[string]*Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || f.Type == errorType {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if _, ok := known[ft]; !ok {
+				g.fields(ft, props)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
@@ -0,0 +1,186 @@
+// ratelimit throttles requests with token buckets, kept in memory or in
+// Redis to be shared by all the nodes.
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/pkg/errors"
+)
+
+var ErrLimited = errors.New("too many requests, retry later")
+
+// Limit of a bucket: Burst requests at once, then one every Every. Zero
+// Limit doesn't limit.
+type Limit struct {
+	Burst int
+	Every time.Duration
+}
+
+func (l Limit) unlimited() bool {
+	return l.Burst <= 0 || l.Every <= 0
+}
+
+// Store keeps the buckets. They are kept as the time they are full again,
+// the generic cell rate algorithm, a token bucket needing a single value
+// per key.
+type Store interface {
+	// Take takes a token from the bucket of key. It returns 0 if there
+	// was one, how long until there is one otherwise.
+	Take(ctx context.Context, key string, limit Limit) (time.Duration, error)
+}
+
+// Error is the error of a request to retry after Wait, see
+// transport.RetryAfterer. Err is its cause, ErrLimited for the limiters.
+type Error struct {
+	Err  error
+	Wait time.Duration
+}
+
+func (e Error) Error() string             { return e.Err.Error() }
+func (e Error) Cause() error              { return e.Err }
+func (e Error) RetryAfter() time.Duration { return e.Wait }
+
+// Limiter limits the requests by key, e.g: client IP or account, to Limit
+// each.
+type Limiter struct {
+	// Name of the limiter, e.g: login_ip, prefixing its keys in Store.
+	Name  string
+	Store Store
+	Limit Limit
+
+	// Blocked counts the requests over the limit, labelled by limiter.
+	// Optional.
+	Blocked metrics.Counter
+}
+
+// Allow fails with Error if the request of key is over the limit. Empty
+// keys aren't limited. A failing Store lets the requests through, the same
+// way reads bypass an unreachable cache.
+func (l Limiter) Allow(ctx context.Context, key string) error {
+	if l.Store == nil || key == "" || l.Limit.unlimited() {
+		return nil
+	}
+	wait, err := l.Store.Take(ctx, l.Name+":"+key, l.Limit)
+	if err != nil || wait <= 0 {
+		return nil
+	}
+	if l.Blocked != nil {
+		l.Blocked.With("limiter", l.Name).Add(1)
+	}
+	return Error{Err: ErrLimited, Wait: wait}
+}
+
+// Memory keeps the buckets of a single node.
+type Memory struct {
+	mu    sync.Mutex
+	full  map[string]time.Time // when buckets are full again
+	swept time.Time
+}
+
+// sweepInterval is how often the full buckets are forgotten.
+const sweepInterval = time.Minute
+
+func NewMemory() *Memory {
+	return &Memory{full: make(map[string]time.Time), swept: time.Now()}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	if limit.unlimited() {
+		return 0, nil
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.swept) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	full := m.full[key]
+	if full.Before(now) {
+		full = now
+	}
+	// Every request pushes back when the bucket is full again by Every,
+	// a request is over the limit when that is more than Burst away.
+	next := full.Add(limit.Every)
+	if wait := next.Sub(now) - time.Duration(limit.Burst)*limit.Every; wait > 0 {
+		return wait, nil
+	}
+	m.full[key] = next
+	return 0, nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	for key, full := range m.full {
+		if !full.After(now) {
+			delete(m.full, key)
+		}
+	}
+	m.swept = now
+}
+
+// takeScript is Memory.Take run atomically by Redis, times in milliseconds
+// of the clock of the caller. Keys expire once their bucket is full.
+const takeScript = `
+local now = tonumber(ARGV[1])
+local every = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local full = tonumber(redis.call('GET', KEYS[1])) or now
+if full < now then full = now end
+local later = full + every
+local wait = later - now - burst * every
+if wait > 0 then return wait end
+redis.call('SET', KEYS[1], later, 'PX', later - now)
+return 0
+`
+
+// Redis keeps the buckets in Redis, shared by all the nodes.
+type Redis struct {
+	redis  *cache.Redis
+	prefix string
+}
+
+// NewRedis returns Store of the buckets in r, their keys prefixed by
+// ratelimit:.
+func NewRedis(r *cache.Redis) *Redis {
+	return &Redis{redis: r, prefix: "ratelimit:"}
+}
+
+func (s *Redis) Take(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	if limit.unlimited() {
+		return 0, nil
+	}
+	reply, err := s.redis.Eval(ctx, takeScript, []string{s.prefix + key},
+		millis(time.Duration(time.Now().UnixNano())), millis(limit.Every), strconv.Itoa(limit.Burst))
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, cache.ErrProtocol
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns ctx of a request from ip, the key of the limiters
+// by client.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the IP of the client of ctx, empty if unknown.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
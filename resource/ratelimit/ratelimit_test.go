@@ -0,0 +1,100 @@
+package ratelimit_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/kavirajk/bookshop/cache"
+	"github.com/kavirajk/bookshop/ratelimit"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+var ctx = context.Background()
+
+// testStore runs the tests every Store passes.
+func testStore(t *testing.T, store ratelimit.Store) {
+	limit := ratelimit.Limit{Burst: 3, Every: time.Hour}
+	key := uuid.New()
+	for i := 0; i < limit.Burst; i++ {
+		if wait, err := store.Take(ctx, key, limit); err != nil || wait != 0 {
+			t.Fatalf("expected request %v of the burst allowed, got wait %v, %v", i, wait, err)
+		}
+	}
+	wait, err := store.Take(ctx, key, limit)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if wait <= time.Hour-time.Minute || wait > time.Hour {
+		t.Errorf("expected to wait about an hour, got %v", wait)
+	}
+	if wait, _ := store.Take(ctx, uuid.New(), limit); wait != 0 {
+		t.Errorf("expected other keys allowed, got wait %v", wait)
+	}
+
+	refill := ratelimit.Limit{Burst: 1, Every: 50 * time.Millisecond}
+	store.Take(ctx, key+"refill", refill)
+	time.Sleep(60 * time.Millisecond)
+	if wait, _ := store.Take(ctx, key+"refill", refill); wait != 0 {
+		t.Errorf("expected a token again after Every, got wait %v", wait)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, ratelimit.NewMemory())
+}
+
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("missing REDIS_TEST_ADDR env variable")
+	}
+	r := cache.NewRedis(addr, cache.RedisOptions{})
+	defer r.Close()
+	testStore(t, ratelimit.NewRedis(r))
+}
+
+// counter counts by the label values it is added with.
+type counter struct {
+	lvs    string
+	counts map[string]float64
+}
+
+func (c counter) With(lvs ...string) metrics.Counter {
+	return counter{lvs: strings.Join(lvs, " "), counts: c.counts}
+}
+
+func (c counter) Add(delta float64) { c.counts[c.lvs] += delta }
+
+func TestLimiter(t *testing.T) {
+	blocked := counter{counts: make(map[string]float64)}
+	l := ratelimit.Limiter{
+		Name:    "login_ip",
+		Store:   ratelimit.NewMemory(),
+		Limit:   ratelimit.Limit{Burst: 1, Every: time.Minute},
+		Blocked: blocked,
+	}
+	if err := l.Allow(ctx, "203.0.113.7"); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	err := l.Allow(ctx, "203.0.113.7")
+	if errors.Cause(err) != ratelimit.ErrLimited {
+		t.Fatalf("expected %v, got %v", ratelimit.ErrLimited, err)
+	}
+	if wait := err.(ratelimit.Error).RetryAfter(); wait <= 0 || wait > time.Minute {
+		t.Errorf("expected retry within a minute, got %v", wait)
+	}
+	if blocked.counts["limiter login_ip"] != 1 {
+		t.Errorf("expected 1 blocked by login_ip, got %v", blocked.counts)
+	}
+	if err := l.Allow(ctx, ""); err != nil {
+		t.Errorf("expected unknown clients not limited, got %v", err)
+	}
+	if err := (ratelimit.Limiter{Name: "none"}).Allow(ctx, "203.0.113.7"); err != nil {
+		t.Errorf("expected no store not limiting, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// tracing traces requests with OpenTelemetry through the http transport,
+// the endpoints, the services and the database queries. Trace context
+// crosses processes in W3C traceparent headers.
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of the spans.
+const (
+	None   = "none"
+	Stdout = "stdout"
+	OTLP   = "otlp"
+)
+
+var ErrUnknownExporter = errors.New("unknown trace exporter, want none, stdout or otlp")
+
+// Options of Setup.
+type Options struct {
+	Service  string // e.g: bookserver
+	Exporter string // None, Stdout or OTLP
+
+	// Endpoint is the host:port of the OTLP/HTTP collector, e.g:
+	// localhost:4318. Empty uses OTEL_EXPORTER_OTLP_ENDPOINT or the default.
+	Endpoint string
+	Insecure bool // plain http to the collector
+
+	// SampleRatio of the traces started here, 1 samples all of them.
+	// Traces started by the caller follow its sampling decision.
+	SampleRatio float64
+
+	// Writer of the stdout exporter, os.Stdout if nil.
+	Writer io.Writer
+}
+
+// Setup makes the spans of the process exported as opts says. It returns
+// shutdown flushing the spans not exported yet, to call before exiting.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case None, "":
+		return func(context.Context) error { return nil }, nil
+	case Stdout:
+		var options []stdouttrace.Option
+		if opts.Writer != nil {
+			options = append(options, stdouttrace.WithWriter(opts.Writer))
+		}
+		exporter, err = stdouttrace.New(options...)
+	case OTLP:
+		var options []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, ErrUnknownExporter
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating trace exporter")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", opts.Service))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the bookshop spans, from the provider Setup
+// registers. Spans aren't recorded until then.
+func Tracer() trace.Tracer {
+	return otel.Tracer("github.com/kavirajk/bookshop")
+}
+
+// End ends span, failed with err if any.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// HTTP is a http middleware starting the span of every request, child of
+// the span of the caller given in the traceparent header, if any.
+func HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := Tracer().Start(ctx, "HTTP "+req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("url.path", req.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Inject is a go-kit ClientBefore func that sends the trace context of ctx
+// in the headers of req, so that the span of the service called is a child
+// of the caller's.
+func Inject(ctx context.Context, req *http.Request) context.Context {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return ctx
+}
+
+// failer is the response of an endpoint that failed, see transport.Failer.
+type failer interface {
+	Failed() error
+}
+
+// Endpoint returns a go-kit middleware tracing the endpoint as name, e.g:
+// catalog.get. Failed responses fail the span.
+func Endpoint(name string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			ctx, span := Tracer().Start(ctx, "endpoint "+name)
+			defer func() {
+				failed := err
+				if f, ok := response.(failer); ok && failed == nil {
+					failed = f.Failed()
+				}
+				End(span, failed)
+			}()
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kavirajk/bookshop/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// record makes the spans ended from now on recorded by the returned
+// recorder.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
+
+func TestHTTP(t *testing.T) {
+	recorder := record(t)
+
+	e := tracing.Endpoint("catalog.get")(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return failedResponse{errors.New("book not found")}, nil
+	})
+	h := tracing.HTTP(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		e(req.Context(), nil)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	req := httptest.NewRequest("GET", "/catalog/v1/42", nil)
+	req.Header.Set("traceparent", traceparent)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", len(spans))
+	}
+	endpoint, server := spans[0], spans[1]
+	if server.Name() != "HTTP GET" || server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected HTTP GET child of the caller, got %v child of %v", server.Name(), server.Parent().SpanID())
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("expected 503 failing the span, got %v", server.Status())
+	}
+	if endpoint.Parent().SpanID() != server.SpanContext().SpanID() || endpoint.Status().Code != codes.Error {
+		t.Errorf("expected failed endpoint span child of the http one, got %v %v", endpoint.Parent().SpanID(), endpoint.Status())
+	}
+	for _, span := range spans {
+		if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected trace of the caller, got %v", span.SpanContext().TraceID())
+		}
+	}
+}
+
+func TestInject(t *testing.T) {
+	record(t)
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier{"Traceparent": {traceparent}})
+	ctx, span := tracing.Tracer().Start(ctx, "order.PlaceOrder")
+	defer span.End()
+
+	req := httptest.NewRequest("GET", "/catalog/v1/42", nil)
+	tracing.Inject(ctx, req)
+	got := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(req.Header))
+	_, child := tracing.Tracer().Start(got, "catalog.Get")
+	defer child.End()
+	if child.SpanContext().TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("expected trace %v, got %v", span.SpanContext().TraceID(), child.SpanContext().TraceID())
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "jaeger"}); err != tracing.ErrUnknownExporter {
+		t.Errorf("expected %v, got %v", tracing.ErrUnknownExporter, err)
+	}
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.None})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
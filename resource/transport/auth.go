@@ -0,0 +1,29 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kavirajk/bookshop/auth"
+)
+
+// Authenticate is a http middleware storing the auth token of the request
+// in its context, authenticated by authn once an endpoint requires it, see
+// auth.Require.
+func Authenticate(next http.Handler, authn auth.Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(auth.WithToken(req.Context(), Token(req), authn)))
+	})
+}
+
+// Token returns auth token from the Authorization header.
+// Both "Token <token>" and "Bearer <token>" forms are accepted.
+func Token(req *http.Request) string {
+	h := strings.TrimSpace(req.Header.Get("Authorization"))
+	for _, scheme := range []string{"Token ", "Bearer "} {
+		if strings.HasPrefix(h, scheme) {
+			return strings.TrimSpace(h[len(scheme):])
+		}
+	}
+	return h
+}
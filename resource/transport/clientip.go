@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/kavirajk/bookshop/ratelimit"
+	"github.com/pkg/errors"
+)
+
+// ClientIP is a http middleware storing the IP of the client in the request
+// context, see ratelimit.ClientIP. It is the remote address, unless that is
+// one of trustedProxies: then it is the last address of X-Forwarded-For not
+// of a trusted proxy, the ones before may be forged by the client.
+func ClientIP(next http.Handler, trustedProxies ...*net.IPNet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ip := clientIP(req, trustedProxies)
+		next.ServeHTTP(w, req.WithContext(ratelimit.WithClientIP(req.Context(), ip)))
+	})
+}
+
+func clientIP(req *http.Request, trusted []*net.IPNet) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+	if !contains(trusted, ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !contains(trusted, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func contains(nets []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, n := range nets {
+		if parsed != nil && n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCIDRs parses the networks of trusted proxies, e.g: 10.0.0.0/8. A
+// single address is a network of its own.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			if ip := net.ParseIP(c); ip != nil && ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted proxy %q", c)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cache-Control of the responses.
+const (
+	// CachePublic lets clients and CDNs reuse public data for a minute,
+	// then revalidate it with its ETag or Last-Modified.
+	CachePublic = "public, max-age=60"
+
+	// CacheNoStore keeps private data, e.g: of users and orders, out of
+	// every cache.
+	CacheNoStore = "no-store"
+)
+
+type conditionalKey struct{}
+
+type conditional struct {
+	ifNoneMatch     string
+	ifModifiedSince time.Time
+}
+
+// PopulateConditional is a go-kit ServerBefore func that moves
+// If-None-Match and If-Modified-Since of GET requests into ctx for
+// NotModified.
+func PopulateConditional(ctx context.Context, req *http.Request) context.Context {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return ctx
+	}
+	c := conditional{ifNoneMatch: strings.TrimSpace(req.Header.Get("If-None-Match"))}
+	if ims, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil {
+		c.ifModifiedSince = ims
+	}
+	return context.WithValue(ctx, conditionalKey{}, c)
+}
+
+// NotModified tells whether the client already has the representation of
+// etag, last modified at lastModified, so that 304 can be replied.
+// If-Modified-Since is only looked at without If-None-Match, and not at
+// all for zero lastModified.
+func NotModified(ctx context.Context, etag string, lastModified time.Time) bool {
+	c, ok := ctx.Value(conditionalKey{}).(conditional)
+	if !ok {
+		return false
+	}
+	if c.ifNoneMatch != "" {
+		return etag != "" && matchNone(c.ifNoneMatch, etag)
+	}
+	if c.ifModifiedSince.IsZero() || lastModified.IsZero() {
+		return false
+	}
+	// Last-Modified has second precision.
+	return !lastModified.Truncate(time.Second).After(c.ifModifiedSince)
+}
+
+// matchNone tells whether etag is among the tags of If-None-Match, using
+// weak comparison as the header requires.
+func matchNone(header, etag string) bool {
+	if header == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentETag returns strong ETag of body, for responses without a single
+// version e.g: listings.
+func ContentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// LastModified formats t for the Last-Modified header.
+func LastModified(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/validate"
+	"github.com/pkg/errors"
+)
+
+// Failer is implemented by responses of endpoints that can fail with a
+// domain error.
+type Failer interface {
+	Failed() error
+}
+
+// StatusCoder is implemented by responses with a status other than 200,
+// e.g: 201 for successful resource creation.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Pager is implemented by paginated responses.
+type Pager interface {
+	Page() (total int, previous, next string)
+}
+
+// Versioner is implemented by responses of a single versioned entity, its
+// version is sent as ETag to use in If-Match of the updates.
+type Versioner interface {
+	Version() int64
+}
+
+// Cacheable is implemented by responses of public data, that clients and
+// CDNs may cache and revalidate with If-None-Match, or If-Modified-Since if
+// validates is true. Other responses are not stored.
+type Cacheable interface {
+	LastModified() (at time.Time, validates bool)
+}
+
+// ServerOptions returns the go-kit server options shared by all the
+// services, encoding errors with errs.
+func ServerOptions(errs *Registry) []httptransport.ServerOption {
+	return []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(ErrorEncoder(errs)),
+		httptransport.ServerBefore(PopulateRequestID, PopulateIfMatch, PopulateSession, PopulateConditional),
+	}
+}
+
+// ResponseEncoder returns go-kit encoder of responses in FormatResponse.
+// Failed responses are encoded by ErrorEncoder of errs.
+func ResponseEncoder(errs *Registry) httptransport.EncodeResponseFunc {
+	encodeError := ErrorEncoder(errs)
+	return func(ctx context.Context, w http.ResponseWriter, d interface{}) error {
+		if f, ok := d.(Failer); ok && f.Failed() != nil {
+			encodeError(ctx, f.Failed(), w)
+			return nil
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		f := FormatResponse{
+			Data: d,
+			Meta: MetaResponse{Status: http.StatusOK},
+		}
+		if s, ok := d.(StatusCoder); ok && s.StatusCode() != 0 {
+			f.Meta.Status = s.StatusCode()
+		}
+		if p, ok := d.(Pager); ok {
+			f.Meta.Total, f.Meta.Previous, f.Meta.Next = p.Page()
+		}
+		etag := ""
+		if v, ok := d.(Versioner); ok && v.Version() != 0 {
+			etag = ETag(v.Version())
+		}
+
+		c, ok := d.(Cacheable)
+		if !ok {
+			w.Header().Set("Cache-Control", CacheNoStore)
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			return json.NewEncoder(w).Encode(f)
+		}
+
+		body, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		body = append(body, '\n')
+		if etag == "" {
+			etag = ContentETag(body)
+		}
+		lastModified, validates := c.LastModified()
+		w.Header().Set("Cache-Control", CachePublic)
+		w.Header().Set("ETag", etag)
+		if !lastModified.IsZero() {
+			w.Header().Set("Last-Modified", LastModified(lastModified))
+		}
+		if !validates {
+			lastModified = time.Time{}
+		}
+		if NotModified(ctx, etag, lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		_, err = w.Write(body)
+		return err
+	}
+}
+
+// ErrorEncoder returns go-kit encoder of errors in FormatResponse, with
+// the status and code err is registered with in errs.
+func ErrorEncoder(errs *Registry) httptransport.ErrorEncoder {
+	return func(_ context.Context, err error, w http.ResponseWriter) {
+		if err == nil {
+			panic("encodeError with nil error")
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", CacheNoStore)
+
+		info, ok := errs.Lookup(err)
+		body := ErrorBody{Code: info.Code, Message: http.StatusText(info.Status)}
+		if ok {
+			body.Message = err.Error()
+			body.Details = detailsOf(err)
+		}
+		if d := retryAfterOf(err); d > 0 {
+			// Whole seconds, rounded up not to retry too early.
+			w.Header().Set("Retry-After", strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10))
+		}
+		meta := MetaResponse{Status: info.Status, Error: &body, Errors: validate.FieldErrors(err)}
+		w.WriteHeader(info.Status)
+		json.NewEncoder(w).Encode(FormatResponse{Meta: meta})
+	}
+}
+
+// DecodeJSON decodes JSON body of req into v. Malformed body fails with
+// ErrBadRequest.
+func DecodeJSON(req *http.Request, v interface{}) error {
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil {
+		return errors.Wrap(ErrBadRequest, err.Error())
+	}
+	return nil
+}
+
+// Validator is implemented by requests that validate their fields, see
+// validate.Validator.
+type Validator interface {
+	Validate() error
+}
+
+// Validated returns dec that validates the requests it decodes, if they
+// implement Validator. Invalid ones fail with their validate.Error.
+func Validated(dec httptransport.DecodeRequestFunc) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		request, err := dec(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := request.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		return request, nil
+	}
+}
+
+// Var returns the route variable name of req, e.g: id of /books/{id}.
+// Missing one fails with ErrBadRouting.
+func Var(req *http.Request, name string) (string, error) {
+	v, ok := mux.Vars(req)[name]
+	if !ok {
+		return "", errors.Wrap(ErrBadRouting, name)
+	}
+	return v, nil
+}
@@ -0,0 +1,151 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/ratelimit"
+	"github.com/kavirajk/bookshop/validate"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrBadRouting = errors.New("bad routing")
+	ErrBadRequest = errors.New("malformed request")
+)
+
+// ErrorInfo tells how a domain error is sent over HTTP.
+type ErrorInfo struct {
+	Status int
+	// Code is the machine readable code of the error, e.g: book_not_found.
+	Code string
+}
+
+// internalError is sent for the errors not registered. Their message is
+// left out, it may leak details of the internals.
+var internalError = ErrorInfo{Status: http.StatusInternalServerError, Code: "internal"}
+
+// Registry is where every service registers its domain errors, with the
+// status and code they are sent with.
+type Registry struct {
+	errs map[error]ErrorInfo
+}
+
+// NewRegistry returns Registry of the errors common to all the services,
+// e.g: routing, authorization, validation and version mismatches.
+func NewRegistry() *Registry {
+	r := &Registry{errs: make(map[error]ErrorInfo)}
+	return r.
+		Register(ErrBadRouting, http.StatusBadRequest, "bad_routing").
+		Register(ErrBadRequest, http.StatusBadRequest, "bad_request").
+		Register(auth.ErrUnauthorized, http.StatusUnauthorized, "unauthorized").
+		Register(auth.ErrForbidden, http.StatusForbidden, "forbidden").
+		Register(validate.ErrInvalid, http.StatusUnprocessableEntity, "invalid").
+		Register(db.ErrNotFound, http.StatusNotFound, "not_found").
+		Register(db.ErrAlreadyExists, http.StatusConflict, "already_exists").
+		Register(db.ErrConflict, http.StatusConflict, "conflict").
+		Register(db.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed").
+		Register(ratelimit.ErrLimited, http.StatusTooManyRequests, "rate_limited")
+}
+
+// Register registers err to be sent with status and code, and returns r.
+// It is meant for package initialization and panics if err is already
+// registered differently.
+func (r *Registry) Register(err error, status int, code string) *Registry {
+	info := ErrorInfo{Status: status, Code: code}
+	if old, ok := r.errs[err]; ok && old != info {
+		panic(fmt.Sprintf("transport: %q registered as %v, then as %v", err, old, info))
+	}
+	r.errs[err] = info
+	return r
+}
+
+// Lookup returns how err is sent, by its cause. Errors not registered are
+// internal errors.
+func (r *Registry) Lookup(err error) (ErrorInfo, bool) {
+	info, ok := r.errs[errors.Cause(err)]
+	if !ok {
+		return internalError, false
+	}
+	return info, true
+}
+
+// Infos returns how the registered errors are sent, by status then code,
+// and internal errors last. Errors sent the same way are listed once.
+func (r *Registry) Infos() []ErrorInfo {
+	seen := make(map[ErrorInfo]bool)
+	infos := make([]ErrorInfo, 0, len(r.errs)+1)
+	for _, info := range r.errs {
+		if !seen[info] {
+			seen[info] = true
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Status != infos[j].Status {
+			return infos[i].Status < infos[j].Status
+		}
+		return infos[i].Code < infos[j].Code
+	})
+	return append(infos, internalError)
+}
+
+// Detailer is implemented by errors with details of the failure for the
+// client, e.g: the offending fields.
+type Detailer interface {
+	Details() interface{}
+}
+
+type detailedError struct {
+	error
+	details interface{}
+}
+
+func (e detailedError) Cause() error         { return e.error }
+func (e detailedError) Details() interface{} { return e.details }
+
+// WithDetails returns err carrying details, they are sent in the error
+// body. Cause of the returned error is err.
+func WithDetails(err error, details interface{}) error {
+	return detailedError{error: err, details: details}
+}
+
+// detailsOf returns details of the first Detailer err wraps.
+func detailsOf(err error) interface{} {
+	for err != nil {
+		if d, ok := err.(Detailer); ok {
+			return d.Details()
+		}
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return nil
+		}
+		err = c.Cause()
+	}
+	return nil
+}
+
+// RetryAfterer is implemented by errors of requests to retry later, e.g:
+// ratelimit.Error. The delay is sent in the Retry-After header.
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// retryAfterOf returns the delay of the first RetryAfterer err wraps.
+func retryAfterOf(err error) time.Duration {
+	for err != nil {
+		if r, ok := err.(RetryAfterer); ok {
+			return r.RetryAfter()
+		}
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return 0
+		}
+		err = c.Cause()
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/pborman/uuid"
+)
+
+// RequestIDHeader carries the ID of a request, from the client or a proxy in
+// front, and back in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID keeps IDs given by clients short and safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID is a http middleware that gives every request an ID, the one of
+// its X-Request-ID header if valid, a new one otherwise. The ID is sent back
+// in the response header and stored in the request context for the logs,
+// see logging.FromContext.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, req.WithContext(logging.WithRequestID(req.Context(), id)))
+	})
+}
+
+// PopulateRequestID is a go-kit ServerBefore func that moves the ID given
+// to the request by RequestID into ctx.
+func PopulateRequestID(ctx context.Context, req *http.Request) context.Context {
+	if id := logging.RequestID(req.Context()); id != "" {
+		return logging.WithRequestID(ctx, id)
+	}
+	return ctx
+}
@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// Routes are the routes of a service, mounted under its prefix by Mount.
+type Routes interface {
+	// Prefix is the path with the version the routes are under, e.g:
+	// /catalog/v1.
+	Prefix() string
+
+	// Register registers the routes on r, paths relative to the prefix.
+	// e.g: /{id} for /catalog/v1/{id}.
+	Register(r *mux.Router)
+}
+
+type routes struct {
+	prefix   string
+	register func(r *mux.Router)
+}
+
+func (r routes) Prefix() string           { return r.prefix }
+func (r routes) Register(sub *mux.Router) { r.register(sub) }
+
+// NewRoutes returns Routes registered by register under prefix.
+func NewRoutes(prefix string, register func(r *mux.Router)) Routes {
+	return routes{prefix: prefix, register: register}
+}
+
+// Mount registers the routes of every service on router, under their
+// prefix.
+func Mount(router *mux.Router, services ...Routes) {
+	for _, s := range services {
+		s.Register(router.PathPrefix(s.Prefix()).Subrouter())
+	}
+}
+
+// Route is a route registered on a router.
+type Route struct {
+	// Path is the path template, e.g: /catalog/v1/{id}.
+	Path string `json:"path"`
+	// Methods are the methods routed to it, "*" if any. None if an earlier
+	// route matches all its requests.
+	Methods []string `json:"methods"`
+}
+
+// methods are the methods routes are probed with.
+var methods = []string{"GET", "HEAD", "PUT", "POST", "DELETE", "PATCH"}
+
+var pathVar = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// ListRoutes returns the routes registered on router, in the order they
+// are matched. Which methods reach a route is found by matching requests
+// to an example path of it, variables replaced by their name.
+func ListRoutes(router *mux.Router) []Route {
+	var list []Route
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			return nil // prefix of a subrouter
+		}
+		r := Route{Path: tmpl, Methods: make([]string, 0)}
+		for _, method := range methods {
+			if routedTo(router, route, method, pathVar.ReplaceAllString(tmpl, "$1")) {
+				r.Methods = append(r.Methods, method)
+			}
+		}
+		if len(r.Methods) == len(methods) {
+			r.Methods = []string{"*"}
+		}
+		list = append(list, r)
+		return nil
+	})
+	return list
+}
+
+func routedTo(router *mux.Router, route *mux.Route, method, path string) bool {
+	var m mux.RouteMatch
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil || !router.Match(req, &m) {
+		return false
+	}
+	return m.Route == route
+}
+
+// CheckRoutes fails if a route of router is unreachable, i.e: an earlier
+// route matches all its requests. e.g: /books/{id} registered before
+// /books/search.
+func CheckRoutes(router *mux.Router) error {
+	var unreachable []string
+	for _, r := range ListRoutes(router) {
+		if len(r.Methods) == 0 {
+			unreachable = append(unreachable, r.Path)
+		}
+	}
+	if len(unreachable) > 0 {
+		return errors.Errorf("unreachable routes, an earlier route matches their requests: %s",
+			strings.Join(unreachable, ", "))
+	}
+	return nil
+}
+
+// RoutesHandler returns handler listing the routes of router, for
+// debugging.
+func RoutesHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", CacheNoStore)
+		json.NewEncoder(w).Encode(FormatResponse{
+			Data: ListRoutes(router),
+			Meta: MetaResponse{Status: http.StatusOK},
+		})
+	})
+}
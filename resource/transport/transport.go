@@ -1,6 +1,16 @@
 // tranport contains common tranport utils for all the services.
 package transport
 
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/validate"
+)
+
 // formatResponse is the uniform response format used throughout the books service,
 // for every endpoint response.
 type FormatResponse struct {
@@ -10,9 +20,53 @@ type FormatResponse struct {
 
 // metaResponse is part of response json that tells about basic meta information.
 type MetaResponse struct {
-	Status   int    `json:"status"`
-	Error    string `json:"error,omitempty"`
-	Previous string `json:"previous,omitempty"`
-	Next     string `json:"next,omitempty"`
-	Total    int    `json:"total,omitempty"`
+	Status int        `json:"status"`
+	Error  *ErrorBody `json:"error,omitempty"`
+	// Errors are the invalid fields of a request failed validation.
+	Errors   []validate.FieldError `json:"errors,omitempty"`
+	Previous string                `json:"previous,omitempty"`
+	Next     string                `json:"next,omitempty"`
+	Total    int                   `json:"total,omitempty"`
+}
+
+// ErrorBody is the error of a failed request, the same for every service.
+type ErrorBody struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// ETag formats version of an entity as a strong entity tag.
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// PopulateIfMatch is a go-kit ServerBefore func that moves the versions of
+// If-Match header into ctx, see db.WithIfMatch. "*" matches any version,
+// weak and malformed tags never match.
+func PopulateIfMatch(ctx context.Context, req *http.Request) context.Context {
+	h := strings.TrimSpace(req.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return ctx
+	}
+	versions := make([]int64, 0)
+	for _, tag := range strings.Split(h, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return db.WithIfMatch(ctx, versions...)
+}
+
+// PopulateSession is a go-kit ServerBefore func that starts a db session
+// for the request, so that its reads after a write see the write even when
+// they would go to a lagging replica, see db.WithSession.
+func PopulateSession(ctx context.Context, req *http.Request) context.Context {
+	return db.WithSession(ctx)
 }
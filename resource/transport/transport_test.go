@@ -0,0 +1,370 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kavirajk/bookshop/auth"
+	"github.com/kavirajk/bookshop/db"
+	"github.com/kavirajk/bookshop/logging"
+	"github.com/kavirajk/bookshop/ratelimit"
+	"github.com/kavirajk/bookshop/transport"
+	"github.com/kavirajk/bookshop/validate"
+)
+
+func TestPopulateIfMatch(t *testing.T) {
+	cases := []struct {
+		header  string
+		version int64
+		err     error
+	}{
+		{"", 3, nil},
+		{"*", 3, nil},
+		{transport.ETag(3), 3, nil},
+		{`"2", "3"`, 3, nil},
+		{`"2"`, 3, db.ErrPreconditionFailed},
+		{`W/"3"`, 3, db.ErrPreconditionFailed},
+		{`3`, 3, db.ErrPreconditionFailed},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/", nil)
+		if c.header != "" {
+			req.Header.Set("If-Match", c.header)
+		}
+		ctx := transport.PopulateIfMatch(context.Background(), req)
+		if err := db.CheckIfMatch(ctx, c.version); err != c.err {
+			t.Errorf("%q: expected %v, got %v", c.header, c.err, err)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2017, 6, 1, 10, 0, 0, 500, time.UTC)
+	etag := transport.ContentETag([]byte("dune"))
+	cases := []struct {
+		method          string
+		ifNoneMatch     string
+		ifModifiedSince time.Time
+		notModified     bool
+	}{
+		{"GET", "", time.Time{}, false},
+		{"GET", etag, time.Time{}, true},
+		{"GET", `"other", ` + etag, time.Time{}, true},
+		{"GET", "W/" + etag, time.Time{}, true},
+		{"GET", "*", time.Time{}, true},
+		{"GET", `"other"`, modified, false}, // If-None-Match takes precedence
+		{"GET", "", modified, true},
+		{"GET", "", modified.Add(-time.Second), false},
+		{"POST", etag, time.Time{}, false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/", nil)
+		if c.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", c.ifNoneMatch)
+		}
+		if !c.ifModifiedSince.IsZero() {
+			req.Header.Set("If-Modified-Since", c.ifModifiedSince.Format(http.TimeFormat))
+		}
+		ctx := transport.PopulateConditional(context.Background(), req)
+		if got := transport.NotModified(ctx, etag, modified); got != c.notModified {
+			t.Errorf("%s %q %v: expected %v, got %v", c.method, c.ifNoneMatch, c.ifModifiedSince, c.notModified, got)
+		}
+	}
+}
+
+var errBookNotFound = errors.New("book not found")
+
+func TestErrorEncoder(t *testing.T) {
+	errs := transport.NewRegistry().Register(errBookNotFound, http.StatusNotFound, "book_not_found")
+	encode := transport.ErrorEncoder(errs)
+
+	cases := []struct {
+		err     error
+		status  int
+		code    string
+		message string
+		details interface{}
+	}{
+		{errBookNotFound, http.StatusNotFound, "book_not_found", "book not found", nil},
+		{
+			transport.WithDetails(errBookNotFound, map[string]interface{}{"id": "dune"}),
+			http.StatusNotFound, "book_not_found", "book not found", map[string]interface{}{"id": "dune"},
+		},
+		{db.ErrConflict, http.StatusConflict, "conflict", db.ErrConflict.Error(), nil},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError, "internal", "Internal Server Error", nil},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		encode(context.Background(), c.err, w)
+
+		var body struct {
+			Meta struct {
+				Status int
+				Error  struct {
+					Code    string
+					Message string
+					Details interface{}
+				}
+			}
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		e := body.Meta.Error
+		if w.Code != c.status || body.Meta.Status != c.status {
+			t.Errorf("%v: expected status %v, got %v and %v", c.err, c.status, w.Code, body.Meta.Status)
+		}
+		if e.Code != c.code || e.Message != c.message {
+			t.Errorf("%v: expected %v %q, got %v %q", c.err, c.code, c.message, e.Code, e.Message)
+		}
+		if got, want := fmt.Sprint(e.Details), fmt.Sprint(c.details); c.details != nil && got != want {
+			t.Errorf("%v: expected details %v, got %v", c.err, want, got)
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	errs := transport.NewRegistry().Register(errBookNotFound, http.StatusNotFound, "book_not_found")
+	errs.Register(errBookNotFound, http.StatusNotFound, "book_not_found") // same is fine
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic registering %v differently", errBookNotFound)
+		}
+	}()
+	errs.Register(errBookNotFound, http.StatusGone, "book_gone")
+}
+
+func TestValidated(t *testing.T) {
+	dec := transport.Validated(func(context.Context, *http.Request) (interface{}, error) {
+		return signup{Email: "not an email"}, nil
+	})
+	_, err := dec(context.Background(), httptest.NewRequest("POST", "/signup", nil))
+	if validate.FieldErrors(err) == nil {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	transport.ErrorEncoder(transport.NewRegistry())(context.Background(), err, w)
+	var body transport.FormatResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || body.Meta.Error.Code != "invalid" {
+		t.Errorf("expected 422 invalid, got %v %v", w.Code, body.Meta.Error.Code)
+	}
+	want := []validate.FieldError{
+		{Field: "name", Code: validate.Missing, Message: "is required"},
+		{Field: "email", Code: validate.Format, Message: "must be an email address"},
+	}
+	if !reflect.DeepEqual(body.Meta.Errors, want) {
+		t.Errorf("expected %v, got %v", want, body.Meta.Errors)
+	}
+}
+
+type signup struct {
+	Name  string
+	Email string
+}
+
+func (s signup) Validate() error {
+	var v validate.Validator
+	v.Required("name", s.Name)
+	v.Email("email", s.Email)
+	return v.Err()
+}
+
+func TestRoutes(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	books := transport.NewRoutes("/books/v1", func(r *mux.Router) {
+		r.Handle("/search", ok).Methods("GET")
+		r.Handle("/{id}", ok).Methods("GET", "PUT")
+	})
+	router := mux.NewRouter()
+	transport.Mount(router, books)
+	router.Handle("/metrics", ok)
+
+	t.Run("mounted under prefix", func(t *testing.T) {
+		for _, url := range []string{"/books/v1/search", "/books/v1/dune"} {
+			var m mux.RouteMatch
+			if !router.Match(httptest.NewRequest("GET", url, nil), &m) {
+				t.Errorf("expected %v routed, got not", url)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		want := []transport.Route{
+			{Path: "/books/v1/search", Methods: []string{"GET"}},
+			{Path: "/books/v1/{id}", Methods: []string{"GET", "PUT"}},
+			{Path: "/metrics", Methods: []string{"*"}},
+		}
+		if got := transport.ListRoutes(router); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if err := transport.CheckRoutes(router); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		transport.RoutesHandler(router).ServeHTTP(w, httptest.NewRequest("GET", "/routes", nil))
+		var body struct{ Data []transport.Route }
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if !reflect.DeepEqual(body.Data, want) {
+			t.Errorf("expected %v, got %v", want, body.Data)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		router.Handle("/books/v1/deleted", ok).Methods("GET") // after /books/v1/{id}
+		err := transport.CheckRoutes(router)
+		if err == nil || !strings.Contains(err.Error(), "/books/v1/deleted") {
+			t.Errorf("expected /books/v1/deleted unreachable, got %v", err)
+		}
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		name, header string
+		given        bool
+	}{
+		{"given", "req-42.a:b", true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"unsafe", "id\" injected=1", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			h := transport.RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got = logging.RequestID(transport.PopulateRequestID(context.Background(), req))
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if c.header != "" {
+				req.Header.Set(transport.RequestIDHeader, c.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if got == "" || got != w.Header().Get(transport.RequestIDHeader) {
+				t.Errorf("expected request id %q in context and response, got %q", w.Header().Get(transport.RequestIDHeader), got)
+			}
+			if (got == c.header) != c.given {
+				t.Errorf("expected given id used %v, got %q", c.given, got)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	encode := transport.ErrorEncoder(transport.NewRegistry())
+	w := httptest.NewRecorder()
+	encode(context.Background(), ratelimit.Error{Err: ratelimit.ErrLimited, Wait: 1500 * time.Millisecond}, w)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected 429 retried after 2s, got %v after %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = httptest.NewRecorder()
+	encode(context.Background(), db.ErrConflict, w)
+	if h := w.Header().Get("Retry-After"); h != "" {
+		t.Errorf("expected no Retry-After, got %q", h)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := transport.ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	cases := []struct {
+		name, remote, forwarded, ip string
+	}{
+		{"direct", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"untrusted forwarding", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5000", "198.51.100.1", "198.51.100.1"},
+		{"forged hops", "10.1.2.3:5000", "1.1.1.1, 198.51.100.1, 192.168.1.1", "198.51.100.1"},
+		{"only proxies", "10.1.2.3:5000", "10.0.0.1", "10.0.0.1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			h := transport.ClientIP(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got = ratelimit.ClientIP(req.Context())
+			}), trusted...)
+			req := httptest.NewRequest("POST", "/users/v1/login", nil)
+			req.RemoteAddr = c.remote
+			if c.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", c.forwarded)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != c.ip {
+				t.Errorf("expected %v, got %v", c.ip, got)
+			}
+		})
+	}
+
+	if _, err := transport.ParseCIDRs([]string{"10.0.0.0/33"}); err == nil {
+		t.Errorf("expected invalid network error, got nil")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	var calls int
+	authn := auth.AuthenticatorFunc(func(ctx context.Context, token string) (auth.Principal, error) {
+		calls++
+		if token != "secret" {
+			return auth.Principal{}, auth.ErrUnauthorized
+		}
+		return auth.Principal{UserID: "1", Roles: auth.Roles{auth.Admin}}, nil
+	})
+	cases := []struct {
+		name, header string
+		err          error
+	}{
+		{"no token", "", auth.ErrUnauthorized},
+		{"bearer", "Bearer secret", nil},
+		{"token", "Token secret", nil},
+		{"unknown token", "Bearer guess", auth.ErrUnauthorized},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var err error
+			h := transport.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				// Asked twice, authenticated once.
+				auth.PrincipalFrom(req.Context())
+				_, err = auth.PrincipalFrom(req.Context())
+			}), authn)
+			req := httptest.NewRequest("GET", "/users/v1/list", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if err != c.err {
+				t.Errorf("expected %v, got %v", c.err, err)
+			}
+		})
+	}
+	if calls != 3 {
+		t.Errorf("expected a single authentication per request with a token, got %v", calls)
+	}
+
+	calls = 0
+	h := transport.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}), authn)
+	req := httptest.NewRequest("GET", "/catalog/v1/search", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if calls != 0 {
+		t.Errorf("expected public routes not to authenticate, got %v calls", calls)
+	}
+}
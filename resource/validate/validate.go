@@ -0,0 +1,149 @@
+// validate checks the fields of requests, collecting the errors of all of
+// them so that clients can fix them at once.
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalid is the cause of every validation Error.
+var ErrInvalid = errors.New("invalid request")
+
+// Codes of the field errors.
+const (
+	Missing  = "missing"
+	Format   = "format"
+	Length   = "length"
+	Range    = "range"
+	Mismatch = "mismatch"
+)
+
+// FieldError tells why a single field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is the errors of all the invalid fields of a request.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Field + " " + f.Message
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(fields, ", ")
+}
+
+// Cause returns ErrInvalid.
+func (e *Error) Cause() error {
+	return ErrInvalid
+}
+
+// FieldErrors returns the field errors err wraps, if it is a validation
+// error.
+func FieldErrors(err error) []FieldError {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e.Fields
+		}
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return nil
+		}
+		err = c.Cause()
+	}
+	return nil
+}
+
+// Validator collects the field errors of a request.
+// Every check returns whether the field is valid.
+type Validator struct {
+	fields []FieldError
+}
+
+// Err returns the Error of the invalid fields, nil if all are valid.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: v.fields}
+}
+
+// Check adds the error of field with code and message unless ok.
+func (v *Validator) Check(ok bool, field, code, message string) bool {
+	if !ok {
+		v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+	}
+	return ok
+}
+
+// Required checks value of field is not blank.
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(strings.TrimSpace(value) != "", field, Missing, "is required")
+}
+
+// Length checks value of field has min to max characters, 0 max is
+// unlimited. Blank value is left to Required.
+func (v *Validator) Length(field, value string, min, max int) bool {
+	n := utf8.RuneCountInString(value)
+	if n == 0 {
+		return true
+	}
+	ok := n >= min && (max == 0 || n <= max)
+	msg := fmt.Sprintf("must have %d to %d characters", min, max)
+	if max == 0 {
+		msg = fmt.Sprintf("must have at least %d characters", min)
+	}
+	return v.Check(ok, field, Length, msg)
+}
+
+// Range checks n of field is between min and max.
+func (v *Validator) Range(field string, n, min, max int) bool {
+	return v.Check(n >= min && n <= max, field, Range, fmt.Sprintf("must be between %d and %d", min, max))
+}
+
+// Email checks value of field looks like an email address. Blank value is
+// left to Required.
+func (v *Validator) Email(field, value string) bool {
+	if value == "" {
+		return true
+	}
+	at := strings.LastIndex(value, "@")
+	ok := at > 0 && at < len(value)-1 &&
+		strings.Contains(value[at:], ".") && !strings.ContainsAny(value, " \t<>,;")
+	return v.Check(ok, field, Format, "must be an email address")
+}
+
+// Equal checks value of field equals the one of other, e.g: confirmed
+// password.
+func (v *Validator) Equal(field, value, other, otherField string) bool {
+	return v.Check(value == other, field, Mismatch, "must match "+otherField)
+}
+
+// Nested adds the field errors of err, the validation error of field, with
+// their field prefixed e.g: shipping_address.country. It returns err if it
+// is not a validation error, for the caller to fail with.
+func (v *Validator) Nested(field string, err error) error {
+	fields := FieldErrors(err)
+	if fields == nil {
+		return err
+	}
+	for _, f := range fields {
+		f.Field = field + "." + f.Field
+		v.fields = append(v.fields, f)
+	}
+	return nil
+}
+
+// Field returns Error of the single field.
+func Field(field, code, message string) error {
+	return &Error{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
@@ -0,0 +1,67 @@
+package validate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kavirajk/bookshop/validate"
+	"github.com/pkg/errors"
+)
+
+func TestValidator(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		var v validate.Validator
+		v.Required("name", "Dune")
+		v.Length("name", "Dune", 1, 10)
+		v.Range("quantity", 3, 1, 100)
+		v.Email("email", "paul@arrakis.example")
+		v.Equal("confirm", "x", "x", "password")
+		if err := v.Err(); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("collects all", func(t *testing.T) {
+		var v validate.Validator
+		v.Required("name", "  ")
+		v.Length("password", "short", 8, 0)
+		v.Length("title", "ünïcödé", 1, 7) // counted in characters
+		v.Range("quantity", 0, 1, 100)
+		v.Email("email", "paul@arrakis")
+		v.Equal("confirm", "y", "x", "password")
+		v.Nested("address", validate.Field("country", validate.Missing, "is required"))
+
+		err := v.Err()
+		if errors.Cause(err) != validate.ErrInvalid {
+			t.Fatalf("expected %v, got %v", validate.ErrInvalid, err)
+		}
+		want := []validate.FieldError{
+			{Field: "name", Code: validate.Missing, Message: "is required"},
+			{Field: "password", Code: validate.Length, Message: "must have at least 8 characters"},
+			{Field: "quantity", Code: validate.Range, Message: "must be between 1 and 100"},
+			{Field: "email", Code: validate.Format, Message: "must be an email address"},
+			{Field: "confirm", Code: validate.Mismatch, Message: "must match password"},
+			{Field: "address.country", Code: validate.Missing, Message: "is required"},
+		}
+		if got := validate.FieldErrors(errors.Wrap(err, "register")); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("blank left to required", func(t *testing.T) {
+		var v validate.Validator
+		v.Length("password", "", 8, 128)
+		v.Email("email", "")
+		if err := v.Err(); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("nested other error", func(t *testing.T) {
+		var v validate.Validator
+		other := errors.New("boom")
+		if err := v.Nested("address", other); err != other {
+			t.Errorf("expected %v, got %v", other, err)
+		}
+	})
+}